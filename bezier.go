@@ -104,3 +104,38 @@ func (c *cubicBezier) recursiveInterpolate(limit int, level int) [][2]float64 {
 	vertices = append(vertices, c.controlpoints[3])
 	return vertices
 }
+
+// flatten approximates the curve by a polyline whose points are no
+// further than tolerance from it, subdividing until the control points
+// lie within tolerance of the chord. The result starts with the first
+// control point. The other points are counted against limit, and
+// subdivision stops as soon as there are too many.
+func (c *cubicBezier) flatten(tolerance float64, limit *pointLimit) [][2]float64 {
+	vertices := [][2]float64{c.controlpoints[0]}
+	return c.appendFlattened(vertices, tolerance, 0, limit)
+}
+
+// maxFlattenDepth bounds the subdivision of degenerate curves.
+const maxFlattenDepth = 16
+
+func (c *cubicBezier) appendFlattened(vertices [][2]float64, tolerance float64, depth int, limit *pointLimit) [][2]float64 {
+	p := c.controlpoints
+	if limit.err() != nil {
+		return vertices
+	}
+	if depth >= maxFlattenDepth ||
+		(pointSegmentDistance(p[1], p[0], p[3]) <= tolerance && pointSegmentDistance(p[2], p[0], p[3]) <= tolerance) {
+		limit.count(1)
+		return append(vertices, p[3])
+	}
+
+	mid := func(a, b [2]float64) [2]float64 { return [2]float64{(a[0] + b[0]) / 2, (a[1] + b[1]) / 2} }
+	m12, m23, m34 := mid(p[0], p[1]), mid(p[1], p[2]), mid(p[2], p[3])
+	m123, m234 := mid(m12, m23), mid(m23, m34)
+	m1234 := mid(m123, m234)
+
+	left := cubicBezier{controlpoints: [4][2]float64{p[0], m12, m123, m1234}}
+	right := cubicBezier{controlpoints: [4][2]float64{m1234, m234, m34, p[3]}}
+	vertices = left.appendFlattened(vertices, tolerance, depth+1, limit)
+	return right.appendFlattened(vertices, tolerance, depth+1, limit)
+}
@@ -0,0 +1,542 @@
+package svg
+
+import (
+	"math"
+	"sort"
+)
+
+// booleanOp selects how the regions of two shape collections are
+// combined.
+type booleanOp int
+
+const (
+	opUnion booleanOp = iota
+	opIntersect
+	opDifference
+	opXor
+)
+
+// snapEpsilon is the grid that computed vertices are snapped to so that
+// split edges meet exactly.
+const snapEpsilon = 1e-7
+
+// Union returns the outline of the area filled by any shape of a or b.
+// Pass a nil b to merge the shapes of a single collection.
+func Union(a, b []Shape) []Segment {
+	return booleanShapes(a, b, opUnion)
+}
+
+// Intersect returns the outline of the area filled by both a and b.
+func Intersect(a, b []Shape) []Segment {
+	return booleanShapes(a, b, opIntersect)
+}
+
+// Difference returns the outline of the area filled by a but not by b.
+func Difference(a, b []Shape) []Segment {
+	return booleanShapes(a, b, opDifference)
+}
+
+// Xor returns the outline of the area filled by exactly one of a and b.
+func Xor(a, b []Shape) []Segment {
+	return booleanShapes(a, b, opXor)
+}
+
+// region is the filled area of one shape: its rings and fill rule.
+type region struct {
+	rings    []Segment
+	fillRule string
+}
+
+// regionIndex answers point in region queries without visiting every
+// ring edge. The edges are bucketed into horizontal bands, so that a
+// winding number only visits the edges of the band containing the
+// point.
+type regionIndex struct {
+	fillRule   string
+	min, max   [2]float64
+	bandHeight float64
+	bands      [][]edge
+}
+
+// maxBands bounds the number of bands of a regionIndex, and so the
+// number of times a long edge is stored.
+const maxBands = 1024
+
+func newRegionIndex(r region) *regionIndex {
+	idx := &regionIndex{fillRule: r.fillRule, min: [2]float64{math.Inf(1), math.Inf(1)}, max: [2]float64{math.Inf(-1), math.Inf(-1)}}
+	var edges []edge
+	for _, ring := range r.rings {
+		n := len(ring.Points)
+		for i := 0; i < n && n > 1; i++ {
+			a, b := ring.Points[i], ring.Points[(i+1)%n]
+			edges = append(edges, edge{a, b})
+			idx.min = [2]float64{math.Min(idx.min[0], a[0]), math.Min(idx.min[1], a[1])}
+			idx.max = [2]float64{math.Max(idx.max[0], a[0]), math.Max(idx.max[1], a[1])}
+		}
+	}
+	if len(edges) == 0 {
+		return idx
+	}
+
+	n := len(edges) / 8
+	if n < 1 {
+		n = 1
+	}
+	if n > maxBands {
+		n = maxBands
+	}
+	idx.bandHeight = (idx.max[1] - idx.min[1]) / float64(n)
+	idx.bands = make([][]edge, n)
+	for _, e := range edges {
+		lo, hi := idx.band(math.Min(e.a[1], e.b[1])), idx.band(math.Max(e.a[1], e.b[1]))
+		for i := lo; i <= hi; i++ {
+			idx.bands[i] = append(idx.bands[i], e)
+		}
+	}
+	return idx
+}
+
+// band returns the band containing y, which must be within the bounds.
+func (idx *regionIndex) band(y float64) int {
+	if idx.bandHeight <= 0 {
+		return 0
+	}
+	i := int((y - idx.min[1]) / idx.bandHeight)
+	if i >= len(idx.bands) {
+		i = len(idx.bands) - 1
+	}
+	if i < 0 {
+		i = 0
+	}
+	return i
+}
+
+// contains reports whether p is filled, counting windings like
+// Segment.winding.
+func (idx *regionIndex) contains(p [2]float64) bool {
+	if len(idx.bands) == 0 || p[0] < idx.min[0] || p[0] > idx.max[0] || p[1] < idx.min[1] || p[1] > idx.max[1] {
+		return false
+	}
+	w := 0
+	for _, e := range idx.bands[idx.band(p[1])] {
+		a, b := e.a, e.b
+		if a[1] <= p[1] {
+			if b[1] > p[1] && cross(a, b, p) > 0 {
+				w++
+			}
+		} else if b[1] <= p[1] && cross(a, b, p) < 0 {
+			w--
+		}
+	}
+	return insideFill(w, idx.fillRule)
+}
+
+// regionSet finds the regions around a point through a uniform grid of
+// their bounds.
+type regionSet struct {
+	cell  float64
+	cells map[[2]int][]*regionIndex
+	// large are the regions covering too many cells to be stored in them
+	large []*regionIndex
+}
+
+// maxRegionCells is the most cells a region is stored in.
+const maxRegionCells = 256
+
+func newRegionSet(regions []region) *regionSet {
+	set := &regionSet{cells: make(map[[2]int][]*regionIndex)}
+	var indexes []*regionIndex
+	var size float64
+	for _, r := range regions {
+		idx := newRegionIndex(r)
+		if len(idx.bands) == 0 {
+			continue
+		}
+		indexes = append(indexes, idx)
+		size += math.Max(idx.max[0]-idx.min[0], idx.max[1]-idx.min[1])
+	}
+	if len(indexes) == 0 {
+		return set
+	}
+	// cells about the size of the average region
+	if set.cell = size / float64(len(indexes)); set.cell <= 0 {
+		set.cell = 1
+	}
+	for _, idx := range indexes {
+		lo, hi := set.key(idx.min), set.key(idx.max)
+		if (hi[0]-lo[0]+1)*(hi[1]-lo[1]+1) > maxRegionCells {
+			set.large = append(set.large, idx)
+			continue
+		}
+		for x := lo[0]; x <= hi[0]; x++ {
+			for y := lo[1]; y <= hi[1]; y++ {
+				set.cells[[2]int{x, y}] = append(set.cells[[2]int{x, y}], idx)
+			}
+		}
+	}
+	return set
+}
+
+func (set *regionSet) key(p [2]float64) [2]int {
+	return [2]int{int(math.Floor(p[0] / set.cell)), int(math.Floor(p[1] / set.cell))}
+}
+
+// contains reports whether any region contains p.
+func (set *regionSet) contains(p [2]float64) bool {
+	for _, r := range set.large {
+		if r.contains(p) {
+			return true
+		}
+	}
+	if len(set.cells) == 0 {
+		return false
+	}
+	for _, r := range set.cells[set.key(p)] {
+		if r.contains(p) {
+			return true
+		}
+	}
+	return false
+}
+
+func shapeRegions(shapes []Shape) []region {
+	var regions []region
+	for _, s := range shapes {
+		if !s.Filled() || len(s.Segments) == 0 {
+			continue
+		}
+		regions = append(regions, region{rings: s.Segments, fillRule: s.FillRule})
+	}
+	return regions
+}
+
+type edge struct {
+	a, b [2]float64
+}
+
+// booleanShapes combines the filled regions of a and b.
+func booleanShapes(a, b []Shape, op booleanOp) []Segment {
+	return booleanRegions(shapeRegions(a), shapeRegions(b), op)
+}
+
+// fillLoops resolves the area that segments enclose under fillRule into
+// loops oriented like the results of the boolean operations.
+func fillLoops(segments []Segment, fillRule string) []Segment {
+	return booleanRegions([]region{{rings: segments, fillRule: fillRule}}, nil, opUnion)
+}
+
+// booleanRegions combines the regions ra and rb. Every ring edge is
+// split at all intersections, and each resulting edge is kept if the
+// result of op differs on its two sides. Kept edges are oriented with
+// the result on their left and linked into closed loops, so outer
+// boundaries have a positive signed area and holes a negative one.
+func booleanRegions(ra, rb []region, op booleanOp) []Segment {
+	var edges []edge
+	for _, regions := range [][]region{ra, rb} {
+		for _, r := range regions {
+			for _, ring := range r.rings {
+				edges = append(edges, ringEdges(ring)...)
+			}
+		}
+	}
+	if len(edges) == 0 {
+		return nil
+	}
+
+	sa, sb := newRegionSet(ra), newRegionSet(rb)
+	inResult := func(p [2]float64) bool {
+		inA, inB := sa.contains(p), sb.contains(p)
+		switch op {
+		case opIntersect:
+			return inA && inB
+		case opDifference:
+			return inA && !inB
+		case opXor:
+			return inA != inB
+		}
+		return inA || inB
+	}
+
+	delta := boundsDiagonal(edges) * 1e-6
+	var boundary []edge
+	for _, e := range splitEdges(edges) {
+		l := math.Hypot(e.b[0]-e.a[0], e.b[1]-e.a[1])
+		d := math.Min(delta, l/4)
+		n := leftNormal(e.a, e.b)
+		m := [2]float64{(e.a[0] + e.b[0]) / 2, (e.a[1] + e.b[1]) / 2}
+		left := inResult(offsetPoint(m, n, d))
+		right := inResult(offsetPoint(m, n, -d))
+		switch {
+		case left && !right:
+			boundary = append(boundary, e)
+		case right && !left:
+			boundary = append(boundary, edge{e.b, e.a})
+		}
+	}
+
+	return linkEdges(boundary)
+}
+
+// ringEdges returns the snapped edges of a ring, closing it implicitly.
+func ringEdges(s Segment) []edge {
+	var edges []edge
+	n := len(s.Points)
+	for i := 0; i < n && n > 1; i++ {
+		e := edge{snapPoint(s.Points[i]), snapPoint(s.Points[(i+1)%n])}
+		if e.a != e.b {
+			edges = append(edges, e)
+		}
+	}
+	return edges
+}
+
+// splitEdges splits all edges at their mutual intersections and removes
+// duplicates, so that the returned edges only meet at their end points.
+// Candidate pairs are found by sweeping a line across the edges sorted
+// by their left end, only testing edges whose x and y ranges overlap.
+// This is O(n log n + k) for the usual inputs with short edges, where k
+// is the number of overlapping pairs, but degrades to O(n²) when many
+// edges span the whole width of the input.
+func splitEdges(edges []edge) []edge {
+	splits := make([][]float64, len(edges))
+	order := make([]int, len(edges))
+	for i := range order {
+		order[i] = i
+	}
+	minX := func(e edge) float64 { return math.Min(e.a[0], e.b[0]) }
+	sort.Slice(order, func(i, j int) bool { return minX(edges[order[i]]) < minX(edges[order[j]]) })
+
+	var active []int
+	for _, i := range order {
+		e := edges[i]
+		eMinY, eMaxY := math.Min(e.a[1], e.b[1]), math.Max(e.a[1], e.b[1])
+		kept := active[:0]
+		for _, j := range active {
+			f := edges[j]
+			// edges left of the sweep line cannot meet any later edge
+			if math.Max(f.a[0], f.b[0]) < minX(e)-snapEpsilon {
+				continue
+			}
+			kept = append(kept, j)
+			if math.Max(f.a[1], f.b[1]) < eMinY-snapEpsilon || math.Min(f.a[1], f.b[1]) > eMaxY+snapEpsilon {
+				continue
+			}
+			ti, tj := intersectEdges(e, f)
+			splits[i] = append(splits[i], ti...)
+			splits[j] = append(splits[j], tj...)
+		}
+		active = append(kept, i)
+	}
+
+	seen := make(map[edge]bool)
+	var result []edge
+	for i, e := range edges {
+		ts := append(splits[i], 0, 1)
+		sort.Float64s(ts)
+		prev := e.a
+		for _, t := range ts[1:] {
+			p := snapPoint([2]float64{e.a[0] + t*(e.b[0]-e.a[0]), e.a[1] + t*(e.b[1]-e.a[1])})
+			if t >= 1 {
+				p = e.b
+			}
+			if p == prev {
+				continue
+			}
+			key := edge{prev, p}
+			if p[0] < prev[0] || (p[0] == prev[0] && p[1] < prev[1]) {
+				key = edge{p, prev}
+			}
+			if !seen[key] {
+				seen[key] = true
+				result = append(result, edge{prev, p})
+			}
+			prev = p
+		}
+	}
+	return result
+}
+
+// intersectEdges returns the interior parameters at which e and f must
+// be split. Collinear overlapping edges are split at each other's end
+// points.
+func intersectEdges(e, f edge) (te, tf []float64) {
+	r := [2]float64{e.b[0] - e.a[0], e.b[1] - e.a[1]}
+	s := [2]float64{f.b[0] - f.a[0], f.b[1] - f.a[1]}
+	qp := [2]float64{f.a[0] - e.a[0], f.a[1] - e.a[1]}
+	denom := r[0]*s[1] - r[1]*s[0]
+	interior := func(t float64) bool { return t > 1e-12 && t < 1-1e-12 }
+
+	if math.Abs(denom) < 1e-18 {
+		if math.Abs(qp[0]*r[1]-qp[1]*r[0]) > snapEpsilon*math.Hypot(r[0], r[1]) {
+			return nil, nil
+		}
+		rr := r[0]*r[0] + r[1]*r[1]
+		ss := s[0]*s[0] + s[1]*s[1]
+		for _, p := range [][2]float64{f.a, f.b} {
+			if t := ((p[0]-e.a[0])*r[0] + (p[1]-e.a[1])*r[1]) / rr; interior(t) {
+				te = append(te, t)
+			}
+		}
+		for _, p := range [][2]float64{e.a, e.b} {
+			if t := ((p[0]-f.a[0])*s[0] + (p[1]-f.a[1])*s[1]) / ss; interior(t) {
+				tf = append(tf, t)
+			}
+		}
+		return te, tf
+	}
+
+	t := (qp[0]*s[1] - qp[1]*s[0]) / denom
+	u := (qp[0]*r[1] - qp[1]*r[0]) / denom
+	if t < -1e-12 || t > 1+1e-12 || u < -1e-12 || u > 1+1e-12 {
+		return nil, nil
+	}
+	if interior(t) {
+		te = append(te, t)
+	}
+	if interior(u) {
+		tf = append(tf, u)
+	}
+	return te, tf
+}
+
+// linkEdges joins directed edges into closed loops. At vertices with
+// several outgoing edges the sharpest left turn is taken, which keeps
+// regions that touch in a single point in separate loops.
+func linkEdges(edges []edge) []Segment {
+	outgoing := make(map[[2]float64][]int)
+	for i, e := range edges {
+		outgoing[e.a] = append(outgoing[e.a], i)
+	}
+	used := make([]bool, len(edges))
+
+	var loops []Segment
+	for i := range edges {
+		if used[i] {
+			continue
+		}
+		loop := Segment{Closed: true, Points: [][2]float64{edges[i].a}}
+		cur := i
+		for !used[cur] {
+			used[cur] = true
+			e := edges[cur]
+			loop.addPoint(e.b)
+			if e.b == loop.Points[0] {
+				break
+			}
+
+			back := math.Atan2(e.a[1]-e.b[1], e.a[0]-e.b[0])
+			next, best := -1, math.Inf(1)
+			for _, j := range outgoing[e.b] {
+				if used[j] {
+					continue
+				}
+				f := edges[j]
+				turn := math.Mod(back-math.Atan2(f.b[1]-f.a[1], f.b[0]-f.a[0])+4*math.Pi, 2*math.Pi)
+				if turn == 0 {
+					turn = 2 * math.Pi
+				}
+				if turn < best {
+					next, best = j, turn
+				}
+			}
+			if next < 0 {
+				break
+			}
+			cur = next
+		}
+		if len(loop.Points) > 3 && loop.Points[0] == loop.Points[len(loop.Points)-1] {
+			loop.Points = dropCollinear(loop.Points)
+			loops = append(loops, loop)
+		}
+	}
+	return loops
+}
+
+// dropCollinear removes the interior points of straight runs from a
+// closed point list whose first and last points are equal.
+func dropCollinear(pts [][2]float64) [][2]float64 {
+	ring := pts[:len(pts)-1]
+	n := len(ring)
+	var out [][2]float64
+	for i, p := range ring {
+		prev, next := ring[(i+n-1)%n], ring[(i+1)%n]
+		l := math.Hypot(next[0]-prev[0], next[1]-prev[1])
+		if math.Abs(cross(prev, next, p)) <= snapEpsilon*l {
+			continue
+		}
+		out = append(out, p)
+	}
+	if len(out) < 3 {
+		return pts
+	}
+	return append(out, out[0])
+}
+
+func snapPoint(p [2]float64) [2]float64 {
+	return [2]float64{
+		math.Round(p[0]/snapEpsilon) * snapEpsilon,
+		math.Round(p[1]/snapEpsilon) * snapEpsilon,
+	}
+}
+
+func boundsDiagonal(edges []edge) float64 {
+	minX, minY := math.Inf(1), math.Inf(1)
+	maxX, maxY := math.Inf(-1), math.Inf(-1)
+	for _, e := range edges {
+		for _, p := range [][2]float64{e.a, e.b} {
+			minX, maxX = math.Min(minX, p[0]), math.Max(maxX, p[0])
+			minY, maxY = math.Min(minY, p[1]), math.Max(maxY, p[1])
+		}
+	}
+	return math.Max(math.Hypot(maxX-minX, maxY-minY), 1)
+}
+
+// Area returns the signed area enclosed by the segment, treating it as
+// closed. It is positive for outer boundaries returned by the boolean
+// operations and negative for holes.
+func (s Segment) Area() float64 {
+	var a float64
+	n := len(s.Points)
+	for i := 0; i < n; i++ {
+		p, q := s.Points[i], s.Points[(i+1)%n]
+		a += p[0]*q[1] - q[0]*p[1]
+	}
+	return a / 2
+}
+
+// polygons groups the loops returned by the boolean operations into
+// polygons. Every polygon starts with an outer boundary followed by the
+// holes directly inside it.
+func polygons(loops []Segment) [][]Segment {
+	var outers []Segment
+	var holes []Segment
+	for _, l := range loops {
+		if l.Area() > 0 {
+			outers = append(outers, l)
+		} else {
+			holes = append(holes, l)
+		}
+	}
+
+	result := make([][]Segment, len(outers))
+	for i, o := range outers {
+		result[i] = []Segment{o}
+	}
+	for _, h := range holes {
+		// the filled area lies left of the hole's edges, inside the
+		// smallest outer boundary around it
+		a, b := h.Points[0], h.Points[1]
+		d := math.Hypot(b[0]-a[0], b[1]-a[1]) * 1e-3
+		p := offsetPoint([2]float64{(a[0] + b[0]) / 2, (a[1] + b[1]) / 2}, leftNormal(a, b), d)
+		best, area := -1, math.Inf(1)
+		for i, o := range outers {
+			if a := o.Area(); a < area && o.winding(p[0], p[1]) != 0 {
+				best, area = i, a
+			}
+		}
+		if best >= 0 {
+			result[best] = append(result[best], h)
+		}
+	}
+	return result
+}
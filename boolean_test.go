@@ -0,0 +1,121 @@
+package svg
+
+import (
+	"math"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func squareShape(x, y, size float64, fillRule string) Shape {
+	return Shape{
+		FillRule: fillRule,
+		Segments: []Segment{squareSegment(x, y, size)},
+	}
+}
+
+func squareSegment(x, y, size float64) Segment {
+	return Segment{Closed: true, Points: [][2]float64{
+		{x, y}, {x + size, y}, {x + size, y + size}, {x, y + size}, {x, y},
+	}}
+}
+
+func totalArea(segments []Segment) float64 {
+	var a float64
+	for _, s := range segments {
+		a += s.Area()
+	}
+	return a
+}
+
+func TestBooleanOperations(t *testing.T) {
+	a := []Shape{squareShape(0, 0, 10, "nonzero")}
+	b := []Shape{squareShape(5, 5, 10, "nonzero")}
+
+	tests := []struct {
+		Description string
+		Op          func(a, b []Shape) []Segment
+		Loops       int
+		Area        float64
+	}{
+		{"union", Union, 1, 175},
+		{"intersect", Intersect, 1, 25},
+		{"difference", Difference, 1, 75},
+		{"xor", Xor, 2, 150},
+	}
+
+	for _, test := range tests {
+		result := test.Op(a, b)
+		require.Len(t, result, test.Loops, test.Description)
+		for _, s := range result {
+			require.True(t, s.Closed, test.Description)
+			require.Equal(t, s.Points[0], s.Points[len(s.Points)-1], test.Description)
+		}
+		require.InDelta(t, test.Area, totalArea(result), 1e-6, test.Description)
+	}
+}
+
+func TestBooleanFillRuleHoles(t *testing.T) {
+	frame := Shape{
+		FillRule: "evenodd",
+		Segments: []Segment{squareSegment(0, 0, 10), squareSegment(3, 3, 4)},
+	}
+
+	result := Union([]Shape{frame}, nil)
+	require.Len(t, result, 2)
+	require.InDelta(t, 84, totalArea(result), 1e-6)
+
+	var outer, holes int
+	for _, s := range result {
+		if s.Area() > 0 {
+			outer++
+		} else {
+			holes++
+		}
+	}
+	require.Equal(t, 1, outer)
+	require.Equal(t, 1, holes)
+
+	frame.FillRule = "nonzero"
+	result = Union([]Shape{frame}, nil)
+	require.Len(t, result, 1)
+	require.InDelta(t, 100, math.Abs(totalArea(result)), 1e-6)
+}
+
+func TestGroupFillRuleInheritance(t *testing.T) {
+	// a path's own fill-rule must not leak into its group
+	svg, err := ParseSvg(`<svg><g fill-rule="evenodd">
+<path d="M0 0 L1 0 L1 1 Z" fill-rule="nonzero"/>
+<path d="M0 0 L1 0 L1 1 Z"/>
+</g></svg>`, "test", 0)
+	require.NoError(t, err)
+	shapes, err := svg.Shapes()
+	require.NoError(t, err)
+	require.Equal(t, "nonzero", shapes[0].FillRule)
+	require.Equal(t, "evenodd", shapes[1].FillRule)
+	require.Equal(t, "evenodd", svg.Groups[0].FillRule)
+}
+
+// copperShapes returns a grid of n by n overlapping squares, like the
+// pads and tracks of a board.
+func copperShapes(n int) []Shape {
+	var shapes []Shape
+	for i := 0; i < n; i++ {
+		for j := 0; j < n; j++ {
+			shapes = append(shapes, squareShape(float64(i)*7, float64(j)*7, 10, "nonzero"))
+		}
+	}
+	return shapes
+}
+
+func BenchmarkUnion(b *testing.B) {
+	for _, n := range []int{10, 20, 40} {
+		shapes := copperShapes(n)
+		b.Run(strconv.Itoa(n*n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				Union(shapes, nil)
+			}
+		})
+	}
+}
@@ -1,39 +1,97 @@
 package svg
 
-import mt "github.com/rustyoz/Mtransform"
+import (
+	"fmt"
+
+	mt "github.com/rustyoz/Mtransform"
+)
 
 // Circle is an SVG circle element
 type Circle struct {
-	ID        string  `xml:"id,attr"`
-	Transform string  `xml:"transform,attr"`
-	Style     string  `xml:"style,attr"`
-	Cx        float64 `xml:"cx,attr"`
-	Cy        float64 `xml:"cy,attr"`
-	Radius    float64 `xml:"r,attr"`
-	Fill      string  `xml:"fill,attr"`
+	ID          string   `xml:"id,attr"`
+	Transform   string   `xml:"transform,attr"`
+	Style       string   `xml:"style,attr"`
+	Cx          float64  `xml:"cx,attr"`
+	Cy          float64  `xml:"cy,attr"`
+	Radius      float64  `xml:"r,attr"`
+	Fill        string   `xml:"fill,attr"`
+	Opacity     *float64 `xml:"opacity,attr"`
+	FillOpacity *float64 `xml:"fill-opacity,attr"`
+	Display     string   `xml:"display,attr"`
+	Visibility  string   `xml:"visibility,attr"`
 
 	transform mt.Transform
 	group     *Group
+	pos       position
 }
 
 // ParseDrawingInstructions implements the DrawingInstructionParser
 // interface
 func (c *Circle) ParseDrawingInstructions() (chan *DrawingInstruction, chan error) {
 	draw := make(chan *DrawingInstruction)
-	errs := make(chan error)
+	errs := make(chan error, 1)
+
+	var owner *Svg
+	transform := mt.Identity()
+	if c.group != nil {
+		owner = c.group.Owner
+		if c.group.Transform != nil {
+			transform = *c.group.Transform
+		}
+	}
+	if c.Transform != "" {
+		t, err := parseTransform(c.Transform)
+		if err == nil {
+			transform = mt.MultiplyTransforms(transform, t)
+		} else if err = owner.warn(c.pos, "circle", c.ID, -1, fmt.Errorf("invalid transform %q: %w", c.Transform, err)); err != nil {
+			errs <- err
+			close(draw)
+			close(errs)
+			return draw, errs
+		}
+	}
 
 	go func() {
 		defer close(draw)
 		defer close(errs)
 
+		for _, di := range circleInstructions(c.Cx, c.Cy, c.Radius, transform) {
+			draw <- di
+		}
 		draw <- &DrawingInstruction{
-			Kind:   CircleInstruction,
-			M:      &Tuple{c.Cx, c.Cy},
-			Radius: &c.Radius,
+			Kind:         PaintInstruction,
+			Fill:         &c.Fill,
+			Opacity:      c.Opacity,
+			FillOpacity:  c.FillOpacity,
+			FillGradient: owner.paintGradient(&c.Fill, transform),
 		}
-
-		draw <- &DrawingInstruction{Kind: PaintInstruction, Fill: &c.Fill}
 	}()
 
 	return draw, errs
 }
+
+// circleInstructions returns the instructions of the circle at (cx, cy)
+// with radius r mapped by transform. A circle that is only translated
+// stays a CircleInstruction, any other transform maps the four Bézier
+// arcs of the circle.
+func circleInstructions(cx, cy, r float64, transform mt.Transform) []*DrawingInstruction {
+	circle := &DrawingInstruction{Kind: CircleInstruction, M: &Tuple{cx, cy}, Radius: &r}
+	if transform[0][0] == 1 && transform[0][1] == 0 && transform[1][0] == 0 && transform[1][1] == 1 {
+		circle.M[0], circle.M[1] = transform.Apply(cx, cy)
+		return []*DrawingInstruction{circle}
+	}
+	instrs := expandCircles([]*DrawingInstruction{circle})
+	for _, di := range instrs {
+		var points []*Tuple
+		if di.M != nil {
+			points = append(points, di.M)
+		}
+		if cp := di.CurvePoints; cp != nil {
+			points = append(points, cp.C1, cp.C2, cp.T)
+		}
+		for _, p := range points {
+			p[0], p[1] = transform.Apply(p[0], p[1])
+		}
+	}
+	return instrs
+}
@@ -0,0 +1,319 @@
+// Command svgtool converts SVG files to flattened points and to the
+// output formats supported by the svg package.
+//
+// Usage:
+//
+//	svgtool [flags] [input.svg]
+//
+// The input is read from standard input if no file is given. Run
+// svgtool -h for the list of flags.
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"image"
+	"image/png"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+
+	mt "github.com/rustyoz/Mtransform"
+	"github.com/vasalvit/svg"
+)
+
+type options struct {
+	format    string
+	output    string
+	scale     float64
+	tolerance float64
+	units     string
+	layers    string
+	width     int
+	height    int
+	strict    bool
+	font      string
+}
+
+func main() {
+	var opts options
+	flag.StringVar(&opts.format, "format", "csv", "output format: csv, json, dxf, gcode, svg, svgz or png")
+	flag.StringVar(&opts.output, "o", "", "output file (default standard output)")
+	flag.Float64Var(&opts.scale, "scale", 1, "scale passed to the parser; negative values divide")
+	flag.Float64Var(&opts.tolerance, "tolerance", 0, "largest distance between curves and their flattened points, in user units (0 subdivides a fixed number of times)")
+	flag.StringVar(&opts.units, "units", "", "output units for csv, json, dxf and gcode: px, pt, pc, mm, cm or in")
+	flag.StringVar(&opts.layers, "layer", "", "comma separated group IDs or Inkscape layer names to convert (default all)")
+	flag.IntVar(&opts.width, "width", 0, "png width in pixels (default the document width)")
+	flag.IntVar(&opts.height, "height", 0, "png height in pixels (default the document height)")
+	flag.BoolVar(&opts.strict, "strict", false, "fail on problems in the document instead of printing warnings")
+	flag.StringVar(&opts.font, "font", "", "TTF or OTF font file that text is converted to outlines with (default keep text as text)")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: svgtool [flags] [input.svg]\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if err := run(opts, flag.Args()); err != nil {
+		fmt.Fprintf(os.Stderr, "svgtool: %s\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(opts options, args []string) error {
+	if len(args) > 1 {
+		return errors.New("at most one input file can be given")
+	}
+	in, name := io.Reader(os.Stdin), "stdin"
+	if len(args) == 1 {
+		f, err := os.Open(args[0])
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		in, name = f, args[0]
+	}
+	parseOpts := []svg.Option{svg.WithName(name), svg.WithScale(opts.scale), svg.WithStrict(opts.strict)}
+	if opts.scale < 0 {
+		parseOpts[1] = svg.WithScale(1 / -opts.scale)
+	}
+	if opts.format == "csv" || opts.format == "json" {
+		// dxf and gcode convert to their units themselves
+		parseOpts = append(parseOpts, svg.WithUnits(opts.units))
+	}
+	if opts.font != "" {
+		font, err := svg.LoadFontFile(opts.font)
+		if err != nil {
+			return err
+		}
+		parseOpts = append(parseOpts, svg.WithFonts(&svg.Fonts{Default: font}))
+	}
+	doc, err := svg.ParseSvgWithOptions(in, svg.NewOptions(parseOpts...))
+	if err != nil {
+		return err
+	}
+	doc.FlattenTolerance = opts.tolerance
+	defer func() {
+		for _, w := range doc.Warnings {
+			fmt.Fprintf(os.Stderr, "svgtool: warning: %s\n", w)
+		}
+	}()
+
+	out := io.Writer(os.Stdout)
+	if opts.output != "" {
+		f, err := os.Create(opts.output)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		out = f
+	}
+
+	if opts.format == "png" {
+		return writePNG(out, doc, opts)
+	}
+
+	shapes, err := doc.Shapes()
+	if err != nil {
+		return err
+	}
+	shapes = filterLayers(shapes, opts.layers)
+
+	switch opts.format {
+	case "csv":
+		return writeCSV(out, shapes, doc.Transform)
+	case "json":
+		return writeJSON(out, shapes, doc.Transform)
+	}
+
+	// the other formats take the scale from the shapes
+	shapes = transformShapes(shapes, doc.Transform)
+	switch opts.format {
+	case "dxf":
+		return svg.WriteDXF(out, shapes, &svg.DXFOptions{Units: opts.units})
+	case "gcode":
+		units := opts.units
+		if units == "" {
+			units = "mm"
+		}
+		return svg.WriteGCode(out, shapes, &svg.GCodeOptions{Units: units, FlipY: true})
+	case "svg", "svgz":
+		return svg.WriteSvg(out, shapes, &svg.SvgOptions{Compress: opts.format == "svgz"})
+	}
+	return fmt.Errorf("unsupported format %q", opts.format)
+}
+
+// filterLayers keeps the visible shapes whose group ID or Inkscape layer
+// is in the comma separated list layers. An empty list keeps all
+// visible shapes.
+func filterLayers(shapes []svg.Shape, layers string) []svg.Shape {
+	keep := make(map[string]bool)
+	for _, l := range strings.Split(layers, ",") {
+		if l = strings.TrimSpace(l); l != "" {
+			keep[l] = true
+		}
+	}
+
+	var result []svg.Shape
+	for _, s := range shapes {
+		if s.Hidden {
+			continue
+		}
+		if len(keep) == 0 || keep[s.GroupID] || keep[s.Layer] {
+			result = append(result, s)
+		}
+	}
+	return result
+}
+
+// convert maps a point of a shape through the document's transform,
+// which carries the scale and units the document was parsed with.
+func convert(p [2]float64, t *mt.Transform) [2]float64 {
+	p[0], p[1] = t.Apply(p[0], p[1])
+	return p
+}
+
+// transformShapes returns copies of shapes with their segments,
+// instructions and stroke styles mapped through the document's
+// transform, which only scales.
+func transformShapes(shapes []svg.Shape, t *mt.Transform) []svg.Shape {
+	scale := math.Sqrt(math.Abs(t[0][0]*t[1][1] - t[0][1]*t[1][0]))
+	tuple := func(p *svg.Tuple) *svg.Tuple {
+		if p == nil {
+			return nil
+		}
+		q := svg.Tuple(convert(*p, t))
+		return &q
+	}
+
+	result := make([]svg.Shape, len(shapes))
+	for i, s := range shapes {
+		segments := make([]svg.Segment, len(s.Segments))
+		for j, seg := range s.Segments {
+			points := make([][2]float64, len(seg.Points))
+			for k, p := range seg.Points {
+				points[k] = convert(p, t)
+			}
+			seg.Points = points
+			segments[j] = seg
+		}
+		s.Segments = segments
+
+		instrs := make([]*svg.DrawingInstruction, len(s.Instructions))
+		for j, di := range s.Instructions {
+			c := *di
+			c.M = tuple(di.M)
+			if di.CurvePoints != nil {
+				c.CurvePoints = &svg.CurvePoints{C1: tuple(di.CurvePoints.C1), C2: tuple(di.CurvePoints.C2), T: tuple(di.CurvePoints.T)}
+			}
+			if di.Radius != nil {
+				r := *di.Radius * scale
+				c.Radius = &r
+			}
+			instrs[j] = &c
+		}
+		s.Instructions = instrs
+
+		s.StrokeStyle.Width *= scale
+		s.StrokeStyle.DashOffset *= scale
+		if s.StrokeStyle.Dash != nil {
+			dash := make([]float64, len(s.StrokeStyle.Dash))
+			for j, d := range s.StrokeStyle.Dash {
+				dash[j] = d * scale
+			}
+			s.StrokeStyle.Dash = dash
+		}
+		result[i] = s
+	}
+	return result
+}
+
+func format(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+// writeCSV writes one row per flattened point.
+func writeCSV(w io.Writer, shapes []svg.Shape, t *mt.Transform) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"shape", "id", "group", "segment", "closed", "x", "y"})
+	for i, s := range shapes {
+		for j, seg := range s.Segments {
+			for _, p := range seg.Points {
+				p = convert(p, t)
+				cw.Write([]string{
+					strconv.Itoa(i), s.ID, s.GroupID, strconv.Itoa(j),
+					strconv.FormatBool(seg.Closed), format(p[0]), format(p[1]),
+				})
+			}
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+type jsonShape struct {
+	ID       string        `json:"id,omitempty"`
+	Group    string        `json:"group,omitempty"`
+	Layer    string        `json:"layer,omitempty"`
+	Fill     string        `json:"fill,omitempty"`
+	Stroke   string        `json:"stroke,omitempty"`
+	Segments []jsonSegment `json:"segments"`
+}
+
+type jsonSegment struct {
+	Closed bool         `json:"closed"`
+	Width  float64      `json:"width"`
+	Points [][2]float64 `json:"points"`
+}
+
+// writeJSON writes the flattened segments of every shape. Stroke widths
+// are already scaled by the parser.
+func writeJSON(w io.Writer, shapes []svg.Shape, t *mt.Transform) error {
+	result := []jsonShape{}
+	for _, s := range shapes {
+		js := jsonShape{ID: s.ID, Group: s.GroupID, Layer: s.Layer, Fill: s.Fill, Stroke: s.Stroke, Segments: []jsonSegment{}}
+		for _, seg := range s.Segments {
+			jseg := jsonSegment{Closed: seg.Closed, Width: seg.Width}
+			for _, p := range seg.Points {
+				jseg.Points = append(jseg.Points, convert(p, t))
+			}
+			js.Segments = append(js.Segments, jseg)
+		}
+		result = append(result, js)
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(result)
+}
+
+func writePNG(w io.Writer, doc *svg.Svg, opts options) error {
+	if opts.layers != "" {
+		return errors.New("the layer filter is not supported for png output")
+	}
+	width, height := opts.width, opts.height
+	if width <= 0 || height <= 0 {
+		dw, dh, err := doc.Size()
+		if err != nil {
+			return err
+		}
+		switch {
+		case width > 0:
+			height = int(math.Ceil(dh * float64(width) / dw))
+		case height > 0:
+			width = int(math.Ceil(dw * float64(height) / dh))
+		default:
+			width, height = int(math.Ceil(dw)), int(math.Ceil(dh))
+		}
+	}
+
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	if err := svg.Render(doc, img, nil); err != nil {
+		return err
+	}
+	return png.Encode(w, img)
+}
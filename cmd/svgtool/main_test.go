@@ -0,0 +1,67 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const testSvg = `<svg width="96" height="96" xmlns:inkscape="http://www.inkscape.org/namespaces/inkscape">
+<g id="a"><path id="p1" d="M0 0 L96 0" stroke="black" stroke-width="1"/></g>
+<g inkscape:groupmode="layer" inkscape:label="cut"><path id="p2" d="M0 0 L0 48 L48 48 Z"/></g>
+</svg>`
+
+func convertFile(t *testing.T, opts options) string {
+	dir, err := ioutil.TempDir("", "svgtool")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	in := filepath.Join(dir, "in.svg")
+	require.NoError(t, ioutil.WriteFile(in, []byte(testSvg), 0644))
+	opts.output = filepath.Join(dir, "out")
+	if opts.scale == 0 {
+		opts.scale = 1
+	}
+	require.NoError(t, run(opts, []string{in}))
+	out, err := ioutil.ReadFile(opts.output)
+	require.NoError(t, err)
+	return string(out)
+}
+
+func TestConvert(t *testing.T) {
+	require.Equal(t, "shape,id,group,segment,closed,x,y\n"+
+		"0,p1,a,0,false,0,0\n"+
+		"0,p1,a,0,false,1,0\n",
+		convertFile(t, options{format: "csv", units: "in", layers: "a"}))
+
+	// units and scale both apply
+	require.Equal(t, "shape,id,group,segment,closed,x,y\n"+
+		"0,p1,a,0,false,0,0\n"+
+		"0,p1,a,0,false,12,0\n",
+		convertFile(t, options{format: "csv", units: "pc", scale: 2, layers: "a"}))
+	require.Contains(t, convertFile(t, options{format: "json", units: "in", layers: "a"}), `"width": 0.010416666666666666`)
+
+	json := convertFile(t, options{format: "json", layers: "cut"})
+	require.Contains(t, json, `"id": "p2"`)
+	require.Contains(t, json, `"layer": "cut"`)
+	require.NotContains(t, json, `"p1"`)
+
+	require.Contains(t, convertFile(t, options{format: "dxf", units: "mm"}), "LWPOLYLINE")
+	require.Contains(t, convertFile(t, options{format: "gcode"}), "G21\n")
+
+	// the scale reaches the exporters
+	gcode := convertFile(t, options{format: "gcode", layers: "a"})
+	scaled := convertFile(t, options{format: "gcode", scale: 2, layers: "a"})
+	require.NotEqual(t, gcode, scaled)
+	require.Contains(t, gcode, "X25.4")
+	require.Contains(t, scaled, "X50.8")
+	require.Contains(t, convertFile(t, options{format: "svg", scale: 2, layers: "a"}), `stroke-width="2"`)
+	require.Contains(t, convertFile(t, options{format: "svg"}), "<path")
+	require.Contains(t, convertFile(t, options{format: "png", scale: 0.5}), "PNG")
+
+	require.Error(t, run(options{format: "csv", units: "furlong"}, nil))
+	require.Error(t, run(options{format: "csv", font: "missing.ttf"}, nil))
+}
@@ -12,13 +12,14 @@ const (
 	LineInstruction
 	CloseInstruction
 	PaintInstruction
+	TextInstruction
 )
 
 // CurvePoints are the points needed by a bezier curve.
 type CurvePoints struct {
-	C1 *Tuple
-	C2 *Tuple
-	T  *Tuple
+	C1 *Tuple `json:"c1,omitempty"`
+	C2 *Tuple `json:"c2,omitempty"`
+	T  *Tuple `json:"t,omitempty"`
 }
 
 // DrawingInstruction contains enough information that a simple drawing
@@ -27,13 +28,25 @@ type CurvePoints struct {
 // The struct contains all necessary fields but only the ones needed (as
 // indicated byt the InstructionType) will be non-nil.
 type DrawingInstruction struct {
-	Kind           InstructionType
-	M              *Tuple
-	CurvePoints    *CurvePoints
-	Radius         *float64
-	StrokeWidth    *float64
-	Fill           *string
-	Stroke         *string
-	StrokeLineCap  *string
-	StrokeLineJoin *string
+	Kind             InstructionType `json:"kind"`
+	M                *Tuple          `json:"m,omitempty"`
+	CurvePoints      *CurvePoints    `json:"curvePoints,omitempty"`
+	Radius           *float64        `json:"radius,omitempty"`
+	StrokeWidth      *float64        `json:"strokeWidth,omitempty"`
+	Fill             *string         `json:"fill,omitempty"`
+	FillRule         *string         `json:"fillRule,omitempty"`
+	Stroke           *string         `json:"stroke,omitempty"`
+	StrokeLineCap    *string         `json:"strokeLineCap,omitempty"`
+	StrokeLineJoin   *string         `json:"strokeLineJoin,omitempty"`
+	StrokeMiterLimit *float64        `json:"strokeMiterLimit,omitempty"`
+	StrokeDashArray  []float64       `json:"strokeDashArray,omitempty"`
+	StrokeDashOffset *float64        `json:"strokeDashOffset,omitempty"`
+	Opacity          *float64        `json:"opacity,omitempty"`
+	FillOpacity      *float64        `json:"fillOpacity,omitempty"`
+	StrokeOpacity    *float64        `json:"strokeOpacity,omitempty"`
+	Text             *TextRun        `json:"text,omitempty"`
+	// FillGradient and StrokeGradient are the gradients the fill and
+	// stroke refer to, if any.
+	FillGradient   *Gradient `json:"fillGradient,omitempty"`
+	StrokeGradient *Gradient `json:"strokeGradient,omitempty"`
 }
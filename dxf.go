@@ -0,0 +1,637 @@
+package svg
+
+import (
+	"bytes"
+	"fmt"
+	"image/color"
+	"io"
+	"math"
+	"strings"
+)
+
+// DXFVersion selects the DXF release written by WriteDXF.
+type DXFVersion int
+
+// These are the supported DXF releases
+const (
+	DXF2000 DXFVersion = iota
+	DXFR12
+)
+
+// DXFOptions controls how shapes are written by WriteDXF.
+type DXFOptions struct {
+	Version DXFVersion
+	// Units is the unit of the drawing: one of mm, cm, in, pt, pc or px.
+	// Coordinates are converted from user units and $INSUNITS is set
+	// accordingly. An empty Units writes user units unitless.
+	Units string
+	// Curves writes circles as CIRCLE entities and, for DXF 2000, paths
+	// with curves as cubic SPLINE entities instead of flattened
+	// polylines.
+	Curves bool
+	// TrueColor writes the 24 bit colour of each entity next to its
+	// closest ACI colour. It is ignored for R12.
+	TrueColor bool
+}
+
+// dxfInsUnits maps units to their $INSUNITS code.
+var dxfInsUnits = map[string]int{"": 0, "px": 0, "pt": 0, "pc": 0, "in": 1, "mm": 4, "cm": 5}
+
+// WriteDXF writes the visible shapes to w as a DXF drawing. Each shape is
+// put on a layer named after its group ID, or layer 0, and coloured with
+// its stroke colour, or its fill colour if it is not stroked. The y axis
+// is flipped so that the drawing is upright in CAD. A nil opts writes
+// DXF 2000 polylines in user units.
+//
+// DXF 2000 drawings get the tables, blocks, layouts and handles that
+// AutoCAD requires of that release; R12 drawings only get the line type
+// and layer tables their entities refer to.
+func WriteDXF(w io.Writer, shapes []Shape, opts *DXFOptions) error {
+	if opts == nil {
+		opts = &DXFOptions{}
+	}
+	factor, ok := unitFactors[opts.Units]
+	if !ok {
+		return fmt.Errorf("unsupported DXF unit %q", opts.Units)
+	}
+
+	d := &dxfWriter{opts: opts, scale: 1 / factor, handle: 1}
+	layers := []string{"0"}
+	seen := map[string]bool{"0": true}
+	for _, s := range shapes {
+		if l := dxfLayer(s.GroupID); !seen[l] {
+			seen[l] = true
+			layers = append(layers, l)
+		}
+	}
+
+	version := "AC1015"
+	if opts.Version == DXFR12 {
+		version = "AC1009"
+	} else {
+		d.modelSpace, d.paperSpace = d.newHandle(), d.newHandle()
+		d.modelLayout, d.paperLayout = d.newHandle(), d.newHandle()
+		d.section("CLASSES")
+		d.pair(0, "ENDSEC")
+	}
+	d.tables(layers)
+	if opts.Version != DXFR12 {
+		d.blocks()
+	}
+
+	d.section("ENTITIES")
+	for _, s := range shapes {
+		if !s.Hidden {
+			d.shape(s)
+		}
+	}
+	d.pair(0, "ENDSEC")
+	if opts.Version != DXFR12 {
+		d.objects()
+	}
+	d.pair(0, "EOF")
+
+	// the header comes first but $HANDSEED must exceed every handle
+	// written after it
+	h := &dxfWriter{opts: opts}
+	h.section("HEADER")
+	h.pair(9, "$ACADVER")
+	h.pair(1, version)
+	if opts.Version != DXFR12 {
+		h.pair(9, "$HANDSEED")
+		h.pair(5, d.newHandle())
+	}
+	h.pair(9, "$INSUNITS")
+	h.pair(70, dxfInsUnits[opts.Units])
+	h.pair(0, "ENDSEC")
+
+	if _, err := w.Write(h.buf.Bytes()); err != nil {
+		return err
+	}
+	_, err := w.Write(d.buf.Bytes())
+	return err
+}
+
+// dxfLayer returns a valid layer name for a group ID.
+func dxfLayer(id string) string {
+	if id == "" {
+		return "0"
+	}
+	return strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`<>/\":;?*|=',`, r) || r < ' ' {
+			return '_'
+		}
+		return r
+	}, id)
+}
+
+type dxfWriter struct {
+	buf    bytes.Buffer
+	opts   *DXFOptions
+	scale  float64
+	handle int
+
+	// handles of the DXF 2000 block records and layouts of model and
+	// paper space
+	modelSpace, paperSpace   string
+	modelLayout, paperLayout string
+
+	// layer and colour of the shape being written
+	layer string
+	color color.NRGBA
+}
+
+func (d *dxfWriter) pair(code int, value interface{}) {
+	if f, ok := value.(float64); ok {
+		value = formatNumber(f)
+	}
+	fmt.Fprintf(&d.buf, "%3d\n%v\n", code, value)
+}
+
+func (d *dxfWriter) section(name string) {
+	d.pair(0, "SECTION")
+	d.pair(2, name)
+}
+
+func (d *dxfWriter) point(code int, p [2]float64) {
+	d.pair(code, p[0]*d.scale)
+	d.pair(code+10, -p[1]*d.scale)
+}
+
+// origin writes the 3D origin with the given group code.
+func (d *dxfWriter) origin(code int) {
+	d.pair(code, 0.0)
+	d.pair(code+10, 0.0)
+	d.pair(code+20, 0.0)
+}
+
+// newHandle returns the next unused handle.
+func (d *dxfWriter) newHandle() string {
+	h := fmt.Sprintf("%X", d.handle)
+	d.handle++
+	return h
+}
+
+// object writes a new handle and the handle of the owner, which DXF 2000
+// requires on every entity, table, table record and object, and returns
+// the new handle.
+func (d *dxfWriter) object(owner string) string {
+	h := d.newHandle()
+	d.pair(5, h)
+	d.pair(330, owner)
+	return h
+}
+
+// table starts a table of n records and returns its handle, which is
+// empty for R12.
+func (d *dxfWriter) table(name string, n int) string {
+	d.pair(0, "TABLE")
+	d.pair(2, name)
+	if d.opts.Version == DXFR12 {
+		d.pair(70, n)
+		return ""
+	}
+	h := d.object("0")
+	d.pair(100, "AcDbSymbolTable")
+	d.pair(70, n)
+	return h
+}
+
+// record starts a record of the table with the given handle.
+func (d *dxfWriter) record(kind, subclass, table string) {
+	d.pair(0, kind)
+	if d.opts.Version == DXFR12 {
+		return
+	}
+	d.object(table)
+	d.pair(100, "AcDbSymbolTableRecord")
+	d.pair(100, subclass)
+}
+
+func (d *dxfWriter) tables(layers []string) {
+	r12 := d.opts.Version == DXFR12
+	d.section("TABLES")
+	if !r12 {
+		d.table("VPORT", 0)
+		d.pair(0, "ENDTAB")
+	}
+
+	ltypes := []string{"ByBlock", "ByLayer", "Continuous"}
+	if r12 {
+		ltypes = []string{"CONTINUOUS"}
+	}
+	t := d.table("LTYPE", len(ltypes))
+	for _, l := range ltypes {
+		d.record("LTYPE", "AcDbLinetypeTableRecord", t)
+		d.pair(2, l)
+		d.pair(70, 0)
+		if l == "Continuous" || l == "CONTINUOUS" {
+			d.pair(3, "Solid line")
+		} else {
+			d.pair(3, "")
+		}
+		d.pair(72, 65)
+		d.pair(73, 0)
+		d.pair(40, 0.0)
+	}
+	d.pair(0, "ENDTAB")
+
+	t = d.table("LAYER", len(layers))
+	for _, l := range layers {
+		d.record("LAYER", "AcDbLayerTableRecord", t)
+		d.pair(2, l)
+		d.pair(70, 0)
+		d.pair(62, 7)
+		d.pair(6, ltypes[len(ltypes)-1])
+	}
+	d.pair(0, "ENDTAB")
+	if r12 {
+		d.pair(0, "ENDSEC")
+		return
+	}
+
+	t = d.table("STYLE", 1)
+	d.record("STYLE", "AcDbTextStyleTableRecord", t)
+	d.pair(2, "Standard")
+	d.pair(70, 0)
+	d.pair(40, 0.0)
+	d.pair(41, 1.0)
+	d.pair(50, 0.0)
+	d.pair(71, 0)
+	d.pair(42, 2.5)
+	d.pair(3, "txt")
+	d.pair(4, "")
+	d.pair(0, "ENDTAB")
+
+	for _, name := range []string{"VIEW", "UCS"} {
+		d.table(name, 0)
+		d.pair(0, "ENDTAB")
+	}
+
+	t = d.table("APPID", 1)
+	d.record("APPID", "AcDbRegAppTableRecord", t)
+	d.pair(2, "ACAD")
+	d.pair(70, 0)
+	d.pair(0, "ENDTAB")
+
+	d.table("DIMSTYLE", 0)
+	d.pair(100, "AcDbDimStyleTable")
+	d.pair(0, "ENDTAB")
+
+	t = d.table("BLOCK_RECORD", 2)
+	for _, b := range [][3]string{
+		{d.modelSpace, "*Model_Space", d.modelLayout},
+		{d.paperSpace, "*Paper_Space", d.paperLayout},
+	} {
+		d.pair(0, "BLOCK_RECORD")
+		d.pair(5, b[0])
+		d.pair(330, t)
+		d.pair(100, "AcDbSymbolTableRecord")
+		d.pair(100, "AcDbBlockTableRecord")
+		d.pair(2, b[1])
+		d.pair(340, b[2])
+	}
+	d.pair(0, "ENDTAB")
+	d.pair(0, "ENDSEC")
+}
+
+// blocks writes the empty model and paper space blocks of DXF 2000.
+func (d *dxfWriter) blocks() {
+	d.section("BLOCKS")
+	for _, b := range []struct {
+		record, name string
+		paper        bool
+	}{{d.modelSpace, "*Model_Space", false}, {d.paperSpace, "*Paper_Space", true}} {
+		d.pair(0, "BLOCK")
+		d.object(b.record)
+		d.pair(100, "AcDbEntity")
+		if b.paper {
+			d.pair(67, 1)
+		}
+		d.pair(8, "0")
+		d.pair(100, "AcDbBlockBegin")
+		d.pair(2, b.name)
+		d.pair(70, 0)
+		d.origin(10)
+		d.pair(3, b.name)
+		d.pair(1, "")
+
+		d.pair(0, "ENDBLK")
+		d.object(b.record)
+		d.pair(100, "AcDbEntity")
+		if b.paper {
+			d.pair(67, 1)
+		}
+		d.pair(8, "0")
+		d.pair(100, "AcDbBlockEnd")
+	}
+	d.pair(0, "ENDSEC")
+}
+
+// objects writes the root dictionary of DXF 2000 with the group and
+// layout dictionaries it must contain.
+func (d *dxfWriter) objects() {
+	root, groups, layouts := d.newHandle(), d.newHandle(), d.newHandle()
+	d.section("OBJECTS")
+	dictionary := func(handle, owner string, entries ...string) {
+		d.pair(0, "DICTIONARY")
+		d.pair(5, handle)
+		d.pair(330, owner)
+		d.pair(100, "AcDbDictionary")
+		d.pair(281, 1)
+		for i := 0; i < len(entries); i += 2 {
+			d.pair(3, entries[i])
+			d.pair(350, entries[i+1])
+		}
+	}
+	dictionary(root, "0", "ACAD_GROUP", groups, "ACAD_LAYOUT", layouts)
+	dictionary(groups, root)
+	dictionary(layouts, root, "Layout1", d.paperLayout, "Model", d.modelLayout)
+	d.layout(d.modelLayout, layouts, "Model", 1712, 0, d.modelSpace)
+	d.layout(d.paperLayout, layouts, "Layout1", 688, 1, d.paperSpace)
+	d.pair(0, "ENDSEC")
+}
+
+// layout writes a LAYOUT object with default plot settings for the block
+// record with the given handle.
+func (d *dxfWriter) layout(handle, owner, name string, flags, order int, record string) {
+	d.pair(0, "LAYOUT")
+	d.pair(5, handle)
+	d.pair(330, owner)
+	d.pair(100, "AcDbPlotSettings")
+	d.pair(1, "")
+	d.pair(2, "none_device")
+	d.pair(4, "")
+	d.pair(6, "")
+	for code := 40; code <= 49; code++ {
+		d.pair(code, 0.0)
+	}
+	d.pair(140, 0.0)
+	d.pair(141, 0.0)
+	d.pair(142, 1.0)
+	d.pair(143, 1.0)
+	d.pair(70, flags)
+	d.pair(72, 0)
+	d.pair(73, 0)
+	d.pair(74, 5)
+	d.pair(7, "")
+	d.pair(75, 16)
+	d.pair(147, 1.0)
+	d.pair(148, 0.0)
+	d.pair(149, 0.0)
+	d.pair(100, "AcDbLayout")
+	d.pair(1, name)
+	d.pair(70, 1)
+	d.pair(71, order)
+	d.pair(10, 0.0)
+	d.pair(20, 0.0)
+	d.pair(11, 420.0)
+	d.pair(21, 297.0)
+	d.origin(12)
+	d.origin(14)
+	d.origin(15)
+	d.pair(146, 0.0)
+	d.origin(13)
+	d.pair(16, 1.0)
+	d.pair(26, 0.0)
+	d.pair(36, 0.0)
+	d.pair(17, 0.0)
+	d.pair(27, 1.0)
+	d.pair(37, 0.0)
+	d.pair(76, 0)
+	d.pair(330, record)
+}
+
+// start writes the common group codes of an entity, which DXF 2000
+// entities add to model space.
+func (d *dxfWriter) start(entity, subclass string) {
+	d.pair(0, entity)
+	if d.opts.Version == DXFR12 {
+		d.pair(8, d.layer)
+		d.color62()
+		return
+	}
+	d.object(d.modelSpace)
+	d.pair(100, "AcDbEntity")
+	d.pair(8, d.layer)
+	d.color62()
+	d.pair(100, subclass)
+}
+
+func (d *dxfWriter) color62() {
+	d.pair(62, aciColor(d.color))
+	if d.opts.TrueColor && d.opts.Version != DXFR12 {
+		c := d.color
+		d.pair(420, int(c.R)<<16|int(c.G)<<8|int(c.B))
+	}
+}
+
+func (d *dxfWriter) shape(s Shape) {
+	d.layer = dxfLayer(s.GroupID)
+	paint := s.Fill
+	if s.Stroked() || !s.Filled() {
+		paint = s.Stroke
+	}
+	d.color, _ = ParseColor(paint)
+
+	if !d.opts.Curves {
+		for _, seg := range s.Segments {
+			d.polyline(seg)
+		}
+		return
+	}
+
+	for _, sub := range splitSubpaths(s.Instructions) {
+		switch {
+		case sub[0].Kind == CircleInstruction:
+			d.start("CIRCLE", "AcDbCircle")
+			d.point(10, *sub[0].M)
+			d.pair(40, *sub[0].Radius*d.scale)
+		case d.opts.Version != DXFR12 && hasCurves(sub):
+			d.spline(sub)
+		default:
+			for _, seg := range flattenInstructions(sub, s.tolerance) {
+				d.polyline(seg)
+			}
+		}
+	}
+}
+
+func (d *dxfWriter) polyline(s Segment) {
+	pts := s.Points
+	if s.Closed && len(pts) > 2 && samePoint(pts[0], pts[len(pts)-1]) {
+		pts = pts[:len(pts)-1]
+	}
+	flags := 0
+	if s.Closed {
+		flags = 1
+	}
+
+	if d.opts.Version == DXFR12 {
+		d.start("POLYLINE", "")
+		d.pair(66, 1)
+		d.point(10, [2]float64{})
+		d.pair(70, flags)
+		for _, p := range pts {
+			d.pair(0, "VERTEX")
+			d.pair(8, d.layer)
+			d.point(10, p)
+		}
+		d.pair(0, "SEQEND")
+		d.pair(8, d.layer)
+		return
+	}
+
+	d.start("LWPOLYLINE", "AcDbPolyline")
+	d.pair(90, len(pts))
+	d.pair(70, flags)
+	for _, p := range pts {
+		d.point(10, p)
+	}
+}
+
+// spline writes a subpath as a cubic B-spline whose knots make every
+// segment an exact Bézier. Straight lines become Béziers with control
+// points on the line.
+func (d *dxfWriter) spline(sub []*DrawingInstruction) {
+	var ctrl [][2]float64
+	var start, cur [2]float64
+	lineTo := func(p [2]float64) {
+		ctrl = append(ctrl,
+			[2]float64{cur[0] + (p[0]-cur[0])/3, cur[1] + (p[1]-cur[1])/3},
+			[2]float64{cur[0] + (p[0]-cur[0])*2/3, cur[1] + (p[1]-cur[1])*2/3},
+			p)
+		cur = p
+	}
+	for _, di := range sub {
+		switch di.Kind {
+		case MoveInstruction:
+			start, cur = *di.M, *di.M
+			ctrl = append(ctrl, cur)
+		case LineInstruction:
+			lineTo(*di.M)
+		case CurveInstruction:
+			cp := di.CurvePoints
+			ctrl = append(ctrl, *cp.C1, *cp.C2, *cp.T)
+			cur = *cp.T
+		case CloseInstruction:
+			if cur != start {
+				lineTo(start)
+			}
+		}
+	}
+
+	// the knots clamp every Bézier, so a closed subpath is a closed
+	// curve but not a periodic spline
+	spans := (len(ctrl) - 1) / 3
+	d.start("SPLINE", "AcDbSpline")
+	d.pair(70, 8)
+	d.pair(71, 3)
+	d.pair(72, len(ctrl)+4)
+	d.pair(73, len(ctrl))
+	d.pair(74, 0)
+	d.pair(40, 0.0)
+	for i := 0; i <= spans; i++ {
+		for j := 0; j < 3; j++ {
+			d.pair(40, float64(i))
+		}
+	}
+	d.pair(40, float64(spans))
+	for _, p := range ctrl {
+		d.point(10, p)
+	}
+}
+
+// splitSubpaths splits an instruction stream into subpaths, each
+// starting with a Move or consisting of a single Circle. Paint and text
+// instructions are dropped.
+func splitSubpaths(instrs []*DrawingInstruction) [][]*DrawingInstruction {
+	var subs [][]*DrawingInstruction
+	for _, di := range instrs {
+		switch di.Kind {
+		case PaintInstruction, TextInstruction:
+		case CircleInstruction:
+			subs = append(subs, []*DrawingInstruction{di})
+		case MoveInstruction:
+			subs = append(subs, []*DrawingInstruction{di})
+		default:
+			if len(subs) > 0 && subs[len(subs)-1][0].Kind == MoveInstruction {
+				subs[len(subs)-1] = append(subs[len(subs)-1], di)
+			}
+		}
+	}
+	return subs
+}
+
+func hasCurves(instrs []*DrawingInstruction) bool {
+	for _, di := range instrs {
+		if di.Kind == CurveInstruction {
+			return true
+		}
+	}
+	return false
+}
+
+// aciPalette holds the RGB values of the AutoCAD Color Index, computed
+// from its hue, value and saturation layout.
+var aciPalette = func() [256]color.NRGBA {
+	var p [256]color.NRGBA
+	basic := []color.NRGBA{
+		{}, {255, 0, 0, 255}, {255, 255, 0, 255}, {0, 255, 0, 255}, {0, 255, 255, 255},
+		{0, 0, 255, 255}, {255, 0, 255, 255}, {255, 255, 255, 255}, {128, 128, 128, 255}, {192, 192, 192, 255},
+	}
+	copy(p[:], basic)
+	values := []float64{255, 165, 127, 76, 38}
+	for i := 10; i < 250; i++ {
+		hue := float64(i/10-1) * 15
+		v := values[(i%10)/2]
+		s := 1.0
+		if i%2 == 1 {
+			s = 0.5
+		}
+		p[i] = hsvColor(hue, s, v)
+	}
+	for i, g := range []uint8{51, 91, 132, 173, 214, 255} {
+		p[250+i] = color.NRGBA{g, g, g, 255}
+	}
+	return p
+}()
+
+func hsvColor(hue, s, v float64) color.NRGBA {
+	c := v * s
+	x := c * (1 - math.Abs(math.Mod(hue/60, 2)-1))
+	var r, g, b float64
+	switch {
+	case hue < 60:
+		r, g = c, x
+	case hue < 120:
+		r, g = x, c
+	case hue < 180:
+		g, b = c, x
+	case hue < 240:
+		g, b = x, c
+	case hue < 300:
+		r, b = x, c
+	default:
+		r, b = c, x
+	}
+	m := v - c
+	return color.NRGBA{uint8(r + m + 0.5), uint8(g + m + 0.5), uint8(b + m + 0.5), 255}
+}
+
+// aciColor returns the index of the ACI colour closest to c. Black and
+// white both map to 7, which CAD programs draw in the foreground colour.
+func aciColor(c color.NRGBA) int {
+	if c.R == c.G && c.G == c.B && (c.R == 0 || c.R == 255) {
+		return 7
+	}
+	best, dist := 7, math.Inf(1)
+	for i := 1; i < 256; i++ {
+		p := aciPalette[i]
+		dr, dg, db := float64(c.R)-float64(p.R), float64(c.G)-float64(p.G), float64(c.B)-float64(p.B)
+		if d := dr*dr + dg*dg + db*db; d < dist {
+			best, dist = i, d
+		}
+	}
+	return best
+}
@@ -0,0 +1,155 @@
+package svg
+
+import (
+	"bytes"
+	"image/color"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// dxfPairs returns the group code and value pairs of a DXF file.
+func dxfPairs(t *testing.T, dxf string) [][2]string {
+	lines := strings.Split(strings.TrimSuffix(dxf, "\n"), "\n")
+	require.Zero(t, len(lines)%2)
+	var pairs [][2]string
+	for i := 0; i < len(lines); i += 2 {
+		pairs = append(pairs, [2]string{strings.TrimSpace(lines[i]), lines[i+1]})
+	}
+	return pairs
+}
+
+// dxfEntity returns the pairs of the first entity of the given type.
+func dxfEntity(pairs [][2]string, entity string) [][2]string {
+	for i, p := range pairs {
+		if p == [2]string{"0", entity} {
+			j := i + 1
+			for j < len(pairs) && pairs[j][0] != "0" {
+				j++
+			}
+			return pairs[i:j]
+		}
+	}
+	return nil
+}
+
+// checkDXF2000 checks the structure AutoCAD requires of a DXF 2000 file:
+// every handle is unique and below $HANDSEED, every reference points to
+// an existing handle, and entities belong to model space.
+func checkDXF2000(t *testing.T, pairs [][2]string) {
+	var sections []string
+	for i, p := range pairs {
+		if p == [2]string{"0", "SECTION"} {
+			sections = append(sections, pairs[i+1][1])
+		}
+	}
+	require.Equal(t, []string{"HEADER", "CLASSES", "TABLES", "BLOCKS", "ENTITIES", "OBJECTS"}, sections)
+
+	seed, err := strconv.ParseUint(pairs[indexOf(pairs, [2]string{"9", "$HANDSEED"})+1][1], 16, 64)
+	require.NoError(t, err)
+	handles := map[string]bool{}
+	for i, p := range pairs {
+		if p[0] == "5" && pairs[i-1] != [2]string{"9", "$HANDSEED"} {
+			require.False(t, handles[p[1]], "duplicate handle %s", p[1])
+			handles[p[1]] = true
+			h, err := strconv.ParseUint(p[1], 16, 64)
+			require.NoError(t, err)
+			require.Less(t, h, seed)
+		}
+	}
+	for _, p := range pairs {
+		switch p[0] {
+		case "330", "340", "350":
+			require.True(t, p[1] == "0" || handles[p[1]], "dangling reference %s %s", p[0], p[1])
+		}
+	}
+
+	// the first block record is model space
+	modelSpace := pairs[indexOf(pairs, [2]string{"0", "BLOCK_RECORD"})+1]
+	require.Equal(t, "5", modelSpace[0])
+	inEntities := false
+	for i, p := range pairs {
+		if p[0] == "2" && pairs[i-1] == [2]string{"0", "SECTION"} {
+			inEntities = p[1] == "ENTITIES"
+		}
+		if inEntities && p[0] == "0" && p[1] != "ENDSEC" && p[1] != "SECTION" {
+			require.Equal(t, [2]string{"330", modelSpace[1]}, pairs[i+2], p[1])
+		}
+	}
+	for _, table := range []string{"VPORT", "LTYPE", "LAYER", "STYLE", "VIEW", "UCS", "APPID", "DIMSTYLE", "BLOCK_RECORD"} {
+		require.Contains(t, pairs, [2]string{"2", table})
+	}
+	for _, name := range []string{"*Model_Space", "*Paper_Space", "Continuous", "ACAD_GROUP", "ACAD_LAYOUT"} {
+		require.NotEqual(t, -1, indexOf(pairs, [2]string{"2", name})+indexOf(pairs, [2]string{"3", name})+1, name)
+	}
+}
+
+func indexOf(pairs [][2]string, p [2]string) int {
+	for i, q := range pairs {
+		if q == p {
+			return i
+		}
+	}
+	return -1
+}
+
+func TestWriteDXF(t *testing.T) {
+	svg, err := ParseSvg(`<svg>
+<g id="cut"><path d="M0 0 L10 0 L10 10 Z" stroke="#ff0000" stroke-width="1"/></g>
+<path d="M0 0 C0 10 10 10 10 0" stroke="blue" stroke-width="1" fill="none"/>
+<circle cx="5" cy="5" r="2" fill="black"/>
+</svg>`, "test", 0)
+	require.NoError(t, err)
+	shapes, err := svg.Shapes()
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	require.NoError(t, WriteDXF(&buf, shapes, &DXFOptions{Units: "mm", TrueColor: true}))
+	pairs := dxfPairs(t, buf.String())
+	require.Contains(t, pairs, [2]string{"1", "AC1015"})
+	require.Contains(t, pairs, [2]string{"70", "4"})
+	require.Contains(t, pairs, [2]string{"2", "cut"})
+	require.Equal(t, [2]string{"0", "EOF"}, pairs[len(pairs)-1])
+	checkDXF2000(t, pairs)
+
+	poly := dxfEntity(pairs, "LWPOLYLINE")
+	require.Contains(t, poly, [2]string{"8", "cut"})
+	require.Contains(t, poly, [2]string{"62", "1"})
+	require.Contains(t, poly, [2]string{"420", "16711680"})
+	require.Contains(t, poly, [2]string{"90", "3"})
+	require.Contains(t, poly, [2]string{"70", "1"})
+	require.Contains(t, poly, [2]string{"20", "-2.6458333333333335"})
+
+	buf.Reset()
+	require.NoError(t, WriteDXF(&buf, shapes, &DXFOptions{Curves: true}))
+	pairs = dxfPairs(t, buf.String())
+	checkDXF2000(t, pairs)
+	spline := dxfEntity(pairs, "SPLINE")
+	require.Contains(t, spline, [2]string{"73", "4"})
+	require.Contains(t, spline, [2]string{"72", "8"})
+	circle := dxfEntity(pairs, "CIRCLE")
+	require.Contains(t, circle, [2]string{"40", "2"})
+	require.Contains(t, circle, [2]string{"20", "-5"})
+
+	buf.Reset()
+	require.NoError(t, WriteDXF(&buf, shapes, &DXFOptions{Version: DXFR12, Curves: true}))
+	pairs = dxfPairs(t, buf.String())
+	require.Contains(t, pairs, [2]string{"1", "AC1009"})
+	require.Contains(t, dxfEntity(pairs, "LTYPE"), [2]string{"2", "CONTINUOUS"})
+	require.NotContains(t, pairs, [2]string{"330", "0"})
+	require.Nil(t, dxfEntity(pairs, "SPLINE"))
+	require.Nil(t, dxfEntity(pairs, "LWPOLYLINE"))
+	require.NotNil(t, dxfEntity(pairs, "POLYLINE"))
+	require.NotNil(t, dxfEntity(pairs, "CIRCLE"))
+
+	require.Error(t, WriteDXF(&buf, shapes, &DXFOptions{Units: "furlong"}))
+}
+
+func TestACIColor(t *testing.T) {
+	require.Equal(t, 1, aciColor(color.NRGBA{255, 0, 0, 255}))
+	require.Equal(t, 5, aciColor(color.NRGBA{0, 0, 250, 255}))
+	require.Equal(t, 7, aciColor(color.NRGBA{0, 0, 0, 255}))
+	require.Equal(t, 8, aciColor(color.NRGBA{128, 128, 128, 255}))
+}
@@ -0,0 +1,497 @@
+package svg
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image/color"
+	"io"
+	"math"
+
+	mt "github.com/rustyoz/Mtransform"
+)
+
+// instructionNames are the stable names of the instruction types used
+// by the text encodings.
+var instructionNames = [...]string{
+	MoveInstruction:   "move",
+	CircleInstruction: "circle",
+	CurveInstruction:  "curve",
+	LineInstruction:   "line",
+	CloseInstruction:  "close",
+	PaintInstruction:  "paint",
+	TextInstruction:   "text",
+}
+
+func (t InstructionType) String() string {
+	if t >= 0 && int(t) < len(instructionNames) {
+		return instructionNames[t]
+	}
+	return fmt.Sprintf("InstructionType(%d)", int(t))
+}
+
+// MarshalText implements the encoding.TextMarshaler interface
+func (t InstructionType) MarshalText() ([]byte, error) {
+	if t < 0 || int(t) >= len(instructionNames) {
+		return nil, fmt.Errorf("invalid instruction type %d", int(t))
+	}
+	return []byte(instructionNames[t]), nil
+}
+
+// UnmarshalText implements the encoding.TextUnmarshaler interface
+func (t *InstructionType) UnmarshalText(text []byte) error {
+	for i, name := range instructionNames {
+		if string(text) == name {
+			*t = InstructionType(i)
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown instruction type %q", text)
+}
+
+// instructionMagic starts every binary encoded instruction stream. The
+// last byte is the format version. Version 2 added the letter spacing of
+// text runs; version 1 streams are still read.
+var instructionMagic = []byte{'S', 'V', 'G', 'I', 2}
+
+// Limits on decoded values, so that corrupt input cannot request huge
+// allocations.
+const (
+	maxEncodedString = 1 << 16
+	maxEncodedDashes = 1 << 10
+	maxEncodedStops  = 1 << 10
+)
+
+// These are the bits of the field mask that precedes every binary
+// encoded instruction.
+const (
+	fieldM = 1 << iota
+	fieldCurvePoints
+	fieldC1
+	fieldC2
+	fieldT
+	fieldRadius
+	fieldStrokeWidth
+	fieldFill
+	fieldFillRule
+	fieldStroke
+	fieldStrokeLineCap
+	fieldStrokeLineJoin
+	fieldStrokeMiterLimit
+	fieldStrokeDashArray
+	fieldStrokeDashOffset
+	fieldOpacity
+	fieldFillOpacity
+	fieldStrokeOpacity
+	fieldText
+	fieldFillGradient
+	fieldStrokeGradient
+)
+
+// EncodeInstructions writes instrs to w in a compact binary format that
+// DecodeInstructions reads back. Every instruction is written as its
+// kind, a mask of the fields that are set and the values of those
+// fields; numbers are stored as 64 bit floats so that they round-trip
+// exactly.
+func EncodeInstructions(w io.Writer, instrs []*DrawingInstruction) error {
+	bw := bufio.NewWriter(w)
+	e := &instructionEncoder{w: bw}
+	e.write(instructionMagic)
+	e.uvarint(uint64(len(instrs)))
+	for _, di := range instrs {
+		e.instruction(di)
+	}
+	if e.err != nil {
+		return e.err
+	}
+	return bw.Flush()
+}
+
+type instructionEncoder struct {
+	w   *bufio.Writer
+	buf [binary.MaxVarintLen64]byte
+	err error
+}
+
+func (e *instructionEncoder) write(b []byte) {
+	if e.err == nil {
+		_, e.err = e.w.Write(b)
+	}
+}
+
+func (e *instructionEncoder) uvarint(v uint64) {
+	e.write(e.buf[:binary.PutUvarint(e.buf[:], v)])
+}
+
+func (e *instructionEncoder) float(v float64) {
+	binary.LittleEndian.PutUint64(e.buf[:8], math.Float64bits(v))
+	e.write(e.buf[:8])
+}
+
+func (e *instructionEncoder) tuple(t *Tuple) {
+	e.float(t[0])
+	e.float(t[1])
+}
+
+func (e *instructionEncoder) string(s string) {
+	e.uvarint(uint64(len(s)))
+	e.write([]byte(s))
+}
+
+func (e *instructionEncoder) instruction(di *DrawingInstruction) {
+	var mask uint64
+	set := func(bit uint64, ok bool) {
+		if ok {
+			mask |= bit
+		}
+	}
+	set(fieldM, di.M != nil)
+	if cp := di.CurvePoints; cp != nil {
+		mask |= fieldCurvePoints
+		set(fieldC1, cp.C1 != nil)
+		set(fieldC2, cp.C2 != nil)
+		set(fieldT, cp.T != nil)
+	}
+	set(fieldRadius, di.Radius != nil)
+	set(fieldStrokeWidth, di.StrokeWidth != nil)
+	set(fieldFill, di.Fill != nil)
+	set(fieldFillRule, di.FillRule != nil)
+	set(fieldStroke, di.Stroke != nil)
+	set(fieldStrokeLineCap, di.StrokeLineCap != nil)
+	set(fieldStrokeLineJoin, di.StrokeLineJoin != nil)
+	set(fieldStrokeMiterLimit, di.StrokeMiterLimit != nil)
+	set(fieldStrokeDashArray, di.StrokeDashArray != nil)
+	set(fieldStrokeDashOffset, di.StrokeDashOffset != nil)
+	set(fieldOpacity, di.Opacity != nil)
+	set(fieldFillOpacity, di.FillOpacity != nil)
+	set(fieldStrokeOpacity, di.StrokeOpacity != nil)
+	set(fieldText, di.Text != nil)
+	set(fieldFillGradient, di.FillGradient != nil)
+	set(fieldStrokeGradient, di.StrokeGradient != nil)
+
+	e.uvarint(uint64(di.Kind))
+	e.uvarint(mask)
+	if di.M != nil {
+		e.tuple(di.M)
+	}
+	if cp := di.CurvePoints; cp != nil {
+		for _, t := range []*Tuple{cp.C1, cp.C2, cp.T} {
+			if t != nil {
+				e.tuple(t)
+			}
+		}
+	}
+	for _, f := range []*float64{di.Radius, di.StrokeWidth} {
+		if f != nil {
+			e.float(*f)
+		}
+	}
+	for _, s := range []*string{di.Fill, di.FillRule, di.Stroke, di.StrokeLineCap, di.StrokeLineJoin} {
+		if s != nil {
+			e.string(*s)
+		}
+	}
+	if di.StrokeMiterLimit != nil {
+		e.float(*di.StrokeMiterLimit)
+	}
+	if di.StrokeDashArray != nil {
+		e.uvarint(uint64(len(di.StrokeDashArray)))
+		for _, d := range di.StrokeDashArray {
+			e.float(d)
+		}
+	}
+	for _, f := range []*float64{di.StrokeDashOffset, di.Opacity, di.FillOpacity, di.StrokeOpacity} {
+		if f != nil {
+			e.float(*f)
+		}
+	}
+	if t := di.Text; t != nil {
+		e.textRun(t)
+	}
+	for _, g := range []*Gradient{di.FillGradient, di.StrokeGradient} {
+		if g != nil {
+			e.gradient(g)
+		}
+	}
+}
+
+func (e *instructionEncoder) gradient(g *Gradient) {
+	var radial uint64
+	if g.Radial {
+		radial = 1
+	}
+	e.uvarint(radial)
+	for _, s := range []string{g.ID, g.Units, g.SpreadMethod} {
+		e.string(s)
+	}
+	for _, row := range g.Transform[:2] {
+		for _, v := range row {
+			e.float(v)
+		}
+	}
+	for _, v := range []float64{g.X1, g.Y1, g.X2, g.Y2, g.Cx, g.Cy, g.R, g.Fx, g.Fy} {
+		e.float(v)
+	}
+	e.uvarint(uint64(len(g.Stops)))
+	for _, s := range g.Stops {
+		e.float(s.Offset)
+		e.write([]byte{s.Color.R, s.Color.G, s.Color.B, s.Color.A})
+	}
+}
+
+func (e *instructionEncoder) textRun(t *TextRun) {
+	e.string(t.Text)
+	e.float(t.X)
+	e.float(t.Y)
+	var relative uint64
+	if t.Relative {
+		relative = 1
+	}
+	e.uvarint(relative)
+	e.uvarint(uint64(len(t.Rotate)))
+	for _, r := range t.Rotate {
+		e.float(r)
+	}
+	for _, s := range []string{t.FontFamily, t.FontWeight, t.TextAnchor, t.DominantBaseline, t.TextPath, t.StartOffset} {
+		e.string(s)
+	}
+	e.float(t.FontSize)
+	e.float(t.LetterSpacing)
+	for _, v := range t.Transform {
+		e.float(v)
+	}
+}
+
+// DecodeInstructions reads an instruction stream written by
+// EncodeInstructions.
+func DecodeInstructions(r io.Reader) ([]*DrawingInstruction, error) {
+	d := &instructionDecoder{r: bufio.NewReader(r)}
+	magic := make([]byte, len(instructionMagic))
+	if _, err := io.ReadFull(d.r, magic); err != nil || string(magic[:4]) != string(instructionMagic[:4]) {
+		return nil, errors.New("not an encoded instruction stream")
+	}
+	if magic[4] < 1 || magic[4] > instructionMagic[4] {
+		return nil, fmt.Errorf("unsupported instruction stream version %d", magic[4])
+	}
+	d.version = magic[4]
+
+	n := d.uvarint()
+	var instrs []*DrawingInstruction
+	for i := uint64(0); i < n && d.err == nil; i++ {
+		di := d.instruction()
+		if d.err != nil {
+			return nil, fmt.Errorf("error decoding instruction %d: %s", i, d.err)
+		}
+		instrs = append(instrs, di)
+	}
+	if d.err != nil {
+		return nil, d.err
+	}
+	return instrs, nil
+}
+
+type instructionDecoder struct {
+	r       *bufio.Reader
+	buf     [8]byte
+	err     error
+	version byte
+}
+
+func (d *instructionDecoder) uvarint() uint64 {
+	if d.err != nil {
+		return 0
+	}
+	var v uint64
+	v, d.err = binary.ReadUvarint(d.r)
+	if d.err == io.EOF {
+		d.err = io.ErrUnexpectedEOF
+	}
+	return v
+}
+
+func (d *instructionDecoder) float() *float64 {
+	if d.err != nil {
+		return new(float64)
+	}
+	if _, d.err = io.ReadFull(d.r, d.buf[:]); d.err != nil {
+		d.err = io.ErrUnexpectedEOF
+	}
+	f := math.Float64frombits(binary.LittleEndian.Uint64(d.buf[:]))
+	return &f
+}
+
+func (d *instructionDecoder) tuple() *Tuple {
+	x := d.float()
+	y := d.float()
+	return &Tuple{*x, *y}
+}
+
+func (d *instructionDecoder) string() *string {
+	n := d.uvarint()
+	if n > maxEncodedString {
+		d.fail(fmt.Errorf("string of %d bytes is too long", n))
+	}
+	var s string
+	if d.err == nil {
+		b := make([]byte, n)
+		if _, err := io.ReadFull(d.r, b); err != nil {
+			d.fail(io.ErrUnexpectedEOF)
+		}
+		s = string(b)
+	}
+	return &s
+}
+
+func (d *instructionDecoder) fail(err error) {
+	if d.err == nil {
+		d.err = err
+	}
+}
+
+func (d *instructionDecoder) instruction() *DrawingInstruction {
+	kind := d.uvarint()
+	mask := d.uvarint()
+	if d.err != nil {
+		return nil
+	}
+	if kind >= uint64(len(instructionNames)) {
+		d.fail(fmt.Errorf("invalid instruction type %d", kind))
+		return nil
+	}
+	if mask >= fieldStrokeGradient<<1 {
+		d.fail(fmt.Errorf("invalid field mask %#x", mask))
+		return nil
+	}
+
+	di := &DrawingInstruction{Kind: InstructionType(kind)}
+	has := func(bit uint64) bool { return mask&bit != 0 }
+	if has(fieldM) {
+		di.M = d.tuple()
+	}
+	if has(fieldCurvePoints) {
+		di.CurvePoints = &CurvePoints{}
+		if has(fieldC1) {
+			di.CurvePoints.C1 = d.tuple()
+		}
+		if has(fieldC2) {
+			di.CurvePoints.C2 = d.tuple()
+		}
+		if has(fieldT) {
+			di.CurvePoints.T = d.tuple()
+		}
+	}
+	if has(fieldRadius) {
+		di.Radius = d.float()
+	}
+	if has(fieldStrokeWidth) {
+		di.StrokeWidth = d.float()
+	}
+	if has(fieldFill) {
+		di.Fill = d.string()
+	}
+	if has(fieldFillRule) {
+		di.FillRule = d.string()
+	}
+	if has(fieldStroke) {
+		di.Stroke = d.string()
+	}
+	if has(fieldStrokeLineCap) {
+		di.StrokeLineCap = d.string()
+	}
+	if has(fieldStrokeLineJoin) {
+		di.StrokeLineJoin = d.string()
+	}
+	if has(fieldStrokeMiterLimit) {
+		di.StrokeMiterLimit = d.float()
+	}
+	if has(fieldStrokeDashArray) {
+		n := d.uvarint()
+		if n > maxEncodedDashes {
+			d.fail(fmt.Errorf("dash array of %d values is too long", n))
+			return nil
+		}
+		di.StrokeDashArray = make([]float64, n)
+		for i := range di.StrokeDashArray {
+			di.StrokeDashArray[i] = *d.float()
+		}
+	}
+	if has(fieldStrokeDashOffset) {
+		di.StrokeDashOffset = d.float()
+	}
+	if has(fieldOpacity) {
+		di.Opacity = d.float()
+	}
+	if has(fieldFillOpacity) {
+		di.FillOpacity = d.float()
+	}
+	if has(fieldStrokeOpacity) {
+		di.StrokeOpacity = d.float()
+	}
+	if has(fieldText) {
+		di.Text = d.textRun()
+	}
+	if has(fieldFillGradient) {
+		di.FillGradient = d.gradient()
+	}
+	if has(fieldStrokeGradient) {
+		di.StrokeGradient = d.gradient()
+	}
+	return di
+}
+
+func (d *instructionDecoder) gradient() *Gradient {
+	g := &Gradient{Radial: d.uvarint() != 0, Transform: mt.Identity()}
+	for _, s := range []*string{&g.ID, &g.Units, &g.SpreadMethod} {
+		*s = *d.string()
+	}
+	for i := range g.Transform[:2] {
+		for j := range g.Transform[i] {
+			g.Transform[i][j] = *d.float()
+		}
+	}
+	for _, v := range []*float64{&g.X1, &g.Y1, &g.X2, &g.Y2, &g.Cx, &g.Cy, &g.R, &g.Fx, &g.Fy} {
+		*v = *d.float()
+	}
+	n := d.uvarint()
+	if n > maxEncodedStops {
+		d.fail(fmt.Errorf("gradient of %d stops is too long", n))
+		return nil
+	}
+	for i := uint64(0); i < n && d.err == nil; i++ {
+		s := GradientStop{Offset: *d.float()}
+		var rgba [4]byte
+		if _, err := io.ReadFull(d.r, rgba[:]); err != nil {
+			d.fail(io.ErrUnexpectedEOF)
+		}
+		s.Color = color.NRGBA{R: rgba[0], G: rgba[1], B: rgba[2], A: rgba[3]}
+		g.Stops = append(g.Stops, s)
+	}
+	return g
+}
+
+func (d *instructionDecoder) textRun() *TextRun {
+	t := &TextRun{Text: *d.string(), X: *d.float(), Y: *d.float(), Relative: d.uvarint() != 0}
+	n := d.uvarint()
+	if n > maxEncodedString {
+		d.fail(fmt.Errorf("rotate list of %d values is too long", n))
+		return nil
+	}
+	if n > 0 && d.err == nil {
+		t.Rotate = make([]float64, n)
+		for i := range t.Rotate {
+			t.Rotate[i] = *d.float()
+		}
+	}
+	for _, s := range []*string{&t.FontFamily, &t.FontWeight, &t.TextAnchor, &t.DominantBaseline, &t.TextPath, &t.StartOffset} {
+		*s = *d.string()
+	}
+	t.FontSize = *d.float()
+	if d.version >= 2 {
+		t.LetterSpacing = *d.float()
+	}
+	for i := range t.Transform {
+		t.Transform[i] = *d.float()
+	}
+	return t
+}
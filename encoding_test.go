@@ -0,0 +1,80 @@
+package svg
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func encodingInstructions(t *testing.T) []*DrawingInstruction {
+	svg, err := ParseSvg(`<svg>
+<path d="M0 0 C1 1 2 2 3 3 L4 0 Z" fill="red" stroke="blue" stroke-width="2" stroke-dasharray="1 2" stroke-linecap="round" opacity="0.5"/>
+<circle cx="5" cy="5" r="2" fill="green"/>
+<text x="1" y="2" rotate="10" font-size="12" transform="scale(2)">a<tspan dx="3" font-family="serif">b</tspan></text>
+</svg>`, "test", 0)
+	require.NoError(t, err)
+	var instrs []*DrawingInstruction
+	for _, e := range svg.children() {
+		is, err := collectInstructions(e)
+		require.NoError(t, err)
+		instrs = append(instrs, is...)
+	}
+	return instrs
+}
+
+func TestInstructionJSON(t *testing.T) {
+	instrs := encodingInstructions(t)
+
+	data, err := json.Marshal(instrs)
+	require.NoError(t, err)
+	require.Contains(t, string(data), `{"kind":"move","m":[0,0]}`)
+	require.Contains(t, string(data), `{"kind":"curve","curvePoints":{"c1":[1,1],"c2":[2,2],"t":[3,3]}}`)
+	require.Contains(t, string(data), `"strokeDashArray":[1,2]`)
+	require.Contains(t, string(data), `{"kind":"circle","m":[5,5],"radius":2}`)
+
+	var decoded []*DrawingInstruction
+	require.NoError(t, json.Unmarshal(data, &decoded))
+	require.Equal(t, instrs, decoded)
+
+	require.Error(t, json.Unmarshal([]byte(`[{"kind":"spline"}]`), &decoded))
+	require.Equal(t, "paint", PaintInstruction.String())
+	require.Equal(t, "InstructionType(42)", InstructionType(42).String())
+}
+
+func TestInstructionBinary(t *testing.T) {
+	instrs := encodingInstructions(t)
+
+	var buf bytes.Buffer
+	require.NoError(t, EncodeInstructions(&buf, instrs))
+	data := buf.Bytes()
+
+	decoded, err := DecodeInstructions(bytes.NewReader(data))
+	require.NoError(t, err)
+	require.Equal(t, instrs, decoded)
+
+	for i := 0; i < len(data); i++ {
+		_, err := DecodeInstructions(bytes.NewReader(data[:i]))
+		require.Error(t, err, "truncated at %d", i)
+	}
+	_, err = DecodeInstructions(bytes.NewReader([]byte("SVGI\x03")))
+	require.EqualError(t, err, "unsupported instruction stream version 3")
+
+	// version 1 streams have no letter spacing after the font size
+	run := &TextRun{Text: "a", FontSize: 12.5}
+	buf.Reset()
+	require.NoError(t, EncodeInstructions(&buf, []*DrawingInstruction{{Kind: TextInstruction, Text: run}}))
+	data = buf.Bytes()
+	size := make([]byte, 8)
+	binary.LittleEndian.PutUint64(size, math.Float64bits(12.5))
+	i := bytes.Index(data, size) + 8
+	v1 := append(append([]byte("SVGI\x01"), data[5:i]...), data[i+8:]...)
+	decoded, err = DecodeInstructions(bytes.NewReader(v1))
+	require.NoError(t, err)
+	require.Equal(t, run, decoded[0].Text)
+	_, err = DecodeInstructions(bytes.NewReader([]byte("SVGI\x01\x01\x09\x00")))
+	require.Error(t, err)
+}
@@ -0,0 +1,222 @@
+package svg
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// ParseError describes an error in an element of an SVG document. It
+// wraps the underlying cause, so errors.Is and errors.As see through it.
+type ParseError struct {
+	// Document is the path of the external document, loaded for a use
+	// element, that contains the element. It is empty for the document
+	// being parsed.
+	Document string
+	// Line and Column are the 1-based position of the element's start
+	// tag in the document, or zero if the element was not decoded from
+	// a document. Columns count bytes.
+	Line, Column int
+	// Tag and ID identify the element.
+	Tag string
+	ID  string
+	// Ancestors are the elements containing the element, outermost
+	// first, written as the tag followed by "#" and the ID if there is
+	// one, for example "g#layer1".
+	Ancestors []string
+	// Offset is the byte offset within the path data (the d attribute)
+	// of the command that failed, or -1 if the error is not in path data.
+	Offset int
+	Err    error
+}
+
+func (e *ParseError) Error() string {
+	var b strings.Builder
+	if e.Document != "" {
+		b.WriteString(e.Document)
+		b.WriteString(":")
+		if e.Line == 0 {
+			b.WriteString(" ")
+		}
+	}
+	if e.Line > 0 {
+		fmt.Fprintf(&b, "%d:%d: ", e.Line, e.Column)
+	}
+	b.WriteString(elementName(e.Tag, e.ID))
+	if len(e.Ancestors) > 0 {
+		b.WriteString(" in ")
+		b.WriteString(strings.Join(e.Ancestors, " > "))
+	}
+	if e.Offset >= 0 {
+		fmt.Fprintf(&b, ": d offset %d", e.Offset)
+	}
+	fmt.Fprintf(&b, ": %v", e.Err)
+	return b.String()
+}
+
+// Unwrap returns the cause of the error.
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// ParseOptions controls how problems in a document are handled.
+type ParseOptions struct {
+	// Strict makes every problem an error. By default the parser
+	// recovers the way SVG user agents do and records a Warning:
+	// invalid attributes are ignored, elements whose attributes cannot
+	// be decoded are skipped and path data is drawn up to the first
+	// error.
+	Strict bool
+}
+
+// Warning is a problem in a document that lenient parsing recovered
+// from.
+type Warning struct {
+	ParseError
+}
+
+func (w Warning) String() string {
+	return w.ParseError.Error()
+}
+
+// warn handles a recoverable problem in the element tag with the given
+// ID at pos. It returns the problem as an error when parsing strictly or
+// without a document, and records it as a warning otherwise.
+func (s *Svg) warn(pos position, tag, id string, offset int, err error) error {
+	err = pos.error(tag, id, offset, err)
+	if s == nil || s.options.Strict {
+		return err
+	}
+	var pe *ParseError
+	errors.As(err, &pe)
+	s.warnMu.Lock()
+	s.Warnings = append(s.Warnings, Warning{*pe})
+	s.warnMu.Unlock()
+	return nil
+}
+
+// skipInvalid handles an element that could not be decoded. Decoding
+// fails on the attributes before the element's content is read, so
+// lenient parsing skips the rest of the element.
+func (s *Svg) skipInvalid(decoder *xml.Decoder, start xml.StartElement, pos position, err error) error {
+	if err := s.warn(pos, start.Name.Local, attrValue(start, "id"), -1, err); err != nil {
+		return err
+	}
+	if err := decoder.Skip(); err != nil {
+		return pos.error(start.Name.Local, attrValue(start, "id"), -1, err)
+	}
+	return nil
+}
+
+// elementName names an element in error messages.
+func elementName(tag, id string) string {
+	if id == "" {
+		return tag
+	}
+	return tag + "#" + id
+}
+
+// position locates an element in the source document.
+type position struct {
+	document     string
+	line, column int
+	ancestors    []string
+}
+
+// error wraps err in a ParseError for the element tag with the given ID
+// at p. Errors that already are ParseErrors are returned unchanged, so
+// that they keep the innermost element.
+func (p position) error(tag, id string, offset int, err error) error {
+	var pe *ParseError
+	if errors.As(err, &pe) {
+		return err
+	}
+	return &ParseError{
+		Document:  p.document,
+		Line:      p.line,
+		Column:    p.column,
+		Tag:       tag,
+		ID:        id,
+		Ancestors: p.ancestors,
+		Offset:    offset,
+		Err:       err,
+	}
+}
+
+// child returns the ancestors of the children of the element with the
+// given tag and ID at p.
+func (p position) child(tag, id string) []string {
+	ancestors := make([]string, len(p.ancestors), len(p.ancestors)+1)
+	copy(ancestors, p.ancestors)
+	return append(ancestors, elementName(tag, id))
+}
+
+// attrValue returns the value of the attribute name of an element.
+func attrValue(start xml.StartElement, name string) string {
+	for _, attr := range start.Attr {
+		if attr.Name.Local == name {
+			return attr.Value
+		}
+	}
+	return ""
+}
+
+// lineIndex records where lines start in the data read through it, so
+// that decoder offsets can be converted to lines and columns.
+type lineIndex struct {
+	r      io.Reader
+	n      int64
+	starts []int64
+}
+
+func newLineIndex(r io.Reader) *lineIndex {
+	return &lineIndex{r: r, starts: []int64{0}}
+}
+
+func (li *lineIndex) Read(p []byte) (int, error) {
+	n, err := li.r.Read(p)
+	for i, c := range p[:n] {
+		if c == '\n' {
+			li.starts = append(li.starts, li.n+int64(i)+1)
+		}
+	}
+	li.n += int64(n)
+	return n, err
+}
+
+// position returns the 1-based line and column of offset. A nil index
+// returns zeros.
+func (li *lineIndex) position(offset int64) (line, column int) {
+	if li == nil {
+		return 0, 0
+	}
+	line = sort.Search(len(li.starts), func(i int) bool { return li.starts[i] > offset })
+	return line, int(offset-li.starts[line-1]) + 1
+}
+
+// commandOffset returns the byte offset of the n-th (0-based) command
+// letter in the path data d, or -1 if there is none.
+func commandOffset(d string, n int) int {
+	for i := 0; i < len(d); i++ {
+		if isCommand(d, i) {
+			if n == 0 {
+				return i
+			}
+			n--
+		}
+	}
+	return -1
+}
+
+// isCommand reports whether the byte at i in the path data d is a
+// command letter rather than, like the e of 1e5, part of a number.
+func isCommand(d string, i int) bool {
+	c := d[i]
+	if c == 'e' && i > 0 && (d[i-1] >= '0' && d[i-1] <= '9' || d[i-1] == '.') {
+		return false
+	}
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
@@ -0,0 +1,81 @@
+package svg
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseError(t *testing.T) {
+	doc := `<svg viewBox="0 0 10 10">
+  <g id="layer1">
+    <g>
+      <path id="p1" d="M0 0 L1 1 L2 x"/>
+    </g>
+  </g>
+</svg>`
+
+	strict := NewOptions(WithName("test"), WithStrict(true))
+	svg, err := ParseSvgWithOptions(strings.NewReader(doc), strict)
+	require.NoError(t, err)
+	_, err = svg.Shapes()
+	require.Error(t, err)
+
+	var pe *ParseError
+	require.True(t, errors.As(err, &pe))
+	require.Equal(t, 4, pe.Line)
+	require.Equal(t, 7, pe.Column)
+	require.Equal(t, "path", pe.Tag)
+	require.Equal(t, "p1", pe.ID)
+	require.Equal(t, []string{"svg", "g#layer1", "g"}, pe.Ancestors)
+	require.Equal(t, 10, pe.Offset)
+	require.Equal(t, `4:7: path#p1 in svg > g#layer1 > g: d offset 10: invalid lineto: expected number, got "x"`, err.Error())
+
+	_, err = ParseSvgWithOptions(strings.NewReader(`<svg>
+<g id="a" stroke-width="wide"></g>
+</svg>`), strict)
+	require.True(t, errors.As(err, &pe))
+	require.Equal(t, 2, pe.Line)
+	require.Equal(t, "a", pe.ID)
+	require.Equal(t, -1, pe.Offset)
+	require.True(t, errors.Is(err, strconv.ErrSyntax))
+}
+
+func TestLenientParsing(t *testing.T) {
+	doc := `<svg viewBox="0 0 10 10">
+<g id="a" stroke-width="wide" transform="bogus(1)" fill="red">
+<path id="skipped" stroke-width="thin" d="M0 0 L1 1"><title>skipped</title></path>
+<path id="cut" d="M0 0 L10 0 L10 x"/>
+</g>
+<path id="stop" d="M0 0 L5 5 # L6 6"/>
+</svg>`
+
+	svg, err := ParseSvg(doc, "test", 0)
+	require.NoError(t, err)
+	shapes, err := svg.Shapes()
+	require.NoError(t, err)
+
+	require.Len(t, shapes, 2)
+	require.Equal(t, "cut", shapes[0].ID)
+	require.Equal(t, "red", shapes[0].Fill)
+	require.Equal(t, [][2]float64{{0, 0}, {10, 0}}, shapes[0].Segments[0].Points)
+	require.Equal(t, [][2]float64{{0, 0}, {5, 5}}, shapes[1].Segments[0].Points)
+
+	var warnings []string
+	for _, w := range svg.Warnings {
+		warnings = append(warnings, w.String())
+	}
+	require.ElementsMatch(t, []string{
+		`2:1: g#a in svg: invalid stroke-width: strconv.ParseFloat: parsing "wide": invalid syntax`,
+		`2:1: g#a in svg: invalid transform "bogus(1)": unknown transform function "bogus"`,
+		`3:1: path#skipped in svg > g#a: strconv.ParseFloat: parsing "thin": invalid syntax`,
+		`4:1: path#cut in svg > g#a: d offset 11: invalid lineto: expected number, got "x"`,
+		`6:1: path#stop in svg: d offset 10: unexpected character '#'`,
+	}, warnings)
+
+	_, err = ParseSvgWithOptions(strings.NewReader(doc), NewOptions(WithStrict(true)))
+	require.Error(t, err)
+}
@@ -0,0 +1,255 @@
+package svg
+
+import "math"
+
+// defaultCornerAngle is the change of direction, in degrees, above which
+// FitCurves starts a new curve when no corner angle is given.
+const defaultCornerAngle = 60
+
+// FitCurves approximates the points of the segment by a minimal sequence
+// of cubic Béziers using Schneider's least-squares method. No point is
+// further than tolerance from the fitted curves. Points where the
+// direction changes by more than cornerAngle degrees (60 if zero) are
+// kept as corners. The result starts with a MoveInstruction, continues
+// with CurveInstructions and ends with a CloseInstruction for closed
+// segments.
+func (s Segment) FitCurves(tolerance, cornerAngle float64) []*DrawingInstruction {
+	pts := dedupPoints(s.Points)
+	if len(pts) == 0 {
+		return nil
+	}
+	if cornerAngle <= 0 {
+		cornerAngle = defaultCornerAngle
+	}
+	limit := math.Cos(cornerAngle * math.Pi / 180)
+
+	ring := s.Closed && len(pts) > 3 && samePoint(pts[0], pts[len(pts)-1])
+	if ring {
+		pts = pts[:len(pts)-1]
+	}
+
+	base := pts
+	isCorner := func(i int) bool {
+		n := len(base)
+		a, b, c := base[(i+n-1)%n], base[i], base[(i+1)%n]
+		d1 := unit([2]float64{b[0] - a[0], b[1] - a[1]})
+		d2 := unit([2]float64{c[0] - b[0], c[1] - b[1]})
+		return dot(d1, d2) < limit
+	}
+
+	var smoothStart [2]float64
+	smoothRing := false
+	if ring {
+		// start the ring at a corner so that every curve ends at one; a
+		// ring without corners keeps a smooth tangent across its start
+		start := -1
+		for i := range pts {
+			if isCorner(i) {
+				start = i
+				break
+			}
+		}
+		if start < 0 {
+			smoothRing = true
+			smoothStart = unit([2]float64{pts[1][0] - pts[len(pts)-1][0], pts[1][1] - pts[len(pts)-1][1]})
+		} else {
+			base = append(append([][2]float64{}, pts[start:]...), pts[:start]...)
+		}
+		pts = append(append([][2]float64{}, base...), base[0])
+	}
+
+	first := Tuple(pts[0])
+	instrs := []*DrawingInstruction{{Kind: MoveInstruction, M: &first}}
+	if len(pts) == 1 {
+		return instrs
+	}
+
+	from := 0
+	for i := 1; i < len(pts); i++ {
+		if i < len(pts)-1 && !isCorner(i%(len(pts)-1)) {
+			continue
+		}
+		run := pts[from : i+1]
+		t1 := unit([2]float64{run[1][0] - run[0][0], run[1][1] - run[0][1]})
+		t2 := unit([2]float64{run[len(run)-2][0] - run[len(run)-1][0], run[len(run)-2][1] - run[len(run)-1][1]})
+		if smoothRing {
+			t1, t2 = smoothStart, [2]float64{-smoothStart[0], -smoothStart[1]}
+		}
+		for _, cb := range fitCubic(run, t1, t2, tolerance*tolerance) {
+			c1, c2, t := Tuple(cb[1]), Tuple(cb[2]), Tuple(cb[3])
+			instrs = append(instrs, &DrawingInstruction{
+				Kind:        CurveInstruction,
+				CurvePoints: &CurvePoints{C1: &c1, C2: &c2, T: &t},
+			})
+		}
+		from = i
+	}
+
+	if s.Closed {
+		instrs = append(instrs, &DrawingInstruction{Kind: CloseInstruction})
+	}
+	return instrs
+}
+
+// fitCubic fits cubic Béziers to pts with the given end tangents,
+// splitting at the point of maximum error until the squared error is
+// below maxError.
+func fitCubic(pts [][2]float64, t1, t2 [2]float64, maxError float64) [][4][2]float64 {
+	first, last := pts[0], pts[len(pts)-1]
+	if len(pts) == 2 {
+		d := math.Hypot(last[0]-first[0], last[1]-first[1]) / 3
+		return [][4][2]float64{{
+			first,
+			{first[0] + t1[0]*d, first[1] + t1[1]*d},
+			{last[0] + t2[0]*d, last[1] + t2[1]*d},
+			last,
+		}}
+	}
+
+	u := chordLengthParameterize(pts)
+	bez := generateBezier(pts, u, t1, t2)
+	err, split := maxBezierError(pts, bez, u)
+	if err < maxError {
+		return [][4][2]float64{bez}
+	}
+
+	if err < maxError*4 {
+		for i := 0; i < 4; i++ {
+			u = reparameterize(pts, u, bez)
+			bez = generateBezier(pts, u, t1, t2)
+			if err, split = maxBezierError(pts, bez, u); err < maxError {
+				return [][4][2]float64{bez}
+			}
+		}
+	}
+
+	center := unit([2]float64{pts[split-1][0] - pts[split+1][0], pts[split-1][1] - pts[split+1][1]})
+	if center == [2]float64{} {
+		center = unit([2]float64{pts[split-1][1] - pts[split][1], pts[split][0] - pts[split-1][0]})
+	}
+	left := fitCubic(pts[:split+1], t1, center, maxError)
+	right := fitCubic(pts[split:], [2]float64{-center[0], -center[1]}, t2, maxError)
+	return append(left, right...)
+}
+
+// generateBezier finds the control point distances along the end
+// tangents that minimise the squared distance to pts.
+func generateBezier(pts [][2]float64, u []float64, t1, t2 [2]float64) [4][2]float64 {
+	first, last := pts[0], pts[len(pts)-1]
+	var c [2][2]float64
+	var x [2]float64
+	for i, p := range pts {
+		b0, b1, b2, b3 := bernstein(u[i])
+		a1 := [2]float64{t1[0] * b1, t1[1] * b1}
+		a2 := [2]float64{t2[0] * b2, t2[1] * b2}
+		c[0][0] += dot(a1, a1)
+		c[0][1] += dot(a1, a2)
+		c[1][1] += dot(a2, a2)
+		tmp := [2]float64{
+			p[0] - (first[0]*(b0+b1) + last[0]*(b2+b3)),
+			p[1] - (first[1]*(b0+b1) + last[1]*(b2+b3)),
+		}
+		x[0] += dot(a1, tmp)
+		x[1] += dot(a2, tmp)
+	}
+	c[1][0] = c[0][1]
+
+	detC := c[0][0]*c[1][1] - c[1][0]*c[0][1]
+	var alphaL, alphaR float64
+	if detC != 0 {
+		alphaL = (x[0]*c[1][1] - x[1]*c[0][1]) / detC
+		alphaR = (c[0][0]*x[1] - c[1][0]*x[0]) / detC
+	}
+
+	segLength := math.Hypot(last[0]-first[0], last[1]-first[1])
+	if eps := 1e-6 * segLength; alphaL < eps || alphaR < eps {
+		alphaL, alphaR = segLength/3, segLength/3
+	}
+	return [4][2]float64{
+		first,
+		{first[0] + t1[0]*alphaL, first[1] + t1[1]*alphaL},
+		{last[0] + t2[0]*alphaR, last[1] + t2[1]*alphaR},
+		last,
+	}
+}
+
+// reparameterize improves the parameter of every point with one
+// Newton-Raphson step towards its closest point on bez.
+func reparameterize(pts [][2]float64, u []float64, bez [4][2]float64) []float64 {
+	result := make([]float64, len(u))
+	for i, p := range pts {
+		t := u[i]
+		q := bezierPoint(bez, t)
+		d1, d2 := bezierDerivatives(bez, t)
+		num := (q[0]-p[0])*d1[0] + (q[1]-p[1])*d1[1]
+		den := dot(d1, d1) + (q[0]-p[0])*d2[0] + (q[1]-p[1])*d2[1]
+		if den != 0 {
+			t -= num / den
+		}
+		result[i] = math.Max(0, math.Min(1, t))
+	}
+	return result
+}
+
+// maxBezierError returns the largest squared distance between pts and
+// bez and the index of the point where it occurs.
+func maxBezierError(pts [][2]float64, bez [4][2]float64, u []float64) (float64, int) {
+	maxDist, split := 0.0, len(pts)/2
+	for i := 1; i < len(pts)-1; i++ {
+		q := bezierPoint(bez, u[i])
+		d := (q[0]-pts[i][0])*(q[0]-pts[i][0]) + (q[1]-pts[i][1])*(q[1]-pts[i][1])
+		if d >= maxDist {
+			maxDist, split = d, i
+		}
+	}
+	return maxDist, split
+}
+
+func chordLengthParameterize(pts [][2]float64) []float64 {
+	u := make([]float64, len(pts))
+	for i := 1; i < len(pts); i++ {
+		u[i] = u[i-1] + math.Hypot(pts[i][0]-pts[i-1][0], pts[i][1]-pts[i-1][1])
+	}
+	total := u[len(u)-1]
+	for i := range u {
+		if total > 0 {
+			u[i] /= total
+		}
+	}
+	return u
+}
+
+func bernstein(t float64) (b0, b1, b2, b3 float64) {
+	mt := 1 - t
+	return mt * mt * mt, 3 * mt * mt * t, 3 * mt * t * t, t * t * t
+}
+
+func bezierPoint(bez [4][2]float64, t float64) [2]float64 {
+	b0, b1, b2, b3 := bernstein(t)
+	return [2]float64{
+		bez[0][0]*b0 + bez[1][0]*b1 + bez[2][0]*b2 + bez[3][0]*b3,
+		bez[0][1]*b0 + bez[1][1]*b1 + bez[2][1]*b2 + bez[3][1]*b3,
+	}
+}
+
+// bezierDerivatives returns the first and second derivative of bez at t.
+func bezierDerivatives(bez [4][2]float64, t float64) (d1, d2 [2]float64) {
+	mt := 1 - t
+	for k := 0; k < 2; k++ {
+		d1[k] = 3*mt*mt*(bez[1][k]-bez[0][k]) + 6*mt*t*(bez[2][k]-bez[1][k]) + 3*t*t*(bez[3][k]-bez[2][k])
+		d2[k] = 6*mt*(bez[2][k]-2*bez[1][k]+bez[0][k]) + 6*t*(bez[3][k]-2*bez[2][k]+bez[1][k])
+	}
+	return d1, d2
+}
+
+func unit(v [2]float64) [2]float64 {
+	l := math.Hypot(v[0], v[1])
+	if l == 0 {
+		return v
+	}
+	return [2]float64{v[0] / l, v[1] / l}
+}
+
+func dot(a, b [2]float64) float64 {
+	return a[0]*b[0] + a[1]*b[1]
+}
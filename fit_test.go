@@ -0,0 +1,62 @@
+package svg
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// curveDistance returns the distance of p to the closest of the sampled
+// points of the fitted curves.
+func curveDistance(instrs []*DrawingInstruction, p [2]float64) float64 {
+	best := math.Inf(1)
+	pos := [2]float64(*instrs[0].M)
+	for _, di := range instrs[1:] {
+		if di.Kind != CurveInstruction {
+			continue
+		}
+		bez := [4][2]float64{pos, *di.CurvePoints.C1, *di.CurvePoints.C2, *di.CurvePoints.T}
+		for i := 0; i <= 200; i++ {
+			q := bezierPoint(bez, float64(i)/200)
+			best = math.Min(best, math.Hypot(q[0]-p[0], q[1]-p[1]))
+		}
+		pos = *di.CurvePoints.T
+	}
+	return best
+}
+
+func TestFitCurves(t *testing.T) {
+	var circle Segment
+	circle.Closed = true
+	for i := 0; i <= 100; i++ {
+		a := 2 * math.Pi * float64(i%100) / 100
+		circle.addPoint([2]float64{50 + 20*math.Cos(a), 50 + 20*math.Sin(a)})
+	}
+
+	instrs := circle.FitCurves(0.1, 0)
+	require.Equal(t, MoveInstruction, instrs[0].Kind)
+	require.Equal(t, CloseInstruction, instrs[len(instrs)-1].Kind)
+	require.True(t, len(instrs) <= 10, "expected a compact fit, got %d instructions", len(instrs))
+	for _, p := range circle.Points {
+		require.True(t, curveDistance(instrs, p) < 0.15)
+	}
+
+	corner := Segment{Points: [][2]float64{{0, 0}, {5, 0}, {10, 0}, {10, 5}, {10, 10}}}
+	instrs = corner.FitCurves(0.1, 0)
+	require.Len(t, instrs, 3)
+	require.Equal(t, Tuple{10, 0}, *instrs[1].CurvePoints.T)
+	require.Equal(t, Tuple{10, 10}, *instrs[2].CurvePoints.T)
+}
+
+func TestPathDataRoundTrip(t *testing.T) {
+	line := Segment{Points: [][2]float64{{0, 0}, {10, 0}, {10, 10}}}
+	d := PathData(line.FitCurves(0.1, 0))
+	require.Regexp(t, `^M0 0 C[0-9. ]+ 10 0 C[0-9. ]+ 10 10$`, d)
+
+	svg, err := ParseSvg(`<svg><path d="`+d+`"/></svg>`, "test", 0)
+	require.NoError(t, err)
+	instrs, err := collectInstructions(svg)
+	require.NoError(t, err)
+	require.Equal(t, CurveInstruction, instrs[1].Kind)
+}
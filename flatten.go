@@ -0,0 +1,163 @@
+package svg
+
+import "math"
+
+// circleSteps is the number of straight segments used to approximate a
+// full circle when flattening.
+const circleSteps = 72
+
+// flattenInstructions turns a stream of drawing instructions into world
+// space polylines. Curves are approximated within tolerance, or with
+// recursiveInterpolate if tolerance is zero, and circles with a regular
+// polygon. The stroke width of every segment is taken from the
+// PaintInstruction that terminates the stream.
+func flattenInstructions(instrs []*DrawingInstruction, tolerance float64) []Segment {
+	segments, _ := flattenLimited(instrs, tolerance, nil)
+	return segments
+}
+
+// flattenLimited is flattenInstructions counting the points it makes
+// against limit, and stopping as soon as there are too many.
+func flattenLimited(instrs []*DrawingInstruction, tolerance float64, limit *pointLimit) ([]Segment, error) {
+	var (
+		segments []Segment
+		current  *Segment
+		pos      [2]float64
+		start    [2]float64
+		width    float64
+	)
+
+	flush := func() {
+		if current != nil && len(current.Points) > 1 {
+			segments = append(segments, *current)
+		}
+		current = nil
+	}
+	ensure := func() {
+		if current == nil {
+			current = &Segment{Points: [][2]float64{pos}}
+			start = pos
+			limit.count(1)
+		}
+	}
+
+	for _, di := range instrs {
+		switch di.Kind {
+		case MoveInstruction:
+			flush()
+			pos = [2]float64(*di.M)
+			ensure()
+		case LineInstruction:
+			ensure()
+			pos = [2]float64(*di.M)
+			current.addPoint(pos)
+			limit.count(1)
+		case CurveInstruction:
+			ensure()
+			var cb cubicBezier
+			cb.controlpoints[0] = pos
+			cb.controlpoints[1] = [2]float64(*di.CurvePoints.C1)
+			cb.controlpoints[2] = [2]float64(*di.CurvePoints.C2)
+			cb.controlpoints[3] = [2]float64(*di.CurvePoints.T)
+			var vertices [][2]float64
+			if tolerance > 0 {
+				vertices = cb.flatten(tolerance, limit)
+			} else {
+				vertices = cb.recursiveInterpolate(10, 0)
+				limit.count(len(vertices) - 1)
+			}
+			for _, v := range vertices[1:] {
+				current.addPoint(v)
+			}
+			pos = cb.controlpoints[3]
+		case CloseInstruction:
+			if current != nil {
+				current.addPoint(start)
+				limit.count(1)
+				current.Closed = true
+				flush()
+			}
+			pos = start
+		case CircleInstruction:
+			flush()
+			c, ok := circleSegmentLimited(*di.M, *di.Radius, tolerance, limit)
+			if !ok {
+				return nil, limit.err()
+			}
+			segments = append(segments, c)
+		case PaintInstruction:
+			if di.StrokeWidth != nil {
+				width = *di.StrokeWidth
+			}
+		}
+		if err := limit.err(); err != nil {
+			return nil, err
+		}
+	}
+	flush()
+
+	for i := range segments {
+		segments[i].Width = width
+	}
+	return segments, nil
+}
+
+// maxCircleSteps bounds the sides of a flattened circle, like
+// maxFlattenDepth bounds the points of a flattened curve.
+const maxCircleSteps = 1 << maxFlattenDepth
+
+// circleSegment approximates a circle by a closed regular polygon with
+// circleSteps sides, or with as many sides as needed to stay within
+// tolerance if tolerance is not zero.
+func circleSegment(c Tuple, r, tolerance float64) Segment {
+	s, _ := circleSegmentLimited(c, r, tolerance, nil)
+	return s
+}
+
+// circleSegmentLimited is circleSegment counting the points against
+// limit before they are made. It reports false if there are too many.
+func circleSegmentLimited(c Tuple, r, tolerance float64, limit *pointLimit) (Segment, bool) {
+	steps := circleSteps
+	if tolerance > 0 && tolerance < r {
+		n := math.Ceil(math.Pi / math.Acos(1-tolerance/r))
+		switch {
+		case !(n <= maxCircleSteps):
+			// also catches the infinity of a tolerance too small to
+			// matter
+			steps = maxCircleSteps
+		case n < 8:
+			steps = 8
+		default:
+			steps = int(n)
+		}
+	}
+	limit.count(steps + 1)
+	if limit.err() != nil {
+		return Segment{}, false
+	}
+	s := Segment{Closed: true, Points: make([][2]float64, 0, steps+1)}
+	for i := 0; i <= steps; i++ {
+		a := 2 * math.Pi * float64(i%steps) / float64(steps)
+		s.addPoint([2]float64{c[0] + r*math.Cos(a), c[1] + r*math.Sin(a)})
+	}
+	return s, true
+}
+
+// collectInstructions drains the channels returned by
+// ParseDrawingInstructions, returning the first error encountered.
+func collectInstructions(dip DrawingInstructionParser) ([]*DrawingInstruction, error) {
+	instrs, errs := dip.ParseDrawingInstructions()
+
+	var result []*DrawingInstruction
+	for di := range instrs {
+		result = append(result, di)
+	}
+
+	var err error
+	for e := range errs {
+		if err == nil {
+			err = e
+		}
+	}
+	return result, err
+}
@@ -0,0 +1,270 @@
+package svg
+
+import (
+	"fmt"
+	"io/fs"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/sfnt"
+	"golang.org/x/image/math/fixed"
+)
+
+// Font is a TrueType or OpenType font that text is converted to
+// outlines with.
+type Font struct {
+	sfnt *sfnt.Font
+}
+
+// ParseFont parses a TTF or OTF font.
+func ParseFont(data []byte) (*Font, error) {
+	f, err := sfnt.Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid font: %w", err)
+	}
+	return &Font{sfnt: f}, nil
+}
+
+// LoadFont loads the font file name from fsys.
+func LoadFont(fsys fs.FS, name string) (*Font, error) {
+	data, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return nil, err
+	}
+	return ParseFont(data)
+}
+
+// LoadFontFile loads the font file at path.
+func LoadFontFile(path string) (*Font, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseFont(data)
+}
+
+// Fonts are the fonts text is converted to outlines with.
+type Fonts struct {
+	// Families maps font family names, compared without case, to fonts.
+	// The bold face of a family is looked up as the family name followed
+	// by " bold".
+	Families map[string]*Font
+	// Default is used for text whose font families are not in Families.
+	Default *Font
+}
+
+// font returns the font for the font-family list family with the given
+// font-weight, or nil if there is none. Nil Fonts have no fonts.
+func (f *Fonts) font(family, weight string) *Font {
+	if f == nil {
+		return nil
+	}
+	bold := weight == "bold" || weight == "bolder"
+	if w, err := strconv.Atoi(weight); err == nil && w >= 600 {
+		bold = true
+	}
+	lookup := func(name string) *Font {
+		for n, font := range f.Families {
+			if strings.EqualFold(n, name) {
+				return font
+			}
+		}
+		return nil
+	}
+	for _, name := range strings.Split(family, ",") {
+		name = strings.Trim(strings.TrimSpace(name), `'"`)
+		if bold {
+			if font := lookup(name + " bold"); font != nil {
+				return font
+			}
+		}
+		if font := lookup(name); font != nil {
+			return font
+		}
+	}
+	return f.Default
+}
+
+// placedGlyph is a glyph positioned in the coordinate system of a text
+// element.
+type placedGlyph struct {
+	font   *Font
+	index  sfnt.GlyphIndex
+	x, y   float64
+	scale  float64
+	rotate float64
+	// transform maps the text element's coordinates to the coordinates
+	// of the drawing instructions
+	transform [6]float64
+}
+
+// Outlines converts the text to glyph outlines with fonts, honouring
+// kerning, read from GPOS pair adjustments or the legacy kern table,
+// letter-spacing, text-anchor, dominant-baseline and rotate. The
+// outlines are Move, Line, Curve and Close instructions in the
+// coordinates of the other drawing instructions, with the transforms of
+// the element and its groups applied. Runs along a textPath are not
+// supported and are skipped with a warning.
+func (t *Text) Outlines(fonts *Fonts) ([]*DrawingInstruction, error) {
+	runs, err := t.Runs()
+	if err != nil {
+		return nil, err
+	}
+	var owner *Svg
+	if t.group != nil {
+		owner = t.group.Owner
+	}
+
+	var buf sfnt.Buffer
+	var glyphs []placedGlyph
+	// chunk is the index of the first glyph of the current text chunk,
+	// which starts at every absolutely positioned run and is aligned
+	// as a whole by text-anchor
+	chunk, anchor := 0, ""
+	var start, pen float64
+	align := func() {
+		var shift float64
+		switch anchor {
+		case "middle":
+			shift = -(pen - start) / 2
+		case "end":
+			shift = -(pen - start)
+		}
+		for i := chunk; i < len(glyphs); i++ {
+			glyphs[i].x += shift
+		}
+	}
+
+	for _, run := range runs {
+		if run.TextPath != "" {
+			err := fmt.Errorf("text along a textPath cannot be converted to outlines")
+			if err = owner.warn(t.pos, "text", t.ID, -1, err); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		f := fonts.font(run.FontFamily, run.FontWeight)
+		if f == nil {
+			return nil, fmt.Errorf("no font for font-family %q", run.FontFamily)
+		}
+		ppem := fixed.Int26_6(f.sfnt.UnitsPerEm())
+		scale := run.FontSize / float64(f.sfnt.UnitsPerEm())
+
+		x, y := run.X, run.Y
+		if run.Relative {
+			x += pen
+		} else {
+			align()
+			chunk, anchor, start = len(glyphs), run.TextAnchor, x
+		}
+		metrics, err := f.sfnt.Metrics(&buf, ppem, font.HintingNone)
+		if err != nil {
+			return nil, fmt.Errorf("invalid font: %w", err)
+		}
+		ascent, descent := float64(metrics.Ascent)*scale, float64(metrics.Descent)*scale
+		switch run.DominantBaseline {
+		case "middle", "central":
+			y += (ascent - descent) / 2
+		case "hanging", "text-before-edge":
+			y += ascent
+		case "text-after-edge", "ideographic":
+			y -= descent
+		}
+
+		var prev sfnt.GlyphIndex
+		for i, r := range []rune(run.Text) {
+			index, err := f.sfnt.GlyphIndex(&buf, r)
+			if err != nil {
+				return nil, fmt.Errorf("invalid font: %w", err)
+			}
+			if i > 0 {
+				// sfnt reads pair adjustments of the GPOS kern feature,
+				// falling back to the kern table; fonts without kerning
+				// return an error
+				if kern, err := f.sfnt.Kern(&buf, prev, index, ppem, font.HintingNone); err == nil {
+					x += float64(kern) * scale
+				}
+			}
+			var rotate float64
+			if len(run.Rotate) > 0 {
+				rotate = run.Rotate[len(run.Rotate)-1]
+				if i < len(run.Rotate) {
+					rotate = run.Rotate[i]
+				}
+			}
+			glyphs = append(glyphs, placedGlyph{font: f, index: index, x: x, y: y, scale: scale, rotate: rotate, transform: run.Transform})
+
+			advance, err := f.sfnt.GlyphAdvance(&buf, index, ppem, font.HintingNone)
+			if err != nil {
+				return nil, fmt.Errorf("invalid font: %w", err)
+			}
+			x += float64(advance)*scale + run.LetterSpacing
+			prev = index
+		}
+		pen = x
+	}
+	align()
+
+	var instrs []*DrawingInstruction
+	for _, g := range glyphs {
+		if instrs, err = g.appendOutline(instrs, &buf); err != nil {
+			return nil, err
+		}
+	}
+	return instrs, nil
+}
+
+// appendOutline appends the outline of g to instrs.
+func (g placedGlyph) appendOutline(instrs []*DrawingInstruction, buf *sfnt.Buffer) ([]*DrawingInstruction, error) {
+	segments, err := g.font.sfnt.LoadGlyph(buf, g.index, fixed.Int26_6(g.font.sfnt.UnitsPerEm()), nil)
+	if err != nil {
+		return nil, fmt.Errorf("invalid font: %w", err)
+	}
+
+	sin, cos := math.Sincos(g.rotate * math.Pi / 180)
+	t := g.transform
+	point := func(p fixed.Point26_6) Tuple {
+		// the glyph is in font units with the y axis pointing down
+		u, v := float64(p.X)*g.scale, float64(p.Y)*g.scale
+		x, y := g.x+u*cos-v*sin, g.y+u*sin+v*cos
+		return Tuple{t[0]*x + t[2]*y + t[4], t[1]*x + t[3]*y + t[5]}
+	}
+
+	var cur Tuple
+	open := false
+	for _, s := range segments {
+		switch s.Op {
+		case sfnt.SegmentOpMoveTo:
+			if open {
+				instrs = append(instrs, &DrawingInstruction{Kind: CloseInstruction})
+			}
+			cur = point(s.Args[0])
+			m := cur
+			instrs = append(instrs, &DrawingInstruction{Kind: MoveInstruction, M: &m})
+			open = true
+		case sfnt.SegmentOpLineTo:
+			cur = point(s.Args[0])
+			m := cur
+			instrs = append(instrs, &DrawingInstruction{Kind: LineInstruction, M: &m})
+		case sfnt.SegmentOpQuadTo:
+			// a quadratic Bézier is a cubic with control points two
+			// thirds of the way to the quadratic control point
+			q, end := point(s.Args[0]), point(s.Args[1])
+			c1 := Tuple{cur[0] + (q[0]-cur[0])*2/3, cur[1] + (q[1]-cur[1])*2/3}
+			c2 := Tuple{end[0] + (q[0]-end[0])*2/3, end[1] + (q[1]-end[1])*2/3}
+			cur = end
+			instrs = append(instrs, &DrawingInstruction{Kind: CurveInstruction, CurvePoints: &CurvePoints{C1: &c1, C2: &c2, T: &end}})
+		case sfnt.SegmentOpCubeTo:
+			c1, c2, end := point(s.Args[0]), point(s.Args[1]), point(s.Args[2])
+			cur = end
+			instrs = append(instrs, &DrawingInstruction{Kind: CurveInstruction, CurvePoints: &CurvePoints{C1: &c1, C2: &c2, T: &end}})
+		}
+	}
+	if open {
+		instrs = append(instrs, &DrawingInstruction{Kind: CloseInstruction})
+	}
+	return instrs, nil
+}
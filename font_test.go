@@ -0,0 +1,169 @@
+package svg
+
+import (
+	"encoding/binary"
+	"math"
+	"sort"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/image/font/gofont/gobold"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/font/sfnt"
+)
+
+// outlineBounds returns the bounding box of the shapes of doc converted
+// with fonts.
+func outlineBounds(t *testing.T, doc string, fonts *Fonts) (min, max [2]float64) {
+	svg, err := ParseSvgWithOptions(strings.NewReader(doc), NewOptions(WithFonts(fonts), WithStrict(true)))
+	require.NoError(t, err)
+	shapes, err := svg.Shapes()
+	require.NoError(t, err)
+	require.NotEmpty(t, shapes)
+	for _, s := range shapes {
+		require.False(t, s.isText())
+	}
+	return svgBounds(shapes)
+}
+
+func TestOutlines(t *testing.T) {
+	fsys := fstest.MapFS{"fonts/Go-Regular.ttf": {Data: goregular.TTF}, "fonts/Go-Bold.ttf": {Data: gobold.TTF}}
+	regular, err := LoadFont(fsys, "fonts/Go-Regular.ttf")
+	require.NoError(t, err)
+	bold, err := LoadFont(fsys, "fonts/Go-Bold.ttf")
+	require.NoError(t, err)
+	_, err = ParseFont([]byte("not a font"))
+	require.Error(t, err)
+	fonts := &Fonts{Default: regular}
+
+	// the glyph sits on the baseline right of x
+	min, max := outlineBounds(t, `<svg><text x="10" y="100" font-size="100">H</text></svg>`, fonts)
+	require.Greater(t, min[0], 10.0)
+	require.Less(t, max[0], 90.0)
+	require.InDelta(t, 100, max[1], 1e-9)
+	require.InDelta(t, 30, min[1], 5)
+	width := max[0] - min[0]
+
+	// text-anchor aligns the chunk, including the relative tspan
+	min, max = outlineBounds(t, `<svg><text x="100" y="100" font-size="100" text-anchor="end">H<tspan>H</tspan></text></svg>`, fonts)
+	require.InDelta(t, 100, max[0], 10)
+	min2, max2 := outlineBounds(t, `<svg><text x="100" y="100" font-size="100" text-anchor="middle">HH</text></svg>`, fonts)
+	require.InDelta(t, 100, (min2[0]+max2[0])/2, 1)
+	require.InDelta(t, max[0]-min[0], max2[0]-min2[0], 1e-9)
+
+	// letter-spacing widens the gap between the glyphs
+	min, max = outlineBounds(t, `<svg><text font-size="100">HH</text></svg>`, fonts)
+	min2, max2 = outlineBounds(t, `<svg><text font-size="100" letter-spacing="50">HH</text></svg>`, fonts)
+	require.InDelta(t, 50, (max2[0]-min2[0])-(max[0]-min[0]), 1e-9)
+
+	// the transform stack applies to the outlines
+	min, max = outlineBounds(t, `<svg><g transform="scale(2)"><text font-size="100" transform="translate(10 0)">H</text></g></svg>`, fonts)
+	require.InDelta(t, 2*width, max[0]-min[0], 1e-9)
+	require.Greater(t, min[0], 20.0)
+
+	// rotated glyphs turn around their origin
+	min, max = outlineBounds(t, `<svg><text y="0" font-size="100" rotate="90">H</text></svg>`, fonts)
+	require.InDelta(t, 0, min[0], 1e-9)
+	require.InDelta(t, width, max[1]-min[1], 1e-9)
+
+	// bold text uses the bold face of the family
+	families := &Fonts{Families: map[string]*Font{"Go": regular, "go bold": bold}}
+	boldText := `<svg><text font-family="'Go', sans-serif" font-weight="700" font-size="100">H</text></svg>`
+	min, max = outlineBounds(t, boldText, families)
+	min2, max2 = outlineBounds(t, boldText, &Fonts{Default: bold})
+	require.Equal(t, min2, min)
+	require.Equal(t, max2, max)
+	require.NotEqual(t, width, max[0]-min[0])
+
+	// text without a font is kept as text
+	svg, err := ParseSvgWithOptions(strings.NewReader(`<svg><text font-family="Arial">H</text></svg>`), NewOptions(WithFonts(families)))
+	require.NoError(t, err)
+	shapes, err := svg.Shapes()
+	require.NoError(t, err)
+	require.True(t, shapes[0].isText())
+	require.Len(t, svg.Warnings, 1)
+	require.Contains(t, svg.Warnings[0].String(), `no font for font-family "Arial"`)
+	require.False(t, math.IsNaN(shapes[0].Instructions[0].Text.X))
+
+	// converting without fonts is an error
+	_, err = svg.Elements[0].(*Text).Outlines(nil)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), `no font for font-family "Arial"`)
+}
+
+// withGPOSKerning returns a copy of the TrueType font data with a GPOS
+// table whose kern feature moves the glyph second by xAdvance font units
+// when it follows first.
+func withGPOSKerning(data []byte, first, second sfnt.GlyphIndex, xAdvance int16) []byte {
+	u16 := func(b []byte, v ...uint16) []byte {
+		for _, x := range v {
+			b = append(b, byte(x>>8), byte(x))
+		}
+		return b
+	}
+	var gpos []byte
+	gpos = u16(gpos, 1, 0, 10, 30, 44)                    // header
+	gpos = append(u16(gpos, 1), "DFLT"...)                // script list
+	gpos = u16(gpos, 8, 4, 0, 0, 0xffff, 1, 0)            // script and language system
+	gpos = append(u16(gpos, 1), "kern"...)                // feature list
+	gpos = u16(gpos, 8, 0, 1, 0)                          // feature
+	gpos = u16(gpos, 1, 4, 2, 0, 1, 8)                    // lookup list and pair lookup
+	gpos = u16(gpos, 1, 12, 4, 0, 1, 18)                  // pair adjustment
+	gpos = u16(gpos, 1, 1, uint16(first))                 // coverage
+	gpos = u16(gpos, 1, uint16(second), uint16(xAdvance)) // pair set
+	gpos = append(gpos, make([]byte, (4-len(gpos)%4)%4)...)
+
+	// insert the table record and move the tables behind the larger
+	// directory
+	numTables := int(binary.BigEndian.Uint16(data[4:]))
+	tables := data[12+16*numTables:]
+	shift := uint32(16)
+	var records [][]byte
+	for i := 0; i < numTables; i++ {
+		r := append([]byte(nil), data[12+16*i:28+16*i]...)
+		binary.BigEndian.PutUint32(r[8:], binary.BigEndian.Uint32(r[8:])+shift)
+		records = append(records, r)
+	}
+	record := append([]byte("GPOS"), make([]byte, 12)...)
+	binary.BigEndian.PutUint32(record[8:], uint32(len(data))+shift)
+	binary.BigEndian.PutUint32(record[12:], uint32(len(gpos)))
+	records = append(records, record)
+	sort.Slice(records, func(i, j int) bool { return string(records[i][:4]) < string(records[j][:4]) })
+
+	out := append([]byte(nil), data[:12]...)
+	binary.BigEndian.PutUint16(out[4:], uint16(numTables+1))
+	for _, r := range records {
+		out = append(out, r...)
+	}
+	out = append(out, tables...)
+	return append(out, gpos...)
+}
+
+func TestGPOSKerning(t *testing.T) {
+	plain, err := ParseFont(goregular.TTF)
+	require.NoError(t, err)
+	var buf sfnt.Buffer
+	a, err := plain.sfnt.GlyphIndex(&buf, 'A')
+	require.NoError(t, err)
+	v, err := plain.sfnt.GlyphIndex(&buf, 'V')
+	require.NoError(t, err)
+	kerned, err := ParseFont(withGPOSKerning(goregular.TTF, a, v, -200))
+	require.NoError(t, err)
+	unitsPerEm := float64(kerned.sfnt.UnitsPerEm())
+
+	// the pair moves closer by the adjustment at the font size
+	doc := `<svg><text font-size="100">AV</text></svg>`
+	min, max := outlineBounds(t, doc, &Fonts{Default: plain})
+	min2, max2 := outlineBounds(t, doc, &Fonts{Default: kerned})
+	require.InDelta(t, min[0], min2[0], 1e-9)
+	require.InDelta(t, -200*100/unitsPerEm, max2[0]-max[0], 1e-6)
+
+	// other pairs are not kerned
+	doc = `<svg><text font-size="100">VA</text></svg>`
+	min, max = outlineBounds(t, doc, &Fonts{Default: plain})
+	min2, max2 = outlineBounds(t, doc, &Fonts{Default: kerned})
+	require.Equal(t, min, min2)
+	require.Equal(t, max, max2)
+}
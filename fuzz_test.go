@@ -0,0 +1,129 @@
+package svg
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+)
+
+// fuzzTimeout bounds the time a single fuzz input may take.
+const fuzzTimeout = 10 * time.Second
+
+// fuzzLimits keep every fuzz input small enough to finish quickly.
+var fuzzLimits = Limits{
+	MaxDepth:        64,
+	MaxElements:     1000,
+	MaxPathCommands: 10000,
+	MaxUses:         100,
+	MaxPoints:       100000,
+}
+
+// checkBounded runs fn and fails if it does not return in time or
+// leaves goroutines behind.
+func checkBounded(t *testing.T, fn func()) {
+	before := runtime.NumGoroutine()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		fn()
+	}()
+	select {
+	case <-done:
+	case <-time.After(fuzzTimeout):
+		t.Fatalf("did not finish within %s", fuzzTimeout)
+	}
+
+	// goroutines that are ending may take a moment to be gone
+	deadline := time.Now().Add(time.Second)
+	for runtime.NumGoroutine() > before {
+		if time.Now().After(deadline) {
+			buf := make([]byte, 1<<16)
+			t.Fatalf("%d goroutines leaked:\n%s", runtime.NumGoroutine()-before, buf[:runtime.Stack(buf, true)])
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// addSeedFiles adds the SVG files in testdata to the seed corpus.
+func addSeedFiles(f *testing.F) {
+	files, err := filepath.Glob(filepath.Join("testdata", "*.svg"))
+	if err != nil {
+		f.Fatal(err)
+	}
+	hostile, err := filepath.Glob(filepath.Join("testdata", "hostile", "*.svg"))
+	if err != nil {
+		f.Fatal(err)
+	}
+	for _, file := range append(files, hostile...) {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			f.Fatal(err)
+		}
+		f.Add(data)
+	}
+}
+
+func FuzzParseSvg(f *testing.F) {
+	addSeedFiles(f)
+	f.Add([]byte(testSvg))
+	for _, test := range tests {
+		f.Add([]byte(test.Svg))
+	}
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		checkBounded(t, func() {
+			for _, strict := range []bool{false, true} {
+				svg, err := ParseSvgWithOptions(bytes.NewReader(data), NewOptions(WithLimits(fuzzLimits), WithStrict(strict)))
+				if err != nil {
+					continue
+				}
+				svg.Shapes()
+				Replay(svg, &RecordingRenderer{})
+			}
+		})
+	})
+}
+
+func FuzzPathData(f *testing.F) {
+	for _, d := range []string{
+		"M0 0 L10 0 10 10 Z",
+		"m 40.5,60.25 c 10,-15 30,-15 40,0 l 0,20.5 h -40 z",
+		"M100,100 C120,80 140,120 160,100 V140 H100 Z m10,10 l10,0 0,10 z",
+		"M0 0 M1 1 L2 2",
+		"L1 1",
+		"M1e2.5-3 h.5v-1e-2",
+		"",
+	} {
+		f.Add(d)
+	}
+
+	f.Fuzz(func(t *testing.T, d string) {
+		checkBounded(t, func() {
+			collectInstructions(&Path{D: d})
+			for range (&Path{D: d}).Parse() {
+			}
+		})
+	})
+}
+
+func FuzzTransform(f *testing.F) {
+	for _, s := range []string{
+		"translate(10 20)",
+		"matrix(1 0 0 1 5 5)",
+		"rotate(90 1 0) scale(2, 3)",
+		"skewX(30) skewY(-30)",
+		"translate(-12.5,-30.25)",
+		"rotate( 1  \xee\xee\xee",
+	} {
+		f.Add(s)
+	}
+
+	f.Fuzz(func(t *testing.T, s string) {
+		checkBounded(t, func() {
+			parseTransform(s)
+		})
+	})
+}
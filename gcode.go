@@ -0,0 +1,313 @@
+package svg
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+)
+
+// GCodeTool holds the machine settings used to draw one layer.
+type GCodeTool struct {
+	// PenDown and PenUp are emitted before and after every drawn
+	// segment, for example "G0 Z0" and "G0 Z5" for a pen plotter or
+	// "M3 S1000" and "M5" for a laser.
+	PenDown string
+	PenUp   string
+	// FeedRate is the drawing speed in units per minute.
+	FeedRate float64
+	// TravelRate is the speed of pen-up moves. Zero emits rapid moves.
+	TravelRate float64
+	// Passes is the number of times every segment is drawn, at least
+	// once.
+	Passes int
+}
+
+// defaultGCodeTool is used when no tool is configured.
+var defaultGCodeTool = GCodeTool{PenDown: "M3", PenUp: "M5", FeedRate: 1000}
+
+// GCodeOptions controls how shapes are written by WriteGCode.
+type GCodeOptions struct {
+	// Tool is used for shapes without a matching entry in Tools.
+	Tool GCodeTool
+	// Tools maps group IDs and stroke colours to the tool used for the
+	// shapes with that group ID or stroke. Group IDs take precedence;
+	// colours match any spelling of the same colour, and of several keys
+	// naming the same colour the first in sorted order is used.
+	Tools map[string]GCodeTool
+	// Units is mm or in. Empty means mm.
+	Units string
+	// Origin is the point of the document, in user units, that becomes
+	// the machine origin.
+	Origin [2]float64
+	// FlipY makes the y axis point up, as on most machines.
+	FlipY bool
+	// Arcs writes circles and runs of points that lie on a circle within
+	// ArcTolerance as G2/G3 arcs.
+	Arcs         bool
+	ArcTolerance float64
+}
+
+// defaultArcTolerance is the arc fitting tolerance in output units.
+const defaultArcTolerance = 0.01
+
+// WriteGCode writes the outlines of the visible, painted shapes to w as
+// G-code. Shapes are grouped by tool so that every layer is drawn in
+// one go, in the order the layers first appear. A nil opts uses the
+// defaults.
+func WriteGCode(w io.Writer, shapes []Shape, opts *GCodeOptions) error {
+	if opts == nil {
+		opts = &GCodeOptions{}
+	}
+	units, unitCode := "mm", "G21"
+	switch opts.Units {
+	case "", "mm":
+	case "in":
+		units, unitCode = "in", "G20"
+	default:
+		return fmt.Errorf("unsupported G-code unit %q", opts.Units)
+	}
+
+	g := &gcodeWriter{opts: opts, scale: 1 / unitFactors[units]}
+	if g.tolerance = opts.ArcTolerance; g.tolerance <= 0 {
+		g.tolerance = defaultArcTolerance
+	}
+
+	var keys []string
+	layers := make(map[string][]Shape)
+	tools := make(map[string]GCodeTool)
+	for _, s := range shapes {
+		if s.Hidden || (!s.Filled() && !s.Stroked()) {
+			continue
+		}
+		key, tool := g.tool(s)
+		if _, ok := layers[key]; !ok {
+			keys = append(keys, key)
+			tools[key] = tool
+		}
+		layers[key] = append(layers[key], s)
+	}
+
+	fmt.Fprintf(&g.buf, "%s\nG90\n", unitCode)
+	for _, key := range keys {
+		tool := tools[key]
+		if tool.Passes < 1 {
+			tool.Passes = 1
+		}
+		if key != "" {
+			fmt.Fprintf(&g.buf, "; layer %s\n", key)
+		}
+		g.line(tool.PenUp)
+		for _, s := range layers[key] {
+			for _, path := range g.paths(s) {
+				for i := 0; i < tool.Passes; i++ {
+					g.draw(path, tool)
+				}
+			}
+		}
+	}
+	g.buf.WriteString("M2\n")
+
+	_, err := w.Write(g.buf.Bytes())
+	return err
+}
+
+type gcodeWriter struct {
+	buf       bytes.Buffer
+	opts      *GCodeOptions
+	scale     float64
+	tolerance float64
+}
+
+// gcodeMove is a straight or circular move to a point in machine
+// coordinates.
+type gcodeMove struct {
+	to     [2]float64
+	arc    bool
+	center [2]float64
+	ccw    bool
+}
+
+// gcodePath is a connected sequence of moves starting at start.
+type gcodePath struct {
+	start [2]float64
+	moves []gcodeMove
+}
+
+// tool returns the layer key and tool of a shape.
+func (g *gcodeWriter) tool(s Shape) (string, GCodeTool) {
+	def := g.opts.Tool
+	if def == (GCodeTool{}) {
+		def = defaultGCodeTool
+	}
+	if s.GroupID != "" {
+		if t, ok := g.opts.Tools[s.GroupID]; ok {
+			return s.GroupID, t
+		}
+	}
+	if c, ok := ParseColor(s.Stroke); ok && s.Stroked() {
+		// keys are tried in order so that the choice between keys
+		// naming the same colour, like red and #f00, is stable
+		keys := make([]string, 0, len(g.opts.Tools))
+		for key := range g.opts.Tools {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			if kc, ok := ParseColor(key); ok && kc == c {
+				return key, g.opts.Tools[key]
+			}
+		}
+	}
+	return "", def
+}
+
+func (g *gcodeWriter) machine(p [2]float64) [2]float64 {
+	x := (p[0] - g.opts.Origin[0]) * g.scale
+	y := (p[1] - g.opts.Origin[1]) * g.scale
+	if g.opts.FlipY {
+		y = -y
+	}
+	return [2]float64{x, y}
+}
+
+// paths converts a shape to machine coordinate paths.
+func (g *gcodeWriter) paths(s Shape) []gcodePath {
+	var paths []gcodePath
+	if !g.opts.Arcs {
+		for _, seg := range s.Segments {
+			paths = append(paths, g.linePath(seg.Points))
+		}
+		return paths
+	}
+
+	for _, sub := range splitSubpaths(s.Instructions) {
+		if sub[0].Kind == CircleInstruction {
+			c := *sub[0].M
+			r := *sub[0].Radius
+			start := g.machine([2]float64{c[0] + r, c[1]})
+			center := g.machine(c)
+			paths = append(paths, gcodePath{start: start, moves: []gcodeMove{
+				{to: start, arc: true, center: center, ccw: g.opts.FlipY},
+			}})
+			continue
+		}
+		for _, seg := range flattenInstructions(sub, s.tolerance) {
+			pts := make([][2]float64, len(seg.Points))
+			for i, p := range seg.Points {
+				pts[i] = g.machine(p)
+			}
+			paths = append(paths, gcodePath{start: pts[0], moves: fitArcs(pts, g.tolerance)})
+		}
+	}
+	return paths
+}
+
+func (g *gcodeWriter) linePath(pts [][2]float64) gcodePath {
+	path := gcodePath{start: g.machine(pts[0])}
+	for _, p := range pts[1:] {
+		path.moves = append(path.moves, gcodeMove{to: g.machine(p)})
+	}
+	return path
+}
+
+func (g *gcodeWriter) draw(path gcodePath, tool GCodeTool) {
+	if tool.TravelRate > 0 {
+		fmt.Fprintf(&g.buf, "G1 X%s Y%s F%s\n", gcodeNumber(path.start[0]), gcodeNumber(path.start[1]), gcodeNumber(tool.TravelRate))
+	} else {
+		fmt.Fprintf(&g.buf, "G0 X%s Y%s\n", gcodeNumber(path.start[0]), gcodeNumber(path.start[1]))
+	}
+	g.line(tool.PenDown)
+
+	feed := fmt.Sprintf(" F%s", gcodeNumber(tool.FeedRate))
+	from := path.start
+	for _, m := range path.moves {
+		switch {
+		case !m.arc:
+			fmt.Fprintf(&g.buf, "G1 X%s Y%s%s\n", gcodeNumber(m.to[0]), gcodeNumber(m.to[1]), feed)
+		case m.ccw:
+			fmt.Fprintf(&g.buf, "G3 X%s Y%s I%s J%s%s\n", gcodeNumber(m.to[0]), gcodeNumber(m.to[1]),
+				gcodeNumber(m.center[0]-from[0]), gcodeNumber(m.center[1]-from[1]), feed)
+		default:
+			fmt.Fprintf(&g.buf, "G2 X%s Y%s I%s J%s%s\n", gcodeNumber(m.to[0]), gcodeNumber(m.to[1]),
+				gcodeNumber(m.center[0]-from[0]), gcodeNumber(m.center[1]-from[1]), feed)
+		}
+		// the feed rate is modal
+		feed = ""
+		from = m.to
+	}
+	g.line(tool.PenUp)
+}
+
+func (g *gcodeWriter) line(s string) {
+	if s != "" {
+		g.buf.WriteString(s + "\n")
+	}
+}
+
+// fitArcs converts a polyline into moves, replacing runs of at least
+// four points that lie within tolerance of a circle by a single arc.
+func fitArcs(pts [][2]float64, tolerance float64) []gcodeMove {
+	var moves []gcodeMove
+	for i := 0; i < len(pts)-1; {
+		best := -1
+		var bestCenter [2]float64
+		var bestCCW bool
+		for j := i + 3; j < len(pts); j++ {
+			center, ccw, ok := arcThrough(pts[i:j+1], tolerance)
+			if !ok {
+				break
+			}
+			best, bestCenter, bestCCW = j, center, ccw
+		}
+		if best < 0 {
+			moves = append(moves, gcodeMove{to: pts[i+1]})
+			i++
+			continue
+		}
+		moves = append(moves, gcodeMove{to: pts[best], arc: true, center: bestCenter, ccw: bestCCW})
+		i = best
+	}
+	return moves
+}
+
+// arcThrough fits a circle through the first, middle and last point of
+// pts and reports whether all points lie on it within tolerance and turn
+// in the same direction around it, sweeping less than a full turn.
+func arcThrough(pts [][2]float64, tolerance float64) ([2]float64, bool, bool) {
+	a, b, c := pts[0], pts[len(pts)/2], pts[len(pts)-1]
+	d := 2 * (a[0]*(b[1]-c[1]) + b[0]*(c[1]-a[1]) + c[0]*(a[1]-b[1]))
+	if math.Abs(d) < 1e-12 {
+		return [2]float64{}, false, false
+	}
+	a2, b2, c2 := a[0]*a[0]+a[1]*a[1], b[0]*b[0]+b[1]*b[1], c[0]*c[0]+c[1]*c[1]
+	center := [2]float64{
+		(a2*(b[1]-c[1]) + b2*(c[1]-a[1]) + c2*(a[1]-b[1])) / d,
+		(a2*(c[0]-b[0]) + b2*(a[0]-c[0]) + c2*(b[0]-a[0])) / d,
+	}
+	r := math.Hypot(a[0]-center[0], a[1]-center[1])
+
+	ccw := cross(a, b, c) > 0
+	sweep := 0.0
+	for i, p := range pts {
+		if math.Abs(math.Hypot(p[0]-center[0], p[1]-center[1])-r) > tolerance {
+			return center, ccw, false
+		}
+		if i == 0 {
+			continue
+		}
+		q := pts[i-1]
+		turn := cross(center, q, p)
+		if turn != 0 && (turn > 0) != ccw {
+			return center, ccw, false
+		}
+		sweep += math.Abs(math.Atan2(turn, (q[0]-center[0])*(p[0]-center[0])+(q[1]-center[1])*(p[1]-center[1])))
+	}
+	return center, ccw, sweep < 2*math.Pi-1e-9
+}
+
+// gcodeNumber formats a coordinate or rate with four decimals.
+func gcodeNumber(v float64) string {
+	return fixedNumber(v, 4)
+}
@@ -0,0 +1,106 @@
+package svg
+
+import (
+	"bytes"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteGCode(t *testing.T) {
+	svg, err := ParseSvg(`<svg>
+<path d="M0 0 L10 0 L10 10" stroke="red" stroke-width="1" fill="none"/>
+<g id="engrave"><path d="M0 0 L5 5" fill="black"/></g>
+<path d="M20 20 L30 20" stroke="#f00" stroke-width="1" fill="none"/>
+</svg>`, "test", 0)
+	require.NoError(t, err)
+	shapes, err := svg.Shapes()
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	require.NoError(t, WriteGCode(&buf, shapes, &GCodeOptions{
+		Tool: GCodeTool{PenDown: "G0 Z0", PenUp: "G0 Z5", FeedRate: 500},
+		Tools: map[string]GCodeTool{
+			"#ff0000": {PenDown: "M3 S1000", PenUp: "M5", FeedRate: 300, Passes: 2},
+			"engrave": {PenDown: "M3 S200", PenUp: "M5", FeedRate: 1200, TravelRate: 3000},
+		},
+		Origin: [2]float64{0, 10},
+		FlipY:  true,
+	}))
+
+	require.Equal(t, strings.Join([]string{
+		"G21",
+		"G90",
+		"; layer #ff0000",
+		"M5",
+		"G0 X0 Y2.6458",
+		"M3 S1000",
+		"G1 X2.6458 Y2.6458 F300",
+		"G1 X2.6458 Y0",
+		"M5",
+		"G0 X0 Y2.6458",
+		"M3 S1000",
+		"G1 X2.6458 Y2.6458 F300",
+		"G1 X2.6458 Y0",
+		"M5",
+		"G0 X5.2917 Y-2.6458",
+		"M3 S1000",
+		"G1 X7.9375 Y-2.6458 F300",
+		"M5",
+		"G0 X5.2917 Y-2.6458",
+		"M3 S1000",
+		"G1 X7.9375 Y-2.6458 F300",
+		"M5",
+		"; layer engrave",
+		"M5",
+		"G1 X0 Y2.6458 F3000",
+		"M3 S200",
+		"G1 X1.3229 Y1.3229 F1200",
+		"M5",
+		"M2",
+		"",
+	}, "\n"), buf.String())
+
+	require.Error(t, WriteGCode(&buf, shapes, &GCodeOptions{Units: "furlong"}))
+
+	// keys naming the same colour are tried in sorted order
+	tools := map[string]GCodeTool{"red": {PenDown: "M3 S1"}, "#ff0000": {PenDown: "M3 S2"}, "#f00": {PenDown: "M3 S3"}}
+	for i := 0; i < 20; i++ {
+		buf.Reset()
+		require.NoError(t, WriteGCode(&buf, shapes[:1], &GCodeOptions{Tools: tools}))
+		require.Contains(t, buf.String(), "; layer #f00\n")
+		require.Contains(t, buf.String(), "M3 S3\n")
+	}
+}
+
+func TestWriteGCodeArcs(t *testing.T) {
+	svg, err := ParseSvg(`<svg><circle cx="10" cy="10" r="5" fill="black"/></svg>`, "test", 0)
+	require.NoError(t, err)
+	shapes, err := svg.Shapes()
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	require.NoError(t, WriteGCode(&buf, shapes, &GCodeOptions{Units: "in", Arcs: true}))
+	require.Contains(t, buf.String(), "G20\n")
+	require.Contains(t, buf.String(), "G0 X0.1562 Y0.1042\nM3\nG2 X0.1562 Y0.1042 I-0.0521 J0 F1000\nM5\n")
+}
+
+func TestFitArcs(t *testing.T) {
+	var pts [][2]float64
+	for i := 0; i <= 8; i++ {
+		a := float64(i) * math.Pi / 16
+		pts = append(pts, [2]float64{10 * math.Cos(a), 10 * math.Sin(a)})
+	}
+	pts = append(pts, [2]float64{0, 20})
+
+	moves := fitArcs(pts, 0.01)
+	require.Len(t, moves, 2)
+	require.True(t, moves[0].arc)
+	require.True(t, moves[0].ccw)
+	require.InDelta(t, 0, moves[0].center[0], 1e-9)
+	require.InDelta(t, 0, moves[0].center[1], 1e-9)
+	require.InDelta(t, 10, moves[0].to[1], 1e-9)
+	require.False(t, moves[1].arc)
+}
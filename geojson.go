@@ -0,0 +1,167 @@
+package svg
+
+import (
+	"encoding/json"
+	"io"
+
+	mt "github.com/rustyoz/Mtransform"
+)
+
+// GeoOptions controls how shapes are written by WriteGeoJSON and
+// WriteWKT.
+type GeoOptions struct {
+	// Transform georeferences the drawing: it maps user units to map
+	// coordinates. Nil writes user units with the y axis flipped so that
+	// the drawing is upright.
+	Transform *mt.Transform
+}
+
+// geometry is the geometry of one shape in map coordinates. Polygons
+// are lists of rings, exterior first; every ring repeats its first
+// point at the end.
+type geometry struct {
+	polygons [][][][2]float64
+	lines    [][][2]float64
+}
+
+// shapeGeometry converts the segments of a shape to map coordinates.
+// Closed segments become polygons: the area they enclose under the
+// shape's fill rule is split into exteriors, counterclockwise, and the
+// holes inside them, clockwise. Open segments become lines.
+func shapeGeometry(s Shape, opts *GeoOptions) geometry {
+	t := mt.Identity()
+	t.Scale(1, -1)
+	if opts != nil && opts.Transform != nil {
+		t = *opts.Transform
+	}
+	apply := func(pts [][2]float64) [][2]float64 {
+		out := make([][2]float64, len(pts))
+		for i, p := range pts {
+			out[i][0], out[i][1] = t.Apply(p[0], p[1])
+		}
+		return out
+	}
+
+	var g geometry
+	var closed []Segment
+	for _, seg := range s.Segments {
+		if seg.Closed {
+			closed = append(closed, seg)
+		} else if len(seg.Points) > 1 {
+			g.lines = append(g.lines, apply(seg.Points))
+		}
+	}
+
+	for _, p := range polygons(fillLoops(closed, s.FillRule)) {
+		var rings [][][2]float64
+		for i, loop := range p {
+			ring := apply(loop.Points)
+			if exterior := i == 0; (Segment{Points: ring}.Area() > 0) != exterior {
+				ring = reversePoints(ring)
+			}
+			rings = append(rings, ring)
+		}
+		g.polygons = append(g.polygons, rings)
+	}
+	return g
+}
+
+type geoFeatureCollection struct {
+	Type     string       `json:"type"`
+	Features []geoFeature `json:"features"`
+}
+
+type geoFeature struct {
+	Type       string                 `json:"type"`
+	ID         string                 `json:"id,omitempty"`
+	Geometry   *geoGeometry           `json:"geometry"`
+	Properties map[string]interface{} `json:"properties"`
+}
+
+type geoGeometry struct {
+	Type        string        `json:"type"`
+	Coordinates interface{}   `json:"coordinates,omitempty"`
+	Geometries  []geoGeometry `json:"geometries,omitempty"`
+}
+
+// WriteGeoJSON writes the visible shapes to w as a GeoJSON
+// FeatureCollection with one feature per shape. Closed segments become
+// a Polygon or MultiPolygon, open segments a LineString or
+// MultiLineString, and shapes with both a GeometryCollection. The
+// element and group IDs and the paint of each shape are written as
+// feature properties. A nil opts uses the defaults.
+func WriteGeoJSON(w io.Writer, shapes []Shape, opts *GeoOptions) error {
+	fc := geoFeatureCollection{Type: "FeatureCollection", Features: []geoFeature{}}
+	for _, s := range shapes {
+		if s.Hidden {
+			continue
+		}
+		g := shapeGeometry(s, opts)
+		fc.Features = append(fc.Features, geoFeature{
+			Type:       "Feature",
+			ID:         s.ID,
+			Geometry:   g.geoJSON(),
+			Properties: shapeProperties(s),
+		})
+	}
+
+	enc := json.NewEncoder(w)
+	return enc.Encode(fc)
+}
+
+func (g geometry) geoJSON() *geoGeometry {
+	var parts []geoGeometry
+	switch len(g.polygons) {
+	case 0:
+	case 1:
+		parts = append(parts, geoGeometry{Type: "Polygon", Coordinates: g.polygons[0]})
+	default:
+		parts = append(parts, geoGeometry{Type: "MultiPolygon", Coordinates: g.polygons})
+	}
+	switch len(g.lines) {
+	case 0:
+	case 1:
+		parts = append(parts, geoGeometry{Type: "LineString", Coordinates: g.lines[0]})
+	default:
+		parts = append(parts, geoGeometry{Type: "MultiLineString", Coordinates: g.lines})
+	}
+
+	switch len(parts) {
+	case 0:
+		return nil
+	case 1:
+		return &parts[0]
+	}
+	return &geoGeometry{Type: "GeometryCollection", Geometries: parts}
+}
+
+// shapeProperties returns the IDs and paint of a shape.
+func shapeProperties(s Shape) map[string]interface{} {
+	props := map[string]interface{}{
+		"fill":      s.Fill,
+		"fill-rule": s.FillRule,
+	}
+	if s.ID != "" {
+		props["id"] = s.ID
+	}
+	if s.GroupID != "" {
+		props["group"] = s.GroupID
+	}
+	if s.Layer != "" {
+		props["layer"] = s.Layer
+	}
+	if s.Stroke != "" {
+		props["stroke"] = s.Stroke
+		props["stroke-width"] = s.StrokeStyle.Width
+	}
+	if s.Opacity != 1 {
+		props["opacity"] = s.Opacity
+	}
+	if s.FillOpacity != 1 {
+		props["fill-opacity"] = s.FillOpacity
+	}
+	if s.StrokeOpacity != 1 {
+		props["stroke-opacity"] = s.StrokeOpacity
+	}
+	return props
+}
@@ -0,0 +1,83 @@
+package svg
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	mt "github.com/rustyoz/Mtransform"
+	"github.com/stretchr/testify/require"
+)
+
+const geoSvg = `<svg>
+<path id="room" d="M0 0 L10 0 L10 10 L0 10 Z M2 2 L8 2 L8 8 L2 8 Z" fill="#ccc" fill-rule="evenodd"/>
+<g id="walls"><path id="wall" d="M0 20 L10 20" stroke="black" stroke-width="2" fill="none"/></g>
+<path id="both" d="M0 0 L1 0 L1 1 Z M5 5 L6 6 M10 10 L11 10 L11 11 Z"/>
+</svg>`
+
+func TestWriteGeoJSON(t *testing.T) {
+	svg, err := ParseSvg(geoSvg, "test", 0)
+	require.NoError(t, err)
+	shapes, err := svg.Shapes()
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	require.NoError(t, WriteGeoJSON(&buf, shapes, nil))
+
+	var fc struct {
+		Features []struct {
+			ID       string
+			Geometry struct {
+				Type        string
+				Coordinates json.RawMessage
+				Geometries  []struct{ Type string }
+			}
+			Properties map[string]interface{}
+		}
+	}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &fc))
+	require.Len(t, fc.Features, 3)
+
+	room := fc.Features[0]
+	require.Equal(t, "room", room.ID)
+	require.Equal(t, "Polygon", room.Geometry.Type)
+	var rings [][][2]float64
+	require.NoError(t, json.Unmarshal(room.Geometry.Coordinates, &rings))
+	require.Len(t, rings, 2)
+	// exteriors are counterclockwise and holes clockwise
+	require.InDelta(t, 100, Segment{Points: rings[0]}.Area(), 1e-9)
+	require.InDelta(t, -36, Segment{Points: rings[1]}.Area(), 1e-9)
+	require.Equal(t, "#ccc", room.Properties["fill"])
+	require.Equal(t, "evenodd", room.Properties["fill-rule"])
+
+	wall := fc.Features[1]
+	require.Equal(t, "LineString", wall.Geometry.Type)
+	require.JSONEq(t, `[[0,-20],[10,-20]]`, string(wall.Geometry.Coordinates))
+	require.Equal(t, "walls", wall.Properties["group"])
+	require.Equal(t, 2.0, wall.Properties["stroke-width"])
+
+	both := fc.Features[2]
+	require.Equal(t, "GeometryCollection", both.Geometry.Type)
+	require.Equal(t, "MultiPolygon", both.Geometry.Geometries[0].Type)
+	require.Equal(t, "LineString", both.Geometry.Geometries[1].Type)
+}
+
+func TestWriteWKT(t *testing.T) {
+	svg, err := ParseSvg(geoSvg, "test", 0)
+	require.NoError(t, err)
+	shapes, err := svg.Shapes()
+	require.NoError(t, err)
+
+	geo := mt.Identity()
+	geo.Translate(1000, 2000)
+	geo.Scale(0.5, -0.5)
+
+	require.Equal(t, "LINESTRING (1000 1990, 1005 1990)", shapes[1].WKT(&GeoOptions{Transform: &geo}))
+
+	var buf bytes.Buffer
+	require.NoError(t, WriteWKT(&buf, shapes, nil))
+	lines := bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n"))
+	require.Len(t, lines, 3)
+	require.Regexp(t, `^POLYGON \(\([^()]+\), \([^()]+\)\)$`, string(lines[0]))
+	require.Regexp(t, `^GEOMETRYCOLLECTION \(MULTIPOLYGON \(\(\([^()]+\)\), \(\([^()]+\)\)\), LINESTRING \(5 -5, 6 -6\)\)$`, string(lines[2]))
+}
@@ -1,10 +1,18 @@
 module github.com/vasalvit/svg
 
-go 1.14
+go 1.18
 
 require (
 	github.com/cheekybits/is v0.0.0-20150225183255-68e9c0620927
 	github.com/rustyoz/Mtransform v0.0.0-20190224104252-60c8c35a3681
 	github.com/rustyoz/genericlexer v0.0.0-20190224115003-eb82fd2987bd
 	github.com/stretchr/testify v1.6.1
+	golang.org/x/image v0.18.0
+)
+
+require (
+	github.com/davecgh/go-spew v1.1.0 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	golang.org/x/text v0.16.0 // indirect
+	gopkg.in/yaml.v3 v3.0.0-20200313102051-9f266ea9e77c // indirect
 )
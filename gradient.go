@@ -0,0 +1,460 @@
+package svg
+
+import (
+	"encoding/xml"
+	"fmt"
+	"image/color"
+	"math"
+	"strconv"
+	"strings"
+
+	mt "github.com/rustyoz/Mtransform"
+)
+
+// Gradient is a linearGradient or radialGradient paint server, with the
+// attributes and stops it inherits through its href resolved.
+type Gradient struct {
+	ID string `json:"id,omitempty"`
+	// Radial is true for a radialGradient.
+	Radial bool `json:"radial,omitempty"`
+	// Units is "objectBoundingBox", where the coordinates are fractions
+	// of the bounding box of the painted element, or "userSpaceOnUse".
+	Units string `json:"units"`
+	// Transform is the gradientTransform. In the PaintInstruction of an
+	// element it also includes the transforms of the element and its
+	// groups for userSpaceOnUse gradients, so that the coordinates are
+	// in the space of the drawing instructions.
+	Transform mt.Transform `json:"transform"`
+	// SpreadMethod is "pad", "reflect" or "repeat".
+	SpreadMethod string `json:"spreadMethod"`
+	// X1, Y1, X2 and Y2 are the gradient vector of a linear gradient.
+	X1 float64 `json:"x1,omitempty"`
+	Y1 float64 `json:"y1,omitempty"`
+	X2 float64 `json:"x2,omitempty"`
+	Y2 float64 `json:"y2,omitempty"`
+	// Cx, Cy and R are the end circle of a radial gradient and Fx and Fy
+	// its focal point.
+	Cx float64 `json:"cx,omitempty"`
+	Cy float64 `json:"cy,omitempty"`
+	R  float64 `json:"r,omitempty"`
+	Fx float64 `json:"fx,omitempty"`
+	Fy float64 `json:"fy,omitempty"`
+	// Stops are sorted by offset. A gradient without stops paints
+	// nothing and one with a single stop paints its colour.
+	Stops []GradientStop `json:"stops"`
+}
+
+// GradientStop is a colour of a gradient at an offset between 0 and 1
+// along it. The stop-opacity is included in the alpha of Color.
+type GradientStop struct {
+	Offset float64     `json:"offset"`
+	Color  color.NRGBA `json:"color"`
+}
+
+// gradientElement is a gradient as written in the document, before
+// its href is resolved.
+type gradientElement struct {
+	tag   string
+	id    string
+	attrs map[string]string
+	stops []GradientStop
+	pos   position
+}
+
+// gradientAttrs are the attributes of a gradient that are inherited
+// through its href.
+var gradientAttrs = map[string]bool{
+	"gradientUnits": true, "gradientTransform": true, "spreadMethod": true, "href": true,
+	"x1": true, "y1": true, "x2": true, "y2": true,
+	"cx": true, "cy": true, "r": true, "fx": true, "fy": true,
+}
+
+// decodeGradient decodes the gradient element start and keeps it to be
+// resolved once the document is decoded.
+func (s *Svg) decodeGradient(decoder *xml.Decoder, start xml.StartElement, pos position) error {
+	e := &gradientElement{tag: start.Name.Local, id: attrValue(start, "id"), attrs: make(map[string]string), pos: pos}
+	for _, attr := range start.Attr {
+		if gradientAttrs[attr.Name.Local] {
+			e.attrs[attr.Name.Local] = strings.TrimSpace(attr.Value)
+		}
+	}
+
+	ancestors := pos.child(e.tag, e.id)
+	for {
+		offset := decoder.InputOffset()
+		token, err := decoder.Token()
+		if err != nil {
+			return pos.error(e.tag, e.id, -1, err)
+		}
+		switch tok := token.(type) {
+		case xml.StartElement:
+			stopPos := s.position(offset, ancestors)
+			if err := s.checkLimits(stopPos); err != nil {
+				return stopPos.error(tok.Name.Local, attrValue(tok, "id"), -1, err)
+			}
+			if tok.Name.Local == "stop" {
+				stop, err := parseStop(tok)
+				if err != nil {
+					// an invalid stop is ignored
+					if err = s.warn(stopPos, "stop", attrValue(tok, "id"), -1, err); err != nil {
+						return err
+					}
+				} else {
+					e.stops = append(e.stops, stop)
+				}
+			}
+			if err := decoder.Skip(); err != nil {
+				return stopPos.error(tok.Name.Local, attrValue(tok, "id"), -1, err)
+			}
+		case xml.EndElement:
+			s.gradientElements = append(s.gradientElements, e)
+			return nil
+		}
+	}
+}
+
+// parseStop parses a stop element. Stop offsets are clamped to the
+// range 0 to 1.
+func parseStop(start xml.StartElement) (GradientStop, error) {
+	props := map[string]string{"stop-color": attrValue(start, "stop-color"), "stop-opacity": attrValue(start, "stop-opacity")}
+	for name, value := range splitStyle(attrValue(start, "style")) {
+		if name = strings.TrimSpace(name); name == "stop-color" || name == "stop-opacity" {
+			props[name] = value
+		}
+	}
+
+	stop := GradientStop{Color: color.NRGBA{A: 0xff}}
+	if v := strings.TrimSpace(attrValue(start, "offset")); v != "" {
+		offset, err := parseFraction(v)
+		if err != nil {
+			return stop, fmt.Errorf("invalid offset: %w", err)
+		}
+		stop.Offset = clampUnit(offset)
+	}
+	if v := strings.TrimSpace(props["stop-color"]); v != "" {
+		c, ok := ParseColor(v)
+		if !ok {
+			return stop, fmt.Errorf("invalid stop-color %q", v)
+		}
+		stop.Color = c
+	}
+	if v := strings.TrimSpace(props["stop-opacity"]); v != "" {
+		opacity, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return stop, fmt.Errorf("invalid stop-opacity: %w", err)
+		}
+		stop.Color.A = uint8(float64(stop.Color.A)*clampUnit(opacity) + 0.5)
+	}
+	return stop, nil
+}
+
+// parseFraction parses a number or a percentage as a fraction.
+func parseFraction(v string) (float64, error) {
+	if strings.HasSuffix(v, "%") {
+		f, err := strconv.ParseFloat(strings.TrimSuffix(v, "%"), 64)
+		return f / 100, err
+	}
+	return strconv.ParseFloat(v, 64)
+}
+
+// resolveGradients resolves the href of every gradient element of the
+// document. References that cannot be resolved are ignored with a
+// warning.
+func (s *Svg) resolveGradients() error {
+	ids := make(map[string]*gradientElement)
+	for _, e := range s.gradientElements {
+		// the first element with an ID wins
+		if e.id != "" && ids[e.id] == nil {
+			ids[e.id] = e
+		}
+	}
+	s.gradients = make(map[string]*Gradient)
+	for _, e := range s.gradientElements {
+		if e.id == "" || ids[e.id] != e {
+			continue
+		}
+		// chain is e followed by the gradients it inherits from
+		chain := []*gradientElement{e}
+		for cur := e; cur.attrs["href"] != ""; {
+			href := cur.attrs["href"]
+			if !strings.HasPrefix(href, "#") {
+				err := fmt.Errorf("unsupported reference %q", href)
+				if err = s.warn(cur.pos, cur.tag, cur.id, -1, err); err != nil {
+					return err
+				}
+				break
+			}
+			next := ids[href[1:]]
+			if next == nil {
+				err := fmt.Errorf("reference to unknown gradient %q", href[1:])
+				if err = s.warn(cur.pos, cur.tag, cur.id, -1, err); err != nil {
+					return err
+				}
+				break
+			}
+			circular := false
+			for _, c := range chain {
+				circular = circular || c == next
+			}
+			if circular {
+				err := fmt.Errorf("circular reference to %q", href[1:])
+				if err = s.warn(cur.pos, cur.tag, cur.id, -1, err); err != nil {
+					return err
+				}
+				break
+			}
+			chain = append(chain, next)
+			cur = next
+		}
+
+		g, err := s.newGradient(chain)
+		if err != nil {
+			return err
+		}
+		s.gradients[e.id] = g
+	}
+	return nil
+}
+
+// newGradient builds the gradient chain[0], which inherits the
+// attributes and stops it does not specify from the rest of chain.
+// Geometry is only inherited from gradients of the same kind.
+func (s *Svg) newGradient(chain []*gradientElement) (*Gradient, error) {
+	e := chain[0]
+	attr := func(name string, geometry bool) string {
+		for _, c := range chain {
+			if geometry && c.tag != e.tag {
+				continue
+			}
+			if v, ok := c.attrs[name]; ok {
+				return v
+			}
+		}
+		return ""
+	}
+	warn := func(err error) error {
+		return s.warn(e.pos, e.tag, e.id, -1, err)
+	}
+
+	g := &Gradient{ID: e.id, Radial: e.tag == "radialGradient", Units: "objectBoundingBox", Transform: mt.Identity(), SpreadMethod: "pad"}
+	for _, c := range chain {
+		if len(c.stops) > 0 {
+			g.Stops = append([]GradientStop(nil), c.stops...)
+			break
+		}
+	}
+	// offsets smaller than the one before are raised to it
+	for i := 1; i < len(g.Stops); i++ {
+		g.Stops[i].Offset = math.Max(g.Stops[i].Offset, g.Stops[i-1].Offset)
+	}
+
+	switch units := attr("gradientUnits", false); units {
+	case "":
+	case "objectBoundingBox", "userSpaceOnUse":
+		g.Units = units
+	default:
+		if err := warn(fmt.Errorf("invalid gradientUnits %q", units)); err != nil {
+			return nil, err
+		}
+	}
+	switch spread := attr("spreadMethod", false); spread {
+	case "":
+	case "pad", "reflect", "repeat":
+		g.SpreadMethod = spread
+	default:
+		if err := warn(fmt.Errorf("invalid spreadMethod %q", spread)); err != nil {
+			return nil, err
+		}
+	}
+	if v := attr("gradientTransform", false); v != "" {
+		t, err := parseTransform(v)
+		if err != nil {
+			if err = warn(fmt.Errorf("invalid gradientTransform %q: %w", v, err)); err != nil {
+				return nil, err
+			}
+		} else {
+			g.Transform = t
+		}
+	}
+
+	// percentages of userSpaceOnUse gradients are relative to the
+	// viewport, and those of the radius to its normalised diagonal
+	var w, h float64
+	if vb, err := s.ViewBoxValues(); err == nil && len(vb) == 4 {
+		w, h = vb[2], vb[3]
+	} else {
+		w, _ = s.length(s.Width)
+		h, _ = s.length(s.Height)
+	}
+	bbox := g.Units == "objectBoundingBox"
+	percent := func(f, size float64) float64 {
+		if bbox {
+			return f
+		}
+		return f * size
+	}
+	var warnErr error
+	coordinate := func(name string, def, size float64) float64 {
+		v := attr(name, true)
+		if v == "" {
+			return def
+		}
+		var f float64
+		var err error
+		switch {
+		case strings.HasSuffix(v, "%"):
+			if f, err = parseFraction(v); err == nil {
+				f = percent(f, size)
+			}
+		case bbox:
+			f, err = strconv.ParseFloat(v, 64)
+		default:
+			f, err = s.length(v)
+		}
+		if err != nil {
+			// an invalid coordinate is ignored as if it was not specified
+			if warnErr == nil {
+				warnErr = warn(fmt.Errorf("invalid %s: %w", name, err))
+			}
+			return def
+		}
+		return f
+	}
+	if g.Radial {
+		g.Cx = coordinate("cx", percent(0.5, w), w)
+		g.Cy = coordinate("cy", percent(0.5, h), h)
+		diagonal := math.Sqrt((w*w + h*h) / 2)
+		g.R = coordinate("r", percent(0.5, diagonal), diagonal)
+		// the focal point defaults to the centre
+		g.Fx = coordinate("fx", g.Cx, w)
+		g.Fy = coordinate("fy", g.Cy, h)
+	} else {
+		g.X1 = coordinate("x1", 0, w)
+		g.Y1 = coordinate("y1", 0, h)
+		g.X2 = coordinate("x2", percent(1, w), w)
+		g.Y2 = coordinate("y2", 0, h)
+	}
+	if warnErr != nil {
+		return nil, warnErr
+	}
+	return g, nil
+}
+
+// gradient returns the gradient the paint value paint, like
+// "url(#id)", refers to, or nil if it does not refer to one.
+func (s *Svg) gradient(paint string) *Gradient {
+	paint = strings.TrimSpace(paint)
+	end := strings.IndexByte(paint, ')')
+	if s == nil || !strings.HasPrefix(paint, "url(") || end < 0 {
+		return nil
+	}
+	ref := strings.Trim(strings.TrimSpace(paint[4:end]), `'"`)
+	if !strings.HasPrefix(ref, "#") {
+		return nil
+	}
+	return s.gradients[ref[1:]]
+}
+
+// inSpace returns g for an element whose coordinates are transformed
+// by t. The coordinates of a userSpaceOnUse gradient are in the space
+// of the element, so t is applied to them as well.
+func (g *Gradient) inSpace(t mt.Transform) *Gradient {
+	if g == nil || g.Units != "userSpaceOnUse" {
+		return g
+	}
+	c := *g
+	c.Transform = mt.MultiplyTransforms(t, g.Transform)
+	return &c
+}
+
+// color returns the colour of g, as a premultiplied colour, at the point
+// x, y of the gradient's coordinate system, before Transform and, for
+// objectBoundingBox gradients, the bounding box are applied.
+func (g *Gradient) color(x, y float64) color.RGBA {
+	if len(g.Stops) == 0 {
+		return color.RGBA{}
+	}
+	var t float64
+	if g.Radial {
+		// the point lies on the circle between the focal point, with
+		// radius zero, and the end circle at t
+		fx, fy := g.Fx, g.Fy
+		if d := math.Hypot(fx-g.Cx, fy-g.Cy); d > g.R*0.999 {
+			// a focal point outside the end circle is moved onto it
+			fx = g.Cx + (fx-g.Cx)*g.R*0.999/d
+			fy = g.Cy + (fy-g.Cy)*g.R*0.999/d
+		}
+		dx, dy := x-fx, y-fy
+		ex, ey := g.Cx-fx, g.Cy-fy
+		a := ex*ex + ey*ey - g.R*g.R
+		b := dx*ex + dy*ey
+		if a >= 0 {
+			return g.stopColor(1)
+		}
+		t = (b - math.Sqrt(b*b-a*(dx*dx+dy*dy))) / a
+	} else {
+		vx, vy := g.X2-g.X1, g.Y2-g.Y1
+		l := vx*vx + vy*vy
+		if l == 0 {
+			return g.stopColor(1)
+		}
+		t = ((x-g.X1)*vx + (y-g.Y1)*vy) / l
+	}
+
+	switch g.SpreadMethod {
+	case "repeat":
+		t -= math.Floor(t)
+	case "reflect":
+		if t = math.Mod(math.Abs(t), 2); t > 1 {
+			t = 2 - t
+		}
+	}
+	return g.stopColor(t)
+}
+
+// stopColor returns the colour at offset t, interpolated between the
+// stops with premultiplied alpha.
+func (g *Gradient) stopColor(t float64) color.RGBA {
+	stops := g.Stops
+	if t <= stops[0].Offset {
+		return premultiply(stops[0].Color)
+	}
+	for i := 1; i < len(stops); i++ {
+		if t < stops[i].Offset {
+			a, b := premultiply(stops[i-1].Color), premultiply(stops[i].Color)
+			f := (t - stops[i-1].Offset) / (stops[i].Offset - stops[i-1].Offset)
+			mix := func(u, v uint8) uint8 {
+				return uint8(float64(u) + (float64(v)-float64(u))*f + 0.5)
+			}
+			return color.RGBA{mix(a.R, b.R), mix(a.G, b.G), mix(a.B, b.B), mix(a.A, b.A)}
+		}
+	}
+	return premultiply(stops[len(stops)-1].Color)
+}
+
+func premultiply(c color.NRGBA) color.RGBA {
+	return color.RGBAModel.Convert(c).(color.RGBA)
+}
+
+// invertTransform returns the inverse of the affine transform t.
+func invertTransform(t mt.Transform) (mt.Transform, bool) {
+	det := t[0][0]*t[1][1] - t[0][1]*t[1][0]
+	if det == 0 || math.IsNaN(det) || math.IsInf(det, 0) {
+		return mt.Identity(), false
+	}
+	inv := mt.Identity()
+	inv[0][0], inv[0][1] = t[1][1]/det, -t[0][1]/det
+	inv[1][0], inv[1][1] = -t[1][0]/det, t[0][0]/det
+	inv[0][2] = -(inv[0][0]*t[0][2] + inv[0][1]*t[1][2])
+	inv[1][2] = -(inv[1][0]*t[0][2] + inv[1][1]*t[1][2])
+	return inv, true
+}
+
+// paintGradient returns the gradient paint refers to for an element
+// whose coordinates are transformed by t.
+func (s *Svg) paintGradient(paint *string, t mt.Transform) *Gradient {
+	if paint == nil {
+		return nil
+	}
+	return s.gradient(*paint).inSpace(t)
+}
@@ -0,0 +1,99 @@
+package svg
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const gradientSvg = `<svg width="100" height="20" viewBox="0 0 100 20">
+<defs>
+	<linearGradient id="base" x2="50%" spreadMethod="reflect">
+		<stop offset="0" stop-color="black"/>
+		<stop offset="100%" style="stop-color: #ffffff; stop-opacity: 0.5"/>
+	</linearGradient>
+	<linearGradient id="wide" href="#base" x2="1" spreadMethod="pad" gradientTransform="translate(0.5 0)"/>
+	<radialGradient id="user" href="#base" gradientUnits="userSpaceOnUse" cx="10" cy="10" r="10"/>
+	<radialGradient id="loop" href="#loop2"/>
+	<radialGradient id="loop2" href="#loop"><stop stop-color="red"/></radialGradient>
+</defs>
+<path d="M0 0 L100 0 L100 10 L0 10 Z" fill="url(#wide)"/>
+<g transform="translate(50 0)">
+	<path d="M0 10 L20 10 L20 20 L0 20 Z" fill="url('#user')" stroke="url(#missing) red"/>
+</g>
+</svg>`
+
+func TestGradient(t *testing.T) {
+	svg, err := ParseSvg(gradientSvg, "gradient", 0)
+	require.NoError(t, err)
+	require.Len(t, svg.Warnings, 2)
+	require.Contains(t, svg.Warnings[0].Error(), `circular reference to "loop"`)
+
+	shapes, err := svg.Shapes()
+	require.NoError(t, err)
+	require.Len(t, shapes, 2)
+
+	// stops and attributes are inherited through href
+	wide := shapes[0].FillGradient
+	require.NotNil(t, wide)
+	require.False(t, wide.Radial)
+	require.Equal(t, "objectBoundingBox", wide.Units)
+	require.Equal(t, "pad", wide.SpreadMethod)
+	require.Equal(t, [4]float64{0, 0, 1, 0}, [4]float64{wide.X1, wide.Y1, wide.X2, wide.Y2})
+	require.Equal(t, 0.5, wide.Transform[0][2])
+	require.Equal(t, []GradientStop{
+		{0, color.NRGBA{A: 0xff}},
+		{1, color.NRGBA{R: 0xff, G: 0xff, B: 0xff, A: 0x80}},
+	}, wide.Stops)
+
+	// userSpaceOnUse coordinates follow the transform of the element
+	user := shapes[1].FillGradient
+	require.NotNil(t, user)
+	require.True(t, user.Radial)
+	require.Equal(t, "reflect", user.SpreadMethod)
+	require.Equal(t, [5]float64{10, 10, 10, 10, 10}, [5]float64{user.Cx, user.Cy, user.R, user.Fx, user.Fy})
+	require.Equal(t, 50.0, user.Transform[0][2])
+	require.Len(t, user.Stops, 2)
+	require.Nil(t, shapes[1].StrokeGradient)
+
+	// the gradients survive the binary encoding
+	var instrs []*DrawingInstruction
+	for _, s := range shapes {
+		instrs = append(instrs, s.Instructions...)
+	}
+	var buf bytes.Buffer
+	require.NoError(t, EncodeInstructions(&buf, instrs))
+	decoded, err := DecodeInstructions(&buf)
+	require.NoError(t, err)
+	require.Equal(t, instrs, decoded)
+
+	img := image.NewRGBA(image.Rect(0, 0, 100, 20))
+	require.NoError(t, Render(svg, img, &RenderOptions{Background: color.White}))
+	// the linear gradient is padded black left of its start, which
+	// the transform moves to the middle
+	require.Equal(t, color.RGBA{A: 0xff}, img.RGBAAt(20, 5))
+	// and turns half transparent white, shown over the background
+	require.InDelta(t, 65, int(img.RGBAAt(75, 5).R), 3)
+	require.InDelta(t, 126, int(img.RGBAAt(99, 5).R), 3)
+	// the radial gradient is black in its centre and reflected beyond
+	// its radius
+	require.InDelta(t, 0, int(img.RGBAAt(60, 10).R), 20)
+	require.InDelta(t, 243, int(img.RGBAAt(69, 10).R), 3)
+	require.Less(t, int(img.RGBAAt(50, 19).R), 0xff)
+
+	// the writer keeps the gradients
+	buf.Reset()
+	require.NoError(t, WriteSvg(&buf, shapes, nil))
+	written, err := ParseSvg(buf.String(), "written", 0)
+	require.NoError(t, err)
+	shapes2, err := written.Shapes()
+	require.NoError(t, err)
+	got := *shapes2[0].FillGradient
+	require.Equal(t, "gradient-1", got.ID)
+	got.ID = wide.ID
+	require.Equal(t, *wide, got)
+	require.Equal(t, user.Transform, shapes2[1].FillGradient.Transform)
+}
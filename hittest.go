@@ -0,0 +1,75 @@
+package svg
+
+// Contains reports whether the point (x, y), given in world space, lies
+// inside the filled area of the shape according to its fill rule.
+// Hidden and unfilled shapes contain no points.
+func (s Shape) Contains(x, y float64) bool {
+	if s.Hidden || !s.Filled() {
+		return false
+	}
+	w := 0
+	for _, seg := range s.Segments {
+		w += seg.winding(x, y)
+	}
+	return insideFill(w, s.FillRule)
+}
+
+// StrokeContains reports whether the point (x, y) lies on the painted
+// stroke of the shape, taking its stroke width, caps and joins into
+// account.
+func (s Shape) StrokeContains(x, y float64) bool {
+	if s.Hidden || !s.Stroked() {
+		return false
+	}
+	w := 0
+	for _, seg := range StrokeSegments(s.Segments, s.StrokeStyle) {
+		w += seg.winding(x, y)
+	}
+	return w != 0
+}
+
+// Contains reports whether the point (x, y) lies inside the filled area
+// of the path, honouring its fill rule, transforms and visibility.
+func (p *Path) Contains(x, y float64) (bool, error) {
+	var owner *Svg
+	if p.group != nil {
+		owner = p.group.Owner
+	}
+	shapes, err := appendShapes(nil, p, p.group, p.flattenTolerance(), owner.pointLimit())
+	if err != nil || len(shapes) == 0 {
+		return false, err
+	}
+	return shapes[0].Contains(x, y), nil
+}
+
+// Contains reports whether the point (x, y) lies inside the filled area
+// of any visible element of the document.
+func (s *Svg) Contains(x, y float64) (bool, error) {
+	e, err := s.HitTest(x, y)
+	return e != nil, err
+}
+
+// HitTest returns the topmost element in paint order whose filled area
+// contains the point (x, y), or nil if there is none.
+func (s *Svg) HitTest(x, y float64) (DrawingInstructionParser, error) {
+	return s.hitTest(x, y, false)
+}
+
+// HitTestStroke is like HitTest but also hits elements whose painted
+// stroke contains the point.
+func (s *Svg) HitTestStroke(x, y float64) (DrawingInstructionParser, error) {
+	return s.hitTest(x, y, true)
+}
+
+func (s *Svg) hitTest(x, y float64, stroke bool) (DrawingInstructionParser, error) {
+	shapes, err := s.Shapes()
+	if err != nil {
+		return nil, err
+	}
+	for i := len(shapes) - 1; i >= 0; i-- {
+		if shapes[i].Contains(x, y) || (stroke && shapes[i].StrokeContains(x, y)) {
+			return shapes[i].Element, nil
+		}
+	}
+	return nil, nil
+}
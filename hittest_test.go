@@ -0,0 +1,72 @@
+package svg
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const hitTestSvg = `<svg viewBox="0 0 100 100">
+<path id="frame" d="M0 0 L50 0 L50 50 L0 50 Z M10 10 L40 10 L40 40 L10 40 Z" fill-rule="evenodd"/>
+<path id="hidden" d="M0 0 L100 0 L100 100 L0 100 Z" visibility="hidden"/>
+<g id="moved" transform="translate(60 60)">
+	<path id="square" d="M0 0 L20 0 L20 20 L0 20 Z"/>
+	<circle id="dot" cx="35" cy="35" r="3"/>
+	<g display="none"><path id="gone" d="M0 0 L20 0 L20 20 L0 20 Z"/></g>
+</g>
+<circle id="big" cx="45" cy="5" r="2" transform="scale(2)"/>
+<path id="line" d="M0 90 L50 90" fill="none" stroke="#000" stroke-width="4"/>
+</svg>`
+
+func TestHitTest(t *testing.T) {
+	svg, err := ParseSvg(hitTestSvg, "test", 0)
+	require.NoError(t, err)
+
+	tests := []struct {
+		X, Y   float64
+		Fill   string
+		Stroke string
+	}{
+		{5, 5, "frame", "frame"},
+		{25, 25, "", ""},
+		{65, 65, "square", "square"},
+		{5, 65, "", ""},
+		{95, 95, "dot", "dot"},
+		{35, 35, "", ""},
+		{93, 10, "big", "big"},
+		{95, 10, "", ""},
+		{25, 91, "", "line"},
+		{25, 95, "", ""},
+	}
+
+	for _, test := range tests {
+		e, err := svg.HitTest(test.X, test.Y)
+		require.NoError(t, err)
+		require.Equal(t, test.Fill, elementID(e), "fill hit at %v,%v", test.X, test.Y)
+
+		e, err = svg.HitTestStroke(test.X, test.Y)
+		require.NoError(t, err)
+		require.Equal(t, test.Stroke, elementID(e), "stroke hit at %v,%v", test.X, test.Y)
+	}
+
+	inside, err := svg.Contains(65, 65)
+	require.NoError(t, err)
+	require.True(t, inside)
+
+	inside, err = svg.Elements[0].(*Path).Contains(25, 25)
+	require.NoError(t, err)
+	require.False(t, inside)
+}
+
+func TestHitTestScaled(t *testing.T) {
+	// the stroke is as wide as the segments' user units say, whatever
+	// the scale
+	svg, err := ParseSvg(`<svg><path id="line" d="M0 0 L10 0" fill="none" stroke="#000" stroke-width="4"/></svg>`, "test", 2)
+	require.NoError(t, err)
+	e, err := svg.HitTestStroke(5, 1.5)
+	require.NoError(t, err)
+	require.Equal(t, "line", elementID(e))
+	e, err = svg.HitTestStroke(5, 3)
+	require.NoError(t, err)
+	require.Nil(t, e)
+}
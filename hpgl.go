@@ -0,0 +1,190 @@
+package svg
+
+import (
+	"bytes"
+	"fmt"
+	"image/color"
+	"io"
+	"math"
+)
+
+// hpglUnitsPerMM is the resolution of HPGL plotter units.
+const hpglUnitsPerMM = 40
+
+// HPGLOptions controls how shapes are written by WriteHPGL.
+type HPGLOptions struct {
+	// Pens maps colours to pen numbers. Colours match any spelling of
+	// the same colour. Colours without an entry are given the next free
+	// pen, wrapping around after PenCount pens.
+	Pens map[string]int
+	// PenCount is the number of pens in the carousel. Zero means 6, as
+	// on the HP 7475A.
+	PenCount int
+	// Scale is the size of a user unit in millimetres. Zero means
+	// 25.4/96, the size of a CSS pixel.
+	Scale float64
+	// KeepOrder draws segments in document order instead of ordering
+	// them to minimise pen-up travel.
+	KeepOrder bool
+}
+
+// WriteHPGL writes the outlines of the visible, painted shapes to w as
+// HPGL. Every shape is drawn with the pen of its stroke colour, or of
+// its fill colour if it is not stroked. All segments of a pen are drawn
+// together; unless KeepOrder is set they are ordered greedily by the
+// nearest next start point, reversing open segments and rotating closed
+// ones where that shortens the pen-up move. The drawing is flipped to
+// the plotter's y-up axis and moved so that its bounding box starts at
+// the plotter origin. A nil opts uses the defaults.
+func WriteHPGL(w io.Writer, shapes []Shape, opts *HPGLOptions) error {
+	if opts == nil {
+		opts = &HPGLOptions{}
+	}
+	scale := opts.Scale
+	if scale == 0 {
+		scale = 25.4 / userUnitsPerInch
+	}
+	scale *= hpglUnitsPerMM
+	penCount := opts.PenCount
+	if penCount <= 0 {
+		penCount = 6
+	}
+
+	pens := make(map[color.NRGBA]int)
+	used := make(map[int]bool)
+	for key, pen := range opts.Pens {
+		if c, ok := ParseColor(key); ok {
+			pens[c] = pen
+			used[pen] = true
+		}
+	}
+	// freePen returns the next pen not used yet, or cycles through all
+	// pens once every pen is used
+	next := 0
+	freePen := func() int {
+		for i := 0; i < penCount; i++ {
+			pen := (next+i)%penCount + 1
+			if !used[pen] {
+				next += i + 1
+				used[pen] = true
+				return pen
+			}
+		}
+		pen := next%penCount + 1
+		next++
+		return pen
+	}
+	var order []int
+	paths := make(map[int][][][2]float64)
+	minX, minY := math.Inf(1), math.Inf(1)
+	for _, s := range shapes {
+		if s.Hidden || (!s.Filled() && !s.Stroked()) {
+			continue
+		}
+		paint := s.Fill
+		if s.Stroked() {
+			paint = s.Stroke
+		}
+		c, _ := ParseColor(paint)
+		pen, ok := pens[c]
+		if !ok {
+			pen = freePen()
+			pens[c] = pen
+		}
+		if _, ok := paths[pen]; !ok {
+			paths[pen] = nil
+			order = append(order, pen)
+		}
+
+		for _, seg := range s.Segments {
+			if len(seg.Points) < 2 {
+				continue
+			}
+			pts := make([][2]float64, len(seg.Points))
+			for i, p := range seg.Points {
+				pts[i] = [2]float64{p[0] * scale, -p[1] * scale}
+				minX, minY = math.Min(minX, pts[i][0]), math.Min(minY, pts[i][1])
+			}
+			paths[pen] = append(paths[pen], pts)
+		}
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("IN;")
+	pos := [2]float64{minX, minY}
+	for _, pen := range order {
+		if len(paths[pen]) == 0 {
+			continue
+		}
+		fmt.Fprintf(&buf, "\nSP%d;", pen)
+		ordered := paths[pen]
+		if !opts.KeepOrder {
+			ordered = orderPaths(ordered, pos)
+		}
+		for _, pts := range ordered {
+			fmt.Fprintf(&buf, "\nPU%d,%d;PD", hpglCoord(pts[0][0]-minX), hpglCoord(pts[0][1]-minY))
+			for i, p := range pts[1:] {
+				if i > 0 {
+					buf.WriteByte(',')
+				}
+				fmt.Fprintf(&buf, "%d,%d", hpglCoord(p[0]-minX), hpglCoord(p[1]-minY))
+			}
+			buf.WriteByte(';')
+			pos = pts[len(pts)-1]
+		}
+	}
+	buf.WriteString("\nPU;SP0;\n")
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+func hpglCoord(v float64) int {
+	return int(math.Round(v))
+}
+
+// orderPaths orders paths greedily so that each one starts at the point
+// nearest to the end of the previous one, starting from pos. Open paths
+// may be reversed and closed paths, whose first and last points are
+// equal, may be started at any of their points.
+func orderPaths(paths [][][2]float64, pos [2]float64) [][][2]float64 {
+	used := make([]bool, len(paths))
+	result := make([][][2]float64, 0, len(paths))
+	for range paths {
+		best, bestIndex, dist := -1, 0, math.Inf(1)
+		for i, pts := range paths {
+			if used[i] {
+				continue
+			}
+			if samePoint(pts[0], pts[len(pts)-1]) {
+				for j, p := range pts[:len(pts)-1] {
+					if d := math.Hypot(p[0]-pos[0], p[1]-pos[1]); d < dist {
+						best, bestIndex, dist = i, j, d
+					}
+				}
+				continue
+			}
+			if d := math.Hypot(pts[0][0]-pos[0], pts[0][1]-pos[1]); d < dist {
+				best, bestIndex, dist = i, 0, d
+			}
+			last := pts[len(pts)-1]
+			if d := math.Hypot(last[0]-pos[0], last[1]-pos[1]); d < dist {
+				best, bestIndex, dist = i, len(pts)-1, d
+			}
+		}
+
+		used[best] = true
+		pts := paths[best]
+		switch {
+		case bestIndex == 0:
+		case samePoint(pts[0], pts[len(pts)-1]):
+			ring := append(append([][2]float64{}, pts[bestIndex:len(pts)-1]...), pts[:bestIndex+1]...)
+			pts = ring
+		default:
+			pts = reversePoints(pts)
+		}
+		result = append(result, pts)
+		pos = pts[len(pts)-1]
+	}
+	return result
+}
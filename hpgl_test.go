@@ -0,0 +1,53 @@
+package svg
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteHPGL(t *testing.T) {
+	svg, err := ParseSvg(`<svg>
+<path d="M0 0 L1 0" stroke="red" stroke-width="1" fill="none"/>
+<path d="M10 0 L2 0" stroke="red" stroke-width="1" fill="none"/>
+<path d="M0 5 L1 5 L1 6 L0 6 Z" stroke="blue" stroke-width="1" fill="none"/>
+<path d="M20 20 L21 21" stroke="#ff0000" stroke-width="1" fill="none"/>
+</svg>`, "test", 0)
+	require.NoError(t, err)
+	shapes, err := svg.Shapes()
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	require.NoError(t, WriteHPGL(&buf, shapes, &HPGLOptions{Scale: 1, Pens: map[string]int{"blue": 3}}))
+	require.Equal(t, "IN;"+
+		"\nSP1;"+
+		"\nPU800,40;PD840,0;"+
+		"\nPU400,840;PD80,840;"+
+		"\nPU40,840;PD0,840;"+
+		"\nSP3;"+
+		"\nPU0,640;PD40,640,40,600,0,600,0,640;"+
+		"\nPU;SP0;\n", buf.String())
+
+	buf.Reset()
+	require.NoError(t, WriteHPGL(&buf, shapes, &HPGLOptions{Scale: 1, KeepOrder: true}))
+	require.Contains(t, buf.String(), "\nSP1;\nPU0,840;PD40,840;\nPU400,840;PD80,840;")
+	require.Contains(t, buf.String(), "\nSP2;")
+
+	// automatic pens skip the pens assigned to colours, and every pen
+	// is selected once even if its first shapes have no segments
+	svg, err = ParseSvg(`<svg>
+<path d="M5 5" stroke="green" stroke-width="1"/>
+<path d="M5 5" stroke="green" stroke-width="1"/>
+<path d="M0 0 L1 0" stroke="green" stroke-width="1" fill="none"/>
+<path d="M0 1 L1 1" stroke="blue" stroke-width="1" fill="none"/>
+</svg>`, "test", 0)
+	require.NoError(t, err)
+	shapes, err = svg.Shapes()
+	require.NoError(t, err)
+	buf.Reset()
+	require.NoError(t, WriteHPGL(&buf, shapes, &HPGLOptions{Scale: 1, Pens: map[string]int{"blue": 1}}))
+	require.Equal(t, 1, strings.Count(buf.String(), "SP2;"))
+	require.Equal(t, 1, strings.Count(buf.String(), "SP1;"))
+}
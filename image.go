@@ -0,0 +1,46 @@
+package svg
+
+import (
+	"fmt"
+	"io"
+)
+
+// Image is an SVG image element. It is not drawn; Open reads the image
+// its href refers to through the ResourceLoader of the document.
+type Image struct {
+	ID              string  `xml:"id,attr"`
+	Href            string  `xml:"href,attr"`
+	X               float64 `xml:"x,attr"`
+	Y               float64 `xml:"y,attr"`
+	Width           string  `xml:"width,attr"`
+	Height          string  `xml:"height,attr"`
+	TransformString string  `xml:"transform,attr"`
+	Display         string  `xml:"display,attr"`
+	Visibility      string  `xml:"visibility,attr"`
+
+	group *Group
+	// doc is the document containing the image, which Href is relative
+	// to
+	doc *Svg
+}
+
+// ParseDrawingInstructions implements the DrawingInstructionParser
+// interface
+func (i *Image) ParseDrawingInstructions() (chan *DrawingInstruction, chan error) {
+	draw := make(chan *DrawingInstruction)
+	errs := make(chan error)
+
+	defer close(draw)
+	defer close(errs)
+
+	return draw, errs
+}
+
+// Open opens the image. Images of documents parsed without a
+// ResourceLoader cannot be opened.
+func (i *Image) Open() (io.ReadCloser, error) {
+	if i.doc == nil {
+		return nil, fmt.Errorf("%w: %q", ErrResourceDenied, i.Href)
+	}
+	return i.doc.open(i.Href)
+}
@@ -0,0 +1,317 @@
+package svg
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+)
+
+// KiCadFormat selects the file format written by WriteKiCad.
+type KiCadFormat int
+
+// These are the supported KiCad formats
+const (
+	// KiCadSExpr is the s-expression format of KiCad 6 and later.
+	KiCadSExpr KiCadFormat = iota
+	// KiCadLegacy is the legacy footprint library format (.mod).
+	KiCadLegacy
+)
+
+// KiCadOptions controls how shapes are written by WriteKiCad.
+type KiCadOptions struct {
+	Format KiCadFormat
+	// Footprint is the name of the footprint to write. An empty name
+	// writes a board with graphic items instead, which is only supported
+	// in the s-expression format.
+	Footprint string
+	// Layers maps group IDs and Inkscape layer names to KiCad layers
+	// such as "F.SilkS" or "Edge.Cuts". Group IDs take precedence, and
+	// Inkscape layers named after a KiCad layer need no entry.
+	Layers map[string]string
+	// DefaultLayer is used for shapes without a layer. Empty means
+	// F.SilkS.
+	DefaultLayer string
+	// Scale is the size of a user unit in millimetres. Zero means
+	// 25.4/96, the size of a CSS pixel.
+	Scale float64
+	// ArcTolerance is the distance in millimetres within which stroked
+	// points are fitted with arcs. Zero means 0.01.
+	ArcTolerance float64
+}
+
+// kicadLegacyLayers maps KiCad layer names to legacy layer numbers.
+var kicadLegacyLayers = map[string]int{
+	"B.Cu": 0, "F.Cu": 15, "B.Adhes": 16, "F.Adhes": 17, "B.Paste": 18, "F.Paste": 19,
+	"B.SilkS": 20, "F.SilkS": 21, "B.Mask": 22, "F.Mask": 23, "Dwgs.User": 24,
+	"Cmts.User": 25, "Eco1.User": 26, "Eco2.User": 27, "Edge.Cuts": 28,
+}
+
+// WriteKiCad writes the visible shapes to w as KiCad graphics in
+// millimetres. Filled shapes are merged per layer into polygons whose
+// holes are joined to their outline by zero-width bridges, since KiCad
+// polygons cannot have holes. Strokes become lines and arcs with the
+// stroke width. A nil opts writes an s-expression board on F.SilkS.
+func WriteKiCad(w io.Writer, shapes []Shape, opts *KiCadOptions) error {
+	if opts == nil {
+		opts = &KiCadOptions{}
+	}
+	if opts.Format == KiCadLegacy && opts.Footprint == "" {
+		return fmt.Errorf("the legacy KiCad format needs a footprint name")
+	}
+	k := &kicadWriter{opts: opts, scale: opts.Scale, tolerance: opts.ArcTolerance}
+	if k.scale == 0 {
+		k.scale = 25.4 / userUnitsPerInch
+	}
+	if k.tolerance <= 0 {
+		k.tolerance = 0.01
+	}
+
+	var layers []string
+	seen := make(map[string]bool)
+	filled := make(map[string][]Shape)
+	stroked := make(map[string][]Shape)
+	for _, s := range shapes {
+		if s.Hidden {
+			continue
+		}
+		layer := k.layer(s)
+		if opts.Format == KiCadLegacy {
+			if _, ok := kicadLegacyLayers[layer]; !ok {
+				return fmt.Errorf("layer %q is not supported by the legacy KiCad format", layer)
+			}
+		}
+		if !seen[layer] {
+			seen[layer] = true
+			layers = append(layers, layer)
+		}
+		if s.Filled() {
+			filled[layer] = append(filled[layer], s)
+		}
+		if s.Stroked() {
+			stroked[layer] = append(stroked[layer], s)
+		}
+	}
+
+	k.begin()
+	for _, layer := range layers {
+		for _, p := range polygons(Union(filled[layer], nil)) {
+			k.poly(bridgeHoles(p), layer)
+		}
+		for _, s := range stroked[layer] {
+			// the stroke width is in the user units of the segments
+			width := s.StrokeStyle.Width * k.scale
+			for _, seg := range s.Segments {
+				k.stroke(seg, width, layer)
+			}
+		}
+	}
+	k.end()
+
+	_, err := w.Write(k.buf.Bytes())
+	return err
+}
+
+type kicadWriter struct {
+	buf       bytes.Buffer
+	opts      *KiCadOptions
+	scale     float64
+	tolerance float64
+}
+
+// layer returns the KiCad layer of a shape.
+func (k *kicadWriter) layer(s Shape) string {
+	if l, ok := k.opts.Layers[s.GroupID]; ok && s.GroupID != "" {
+		return l
+	}
+	if l, ok := k.opts.Layers[s.Layer]; ok && s.Layer != "" {
+		return l
+	}
+	if _, ok := kicadLegacyLayers[s.Layer]; ok {
+		return s.Layer
+	}
+	if k.opts.DefaultLayer != "" {
+		return k.opts.DefaultLayer
+	}
+	return "F.SilkS"
+}
+
+func (k *kicadWriter) mm(p [2]float64) [2]float64 {
+	return [2]float64{p[0] * k.scale, p[1] * k.scale}
+}
+
+func (k *kicadWriter) begin() {
+	name := k.opts.Footprint
+	switch {
+	case k.opts.Format == KiCadLegacy:
+		fmt.Fprintf(&k.buf, "PCBNEW-LibModule-V1\n# encoding utf-8\nUnits mm\n$INDEX\n%s\n$EndINDEX\n", name)
+		fmt.Fprintf(&k.buf, "$MODULE %s\nPo 0 0 0 15 00000000 00000000 ~~\nLi %s\n", name, name)
+	case name != "":
+		fmt.Fprintf(&k.buf, "(footprint %s (version 20211014) (generator svg) (layer \"F.Cu\")\n", strconv.Quote(name))
+	default:
+		k.buf.WriteString("(kicad_pcb (version 20211014) (generator svg)\n")
+	}
+}
+
+func (k *kicadWriter) end() {
+	if k.opts.Format == KiCadLegacy {
+		fmt.Fprintf(&k.buf, "$EndMODULE %s\n$EndLIBRARY\n", k.opts.Footprint)
+		return
+	}
+	k.buf.WriteString(")\n")
+}
+
+// prefix returns the item prefix, fp for footprints and gr for boards.
+func (k *kicadWriter) prefix() string {
+	if k.opts.Footprint != "" {
+		return "fp"
+	}
+	return "gr"
+}
+
+func (k *kicadWriter) poly(ring [][2]float64, layer string) {
+	if k.opts.Format == KiCadLegacy {
+		fmt.Fprintf(&k.buf, "DP 0 0 0 0 %d 0 %d\n", len(ring), kicadLegacyLayers[layer])
+		for _, p := range ring {
+			p = k.mm(p)
+			fmt.Fprintf(&k.buf, "Dl %s %s\n", kicadNumber(p[0]), kicadNumber(p[1]))
+		}
+		return
+	}
+	fmt.Fprintf(&k.buf, "  (%s_poly (pts", k.prefix())
+	for _, p := range ring {
+		p = k.mm(p)
+		fmt.Fprintf(&k.buf, " (xy %s %s)", kicadNumber(p[0]), kicadNumber(p[1]))
+	}
+	fmt.Fprintf(&k.buf, ") (layer %s) (width 0) (fill solid))\n", strconv.Quote(layer))
+}
+
+func (k *kicadWriter) stroke(s Segment, width float64, layer string) {
+	if len(s.Points) < 2 {
+		return
+	}
+	pts := make([][2]float64, len(s.Points))
+	for i, p := range s.Points {
+		pts[i] = k.mm(p)
+	}
+
+	from := pts[0]
+	for _, m := range fitArcs(pts, k.tolerance) {
+		if m.arc {
+			k.arc(from, m, width, layer)
+		} else {
+			k.line(from, m.to, width, layer)
+		}
+		from = m.to
+	}
+}
+
+func (k *kicadWriter) line(a, b [2]float64, width float64, layer string) {
+	if k.opts.Format == KiCadLegacy {
+		fmt.Fprintf(&k.buf, "DS %s %s %s %s %s %d\n", kicadNumber(a[0]), kicadNumber(a[1]),
+			kicadNumber(b[0]), kicadNumber(b[1]), kicadNumber(width), kicadLegacyLayers[layer])
+		return
+	}
+	fmt.Fprintf(&k.buf, "  (%s_line (start %s %s) (end %s %s) (layer %s) (width %s))\n", k.prefix(),
+		kicadNumber(a[0]), kicadNumber(a[1]), kicadNumber(b[0]), kicadNumber(b[1]), strconv.Quote(layer), kicadNumber(width))
+}
+
+func (k *kicadWriter) arc(from [2]float64, m gcodeMove, width float64, layer string) {
+	c := m.center
+	a0 := math.Atan2(from[1]-c[1], from[0]-c[0])
+	a1 := math.Atan2(m.to[1]-c[1], m.to[0]-c[0])
+	sweep := math.Mod(a1-a0+4*math.Pi, 2*math.Pi)
+	if !m.ccw {
+		sweep -= 2 * math.Pi
+	}
+
+	if k.opts.Format == KiCadLegacy {
+		// the angle is in tenths of a degree, clockwise on screen
+		fmt.Fprintf(&k.buf, "DA %s %s %s %s %d %s %d\n", kicadNumber(c[0]), kicadNumber(c[1]),
+			kicadNumber(from[0]), kicadNumber(from[1]), int(math.Round(sweep*1800/math.Pi)), kicadNumber(width), kicadLegacyLayers[layer])
+		return
+	}
+	r := math.Hypot(from[0]-c[0], from[1]-c[1])
+	mid := [2]float64{c[0] + r*math.Cos(a0+sweep/2), c[1] + r*math.Sin(a0+sweep/2)}
+	fmt.Fprintf(&k.buf, "  (%s_arc (start %s %s) (mid %s %s) (end %s %s) (layer %s) (width %s))\n", k.prefix(),
+		kicadNumber(from[0]), kicadNumber(from[1]), kicadNumber(mid[0]), kicadNumber(mid[1]),
+		kicadNumber(m.to[0]), kicadNumber(m.to[1]), strconv.Quote(layer), kicadNumber(width))
+}
+
+// bridgeHoles joins the holes of a polygon to its outer boundary with
+// zero-width bridges and returns the resulting ring without the closing
+// point. Each hole is joined at the closest pair of vertices whose
+// bridge does not cross another edge.
+func bridgeHoles(polygon []Segment) [][2]float64 {
+	ring := func(s Segment) [][2]float64 {
+		pts, _ := s.ringPoints()
+		return pts
+	}
+	result := append([][2]float64{}, ring(polygon[0])...)
+	holes := make([][][2]float64, len(polygon)-1)
+	for i, h := range polygon[1:] {
+		holes[i] = ring(h)
+	}
+
+	for hi, hole := range holes {
+		crosses := func(a, b [2]float64) bool {
+			if ringCrosses(result, a, b) {
+				return true
+			}
+			for _, other := range holes[hi:] {
+				if ringCrosses(other, a, b) {
+					return true
+				}
+			}
+			return false
+		}
+
+		bi, bj, best := 0, 0, math.Inf(1)
+		fallbackI, fallbackJ, fallback := 0, 0, math.Inf(1)
+		for i, p := range result {
+			for j, q := range hole {
+				d := math.Hypot(p[0]-q[0], p[1]-q[1])
+				if d < fallback {
+					fallbackI, fallbackJ, fallback = i, j, d
+				}
+				if d < best && !crosses(p, q) {
+					bi, bj, best = i, j, d
+				}
+			}
+		}
+		if math.IsInf(best, 1) {
+			bi, bj = fallbackI, fallbackJ
+		}
+
+		joined := append([][2]float64{}, result[:bi+1]...)
+		joined = append(joined, hole[bj:]...)
+		joined = append(joined, hole[:bj+1]...)
+		joined = append(joined, result[bi:]...)
+		result = joined
+	}
+	return result
+}
+
+// ringCrosses reports whether the segment a-b properly crosses an edge
+// of the closed ring. Edges that merely touch a or b do not count.
+func ringCrosses(ring [][2]float64, a, b [2]float64) bool {
+	n := len(ring)
+	for i := 0; i < n; i++ {
+		p, q := ring[i], ring[(i+1)%n]
+		if p == a || p == b || q == a || q == b {
+			continue
+		}
+		d1, d2 := cross(a, b, p), cross(a, b, q)
+		d3, d4 := cross(p, q, a), cross(p, q, b)
+		if ((d1 > 0 && d2 < 0) || (d1 < 0 && d2 > 0)) && ((d3 > 0 && d4 < 0) || (d3 < 0 && d4 > 0)) {
+			return true
+		}
+	}
+	return false
+}
+
+// kicadNumber formats a length in millimetres with nanometre precision.
+func kicadNumber(v float64) string {
+	return fixedNumber(v, 6)
+}
@@ -0,0 +1,84 @@
+package svg
+
+import (
+	"bytes"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const kicadSvg = `<svg xmlns:inkscape="http://www.inkscape.org/namespaces/inkscape">
+<g id="logo"><path d="M0 0 L10 0 L10 10 L0 10 Z M2 2 L2 8 L8 8 L8 2 Z" fill="black" fill-rule="evenodd"/></g>
+<g inkscape:groupmode="layer" inkscape:label="Edge.Cuts"><path d="M0 20 L10 20" stroke="black" stroke-width="0.5" fill="none"/></g>
+</svg>`
+
+func TestWriteKiCad(t *testing.T) {
+	svg, err := ParseSvg(kicadSvg, "test", 0)
+	require.NoError(t, err)
+	shapes, err := svg.Shapes()
+	require.NoError(t, err)
+	require.Equal(t, "Edge.Cuts", shapes[1].Layer)
+
+	var buf bytes.Buffer
+	require.NoError(t, WriteKiCad(&buf, shapes, &KiCadOptions{
+		Footprint: "logo",
+		Scale:     1,
+		Layers:    map[string]string{"logo": "F.Cu"},
+	}))
+	out := buf.String()
+	require.True(t, strings.HasPrefix(out, `(footprint "logo" (version 20211014)`))
+	require.Contains(t, out, `(fp_line (start 0 20) (end 10 20) (layer "Edge.Cuts") (width 0.5))`)
+
+	poly := regexp.MustCompile(`\(fp_poly \(pts(( \(xy [-\d.]+ [-\d.]+\))+)\) \(layer "F.Cu"\) \(width 0\) \(fill solid\)\)`).FindStringSubmatch(out)
+	require.NotNil(t, poly)
+	// the hole is joined to the outline, which adds two bridge points
+	require.Equal(t, 10, strings.Count(poly[1], "(xy"))
+
+	buf.Reset()
+	require.NoError(t, WriteKiCad(&buf, shapes, &KiCadOptions{Scale: 1}))
+	require.Contains(t, buf.String(), "(kicad_pcb ")
+	require.Contains(t, buf.String(), `(gr_poly (pts`)
+	require.Contains(t, buf.String(), `(layer "F.SilkS")`)
+
+	buf.Reset()
+	require.NoError(t, WriteKiCad(&buf, shapes, &KiCadOptions{Format: KiCadLegacy, Footprint: "logo", Scale: 1}))
+	out = buf.String()
+	require.Contains(t, out, "$MODULE logo\n")
+	require.Contains(t, out, "DP 0 0 0 0 10 0 21\n")
+	require.Contains(t, out, "DS 0 20 10 20 0.5 28\n")
+	require.True(t, strings.HasSuffix(out, "$EndMODULE logo\n$EndLIBRARY\n"))
+
+	require.Error(t, WriteKiCad(&buf, shapes, &KiCadOptions{Format: KiCadLegacy}))
+
+	// unpainted shapes do not repeat the items of their layer
+	svg, err = ParseSvg(`<svg>
+<path d="M0 0 L10 0" fill="none"/>
+<path d="M0 0 L10 0" fill="none"/>
+<path d="M0 0 L10 0" stroke="black" stroke-width="1" fill="none"/>
+</svg>`, "test", 0)
+	require.NoError(t, err)
+	shapes, err = svg.Shapes()
+	require.NoError(t, err)
+	buf.Reset()
+	require.NoError(t, WriteKiCad(&buf, shapes, &KiCadOptions{Footprint: "logo", Scale: 1}))
+	require.Equal(t, 1, strings.Count(buf.String(), "(fp_line "))
+
+	// widths keep their proportion to the geometry of scaled documents
+	svg, err = ParseSvg(kicadSvg, "test", 2)
+	require.NoError(t, err)
+	shapes, err = svg.Shapes()
+	require.NoError(t, err)
+	buf.Reset()
+	require.NoError(t, WriteKiCad(&buf, shapes, &KiCadOptions{Footprint: "logo", Scale: 1}))
+	require.Contains(t, buf.String(), `(fp_line (start 0 20) (end 10 20) (layer "Edge.Cuts") (width 0.5))`)
+}
+
+func TestBridgeHoles(t *testing.T) {
+	outer := Segment{Closed: true, Points: [][2]float64{{0, 0}, {10, 0}, {10, 10}, {0, 10}, {0, 0}}}
+	hole := Segment{Closed: true, Points: [][2]float64{{4, 4}, {4, 6}, {6, 6}, {6, 4}, {4, 4}}}
+	ring := bridgeHoles([]Segment{outer, hole})
+	require.Len(t, ring, 10)
+	require.InDelta(t, 96, Segment{Points: ring}.Area(), 1e-9)
+}
@@ -0,0 +1,192 @@
+package svg
+
+import (
+	"fmt"
+	"io"
+)
+
+// Limits bound the size of a document, so that hostile input cannot
+// exhaust memory or time. Zero fields are unlimited.
+type Limits struct {
+	// MaxBytes is the size of the document in bytes, after compressed
+	// (SVGZ) documents are decompressed.
+	MaxBytes int
+	// MaxDepth is the deepest nesting of groups.
+	MaxDepth int
+	// MaxElements is the number of elements.
+	MaxElements int
+	// MaxPathCommands is the number of commands in the path data of all
+	// paths.
+	MaxPathCommands int
+	// MaxPathData is the length in bytes of the path data of all paths,
+	// which bounds the coordinates that repeat a command.
+	MaxPathData int
+	// MaxUses is the number of times use elements are expanded,
+	// including use elements inside the expanded content.
+	MaxUses int
+	// MaxPoints is the number of points curves are flattened to, in
+	// Shapes and when a document is replayed or rendered.
+	MaxPoints int
+}
+
+// DefaultLimits are limits suitable for untrusted documents.
+var DefaultLimits = Limits{
+	MaxBytes:        64 << 20,
+	MaxDepth:        256,
+	MaxElements:     100000,
+	MaxPathCommands: 1000000,
+	MaxPathData:     16 << 20,
+	MaxUses:         10000,
+	MaxPoints:       10000000,
+}
+
+// validate rejects negative limits, which are neither a bound nor the
+// zero that means unlimited.
+func (l Limits) validate() error {
+	for _, f := range []struct {
+		name string
+		v    int
+	}{
+		{"MaxBytes", l.MaxBytes}, {"MaxDepth", l.MaxDepth}, {"MaxElements", l.MaxElements},
+		{"MaxPathCommands", l.MaxPathCommands}, {"MaxPathData", l.MaxPathData},
+		{"MaxUses", l.MaxUses}, {"MaxPoints", l.MaxPoints},
+	} {
+		if f.v < 0 {
+			return fmt.Errorf("invalid limit %s of %d", f.name, f.v)
+		}
+	}
+	return nil
+}
+
+// LimitError reports that a document exceeds one of its Limits. Limits
+// are enforced in strict and lenient parsing alike.
+type LimitError struct {
+	// Limit is the name of the Limits field, for example "MaxDepth".
+	Limit string
+	Max   int
+}
+
+func (e *LimitError) Error() string {
+	return fmt.Sprintf("document exceeds limit %s of %d", e.Limit, e.Max)
+}
+
+// parseCounts are the sizes that limits are checked against. A
+// document shares them with the external documents it loads.
+type parseCounts struct {
+	bytes, elements, commands, pathData int
+}
+
+// byteLimit is a reader that fails once more than max bytes are read
+// in total into the count n.
+type byteLimit struct {
+	r   io.Reader
+	max int
+	n   *int
+}
+
+func (l *byteLimit) Read(p []byte) (int, error) {
+	if l.max <= 0 {
+		return l.r.Read(p)
+	}
+	if *l.n > l.max {
+		return 0, &LimitError{"MaxBytes", l.max}
+	}
+	// read one byte more than allowed to tell a document of exactly
+	// max bytes from a longer one
+	if rest := l.max - *l.n + 1; len(p) > rest {
+		p = p[:rest]
+	}
+	n, err := l.r.Read(p)
+	*l.n += n
+	if *l.n > l.max {
+		return 0, &LimitError{"MaxBytes", l.max}
+	}
+	return n, err
+}
+
+// checkLimits counts an element at pos and checks it against the
+// document's limits.
+func (s *Svg) checkLimits(pos position) error {
+	if s == nil {
+		return nil
+	}
+	if err := s.countElement(); err != nil {
+		return err
+	}
+	limits := s.options.Limits
+	// the root svg element is the first ancestor
+	if limits.MaxDepth > 0 && len(pos.ancestors)-1 > limits.MaxDepth {
+		return &LimitError{"MaxDepth", limits.MaxDepth}
+	}
+	return nil
+}
+
+// countElement counts an element, parsed or copied by a use element,
+// against MaxElements.
+func (s *Svg) countElement() error {
+	c := s.parseCounts()
+	c.elements++
+	if max := s.options.Limits.MaxElements; max > 0 && c.elements > max {
+		return &LimitError{"MaxElements", max}
+	}
+	return nil
+}
+
+// parseCounts returns the counts of s, which documents decoded without
+// ParseSvgWithOptions get on first use.
+func (s *Svg) parseCounts() *parseCounts {
+	if s.counts == nil {
+		s.counts = &parseCounts{}
+	}
+	return s.counts
+}
+
+// checkPathData counts the commands and the length of the path data d.
+func (s *Svg) checkPathData(d string) error {
+	if s == nil {
+		return nil
+	}
+	limits, c := s.options.Limits, s.parseCounts()
+	for i := 0; i < len(d); i++ {
+		if isCommand(d, i) {
+			c.commands++
+		}
+	}
+	if limits.MaxPathCommands > 0 && c.commands > limits.MaxPathCommands {
+		return &LimitError{"MaxPathCommands", limits.MaxPathCommands}
+	}
+	c.pathData += len(d)
+	if limits.MaxPathData > 0 && c.pathData > limits.MaxPathData {
+		return &LimitError{"MaxPathData", limits.MaxPathData}
+	}
+	return nil
+}
+
+// pointLimit counts the points of flattened shapes.
+type pointLimit struct {
+	max, n int
+}
+
+// pointLimit returns a new count of points against the MaxPoints of s.
+// A nil s is unlimited.
+func (s *Svg) pointLimit() *pointLimit {
+	if s == nil {
+		return nil
+	}
+	return &pointLimit{max: s.options.Limits.MaxPoints}
+}
+
+// count counts n points. A nil limit counts nothing.
+func (l *pointLimit) count(n int) {
+	if l != nil {
+		l.n += n
+	}
+}
+
+// err returns a LimitError once more points are counted than allowed.
+func (l *pointLimit) err() error {
+	if l != nil && l.max > 0 && l.n > l.max {
+		return &LimitError{"MaxPoints", l.max}
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+package svg
+
+import (
+	"errors"
+	"image"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHostileInput(t *testing.T) {
+	limits := Limits{MaxDepth: 100, MaxElements: 1000, MaxPathCommands: 1000, MaxPathData: 10000, MaxUses: 1000, MaxPoints: 100000}
+	tests := map[string]string{
+		"commands.svg": "MaxPathCommands",
+		"cycle.svg":    "",
+		"depth.svg":    "MaxDepth",
+		"elements.svg": "MaxElements",
+		"pathdata.svg": "MaxPathData",
+		"points.svg":   "MaxPoints",
+		// the copies count as elements
+		"uses.svg": "MaxElements",
+	}
+
+	files, err := filepath.Glob(filepath.Join("testdata", "hostile", "*.svg"))
+	require.NoError(t, err)
+	require.Len(t, files, len(tests))
+
+	for _, file := range files {
+		name := filepath.Base(file)
+		expected, ok := tests[name]
+		require.True(t, ok, name)
+
+		f, err := os.Open(file)
+		require.NoError(t, err)
+		svg, err := ParseSvgWithOptions(f, NewOptions(WithName(name), WithLimits(limits), WithFlattenTolerance(1e-9)))
+		f.Close()
+		if err == nil {
+			_, err = svg.Shapes()
+		}
+		if err == nil {
+			err = Render(svg, image.NewRGBA(image.Rect(0, 0, 10, 10)), nil)
+		}
+
+		if expected == "" {
+			require.NoError(t, err, name)
+			continue
+		}
+		var le *LimitError
+		require.True(t, errors.As(err, &le), "%s: %v", name, err)
+		require.Equal(t, expected, le.Limit, name)
+	}
+
+	f, err := os.Open(filepath.Join("testdata", "hostile", "uses.svg"))
+	require.NoError(t, err)
+	defer f.Close()
+	_, err = ParseSvgWithOptions(f, NewOptions(WithLimits(Limits{MaxUses: 1000})))
+	var le *LimitError
+	require.True(t, errors.As(err, &le))
+	require.Equal(t, "MaxUses", le.Limit)
+}
+
+func TestUse(t *testing.T) {
+	doc := `<svg xmlns:xlink="http://www.w3.org/1999/xlink">
+<g id="shape" transform="translate(1 0)"><path id="p" d="M0 0 L1 0"/></g>
+<use xlink:href="#shape" x="10" y="20"/>
+<g transform="scale(2)"><use href="#p" transform="translate(0 1)"/></g>
+<use href="#missing"/>
+</svg>`
+
+	svg, err := ParseSvg(doc, "test", 0)
+	require.NoError(t, err)
+	shapes, err := svg.Shapes()
+	require.NoError(t, err)
+	require.Len(t, shapes, 3)
+	require.Equal(t, [][2]float64{{1, 0}, {2, 0}}, shapes[0].Segments[0].Points)
+	require.Equal(t, [][2]float64{{11, 20}, {12, 20}}, shapes[1].Segments[0].Points)
+	require.Equal(t, "shape", shapes[1].GroupID)
+	require.Equal(t, [][2]float64{{0, 2}, {2, 2}}, shapes[2].Segments[0].Points)
+	require.Len(t, svg.Warnings, 1)
+	require.Equal(t, "use", svg.Warnings[0].Tag)
+}
+
+func TestPointLimitAllocation(t *testing.T) {
+	// a single curve or circle flattens to tens of thousands of points
+	// at this tolerance, which must not be made before the limit stops
+	// them
+	for _, doc := range []string{
+		`<svg><path d="M0 0 C0 1000000 1000000 1000000 1000000 0"/></svg>`,
+		`<svg><circle r="1000000000"/></svg>`,
+	} {
+		svg, err := ParseSvgWithOptions(strings.NewReader(doc), NewOptions(WithLimits(Limits{MaxPoints: 100}), WithFlattenTolerance(1e-9)))
+		require.NoError(t, err)
+
+		var before, after runtime.MemStats
+		runtime.ReadMemStats(&before)
+		_, err = svg.Shapes()
+		runtime.ReadMemStats(&after)
+		var le *LimitError
+		require.True(t, errors.As(err, &le), doc)
+		require.Less(t, after.TotalAlloc-before.TotalAlloc, uint64(64<<10), doc)
+	}
+}
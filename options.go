@@ -0,0 +1,204 @@
+package svg
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+
+	mt "github.com/rustyoz/Mtransform"
+)
+
+// Options configures ParseSvgWithOptions. They can be set directly or
+// with NewOptions and the With functions. The zero value parses the
+// document in user units without scaling.
+type Options struct {
+	ParseOptions
+
+	// Name names the document.
+	Name string
+	// Scale multiplies all coordinates. Zero means 1.
+	Scale float64
+	// Units are the units the document is converted to: "px" (user
+	// units, the default), "pt", "pc", "mm", "cm" or "in". The
+	// conversion multiplies Scale.
+	Units string
+	// DPI is the resolution used to convert between absolute units and
+	// user units, both in the document's width and height and for
+	// Units. Zero means the CSS resolution of 96.
+	DPI float64
+	// FlattenTolerance sets the FlattenTolerance of the document.
+	FlattenTolerance float64
+	// Loader loads the resources the document refers to. Nil does not
+	// load any.
+	Loader ResourceLoader
+	// Limits bound the size of the document.
+	Limits Limits
+	// Defaults are the presentation attributes of the root element.
+	Defaults StyleDefaults
+	// Fonts, if set, convert text to glyph outlines, which are drawn
+	// and exported like paths.
+	Fonts *Fonts
+}
+
+// Option sets a field of Options.
+type Option func(*Options)
+
+// NewOptions returns Options with the given options applied in order.
+func NewOptions(opts ...Option) Options {
+	var o Options
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// WithName sets the name of the document.
+func WithName(name string) Option {
+	return func(o *Options) { o.Name = name }
+}
+
+// WithScale multiplies all coordinates by scale.
+func WithScale(scale float64) Option {
+	return func(o *Options) { o.Scale = scale }
+}
+
+// WithUnits converts the document to units.
+func WithUnits(units string) Option {
+	return func(o *Options) { o.Units = units }
+}
+
+// WithDPI sets the resolution used to convert absolute units.
+func WithDPI(dpi float64) Option {
+	return func(o *Options) { o.DPI = dpi }
+}
+
+// WithFlattenTolerance sets the flattening tolerance of the document.
+func WithFlattenTolerance(tolerance float64) Option {
+	return func(o *Options) { o.FlattenTolerance = tolerance }
+}
+
+// WithStrict makes every problem in the document an error.
+func WithStrict(strict bool) Option {
+	return func(o *Options) { o.Strict = strict }
+}
+
+// WithLoader sets the loader of external resources.
+func WithLoader(loader ResourceLoader) Option {
+	return func(o *Options) { o.Loader = loader }
+}
+
+// WithLimits bounds the size of the document.
+func WithLimits(limits Limits) Option {
+	return func(o *Options) { o.Limits = limits }
+}
+
+// WithDefaults sets the presentation attributes of the root element.
+func WithDefaults(defaults StyleDefaults) Option {
+	return func(o *Options) { o.Defaults = defaults }
+}
+
+// WithFonts converts text to outlines with fonts.
+func WithFonts(fonts *Fonts) Option {
+	return func(o *Options) { o.Fonts = fonts }
+}
+
+// ResourceLoader loads the external resources a document refers to,
+// such as images and other documents. Style sheets imported with
+// @import are not loaded but reported with ErrImportUnsupported.
+type ResourceLoader interface {
+	// Open opens the resource at href, which is relative to the
+	// document.
+	Open(href string) (io.ReadCloser, error)
+}
+
+// StyleDefaults are presentation attributes that apply to the elements
+// that neither set nor inherit them. Empty fields keep the SVG defaults.
+// Circles only take the fill, and rects, which the parser does not draw
+// yet, none.
+type StyleDefaults struct {
+	Fill        string
+	FillRule    string
+	Stroke      string
+	StrokeWidth float64
+}
+
+// ParseSvgWithOptions parses an SVG document from r. Gzip compressed
+// (SVGZ) documents are decompressed.
+func ParseSvgWithOptions(r io.Reader, opts Options) (*Svg, error) {
+	if opts.Scale < 0 {
+		return nil, fmt.Errorf("invalid scale %g", opts.Scale)
+	}
+	if opts.DPI < 0 {
+		return nil, fmt.Errorf("invalid DPI %g", opts.DPI)
+	}
+	if opts.DPI == 0 {
+		opts.DPI = userUnitsPerInch
+	}
+	if opts.FlattenTolerance < 0 {
+		return nil, fmt.Errorf("invalid flatten tolerance %g", opts.FlattenTolerance)
+	}
+	if err := opts.Limits.validate(); err != nil {
+		return nil, err
+	}
+	scale := opts.Scale
+	if scale == 0 {
+		scale = 1
+	}
+	if opts.Units != "" {
+		factor, ok := unitFactor(opts.Units, opts.DPI)
+		if !ok {
+			return nil, fmt.Errorf("unsupported units %q", opts.Units)
+		}
+		scale /= factor
+	}
+
+	svg, err := decodeSvg(r, opts, scale, "", &parseCounts{})
+	if err != nil {
+		return nil, err
+	}
+	if err := svg.resolveUses(); err != nil {
+		return nil, err
+	}
+	return svg, nil
+}
+
+// decodeSvg decodes a document from r without resolving its use
+// elements. Href is the path of an external document, whose size is
+// added to the counts of the document that loads it.
+func decodeSvg(r io.Reader, opts Options, scale float64, href string, counts *parseCounts) (*Svg, error) {
+	svg := &Svg{Name: opts.Name, FlattenTolerance: opts.FlattenTolerance, options: opts, scale: scale, href: href, counts: counts}
+	svg.Transform = mt.NewTransform()
+	if scale != 1 {
+		svg.Transform.Scale(scale, scale)
+	}
+
+	r, err := decompress(r)
+	if err != nil {
+		return nil, parseSvgError(err)
+	}
+	svg.source = newLineIndex(&byteLimit{r: r, max: opts.Limits.MaxBytes, n: &counts.bytes})
+	if err := xml.NewDecoder(svg.source).Decode(svg); err != nil {
+		return nil, parseSvgError(err)
+	}
+	svg.source = nil
+
+	for i := range svg.Groups {
+		svg.Groups[i].SetOwner(svg)
+		if svg.Groups[i].Transform == nil {
+			svg.Groups[i].Transform = mt.NewTransform()
+		}
+	}
+	if err := svg.resolveGradients(); err != nil {
+		return nil, err
+	}
+	return svg, nil
+}
+
+// legacyOptions converts the arguments of ParseSvg, where a negative
+// scale divides, to Options.
+func legacyOptions(name string, scale float64) Options {
+	if scale < 0 {
+		scale = 1 / -scale
+	}
+	return Options{Name: name, Scale: scale}
+}
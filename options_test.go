@@ -0,0 +1,72 @@
+package svg
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSvgWithOptions(t *testing.T) {
+	doc := `<svg width="1in" height="2in"><g><g><path d="M0 0 L1 0"/></g></g><path d="M0 0 L1 1"/></svg>`
+
+	svg, err := ParseSvgWithOptions(strings.NewReader(doc), NewOptions(
+		WithName("test"),
+		WithUnits("mm"),
+		WithDPI(72),
+		WithFlattenTolerance(0.5),
+		WithDefaults(StyleDefaults{Stroke: "blue", StrokeWidth: 3}),
+	))
+	require.NoError(t, err)
+	require.Equal(t, "test", svg.Name)
+	require.Equal(t, 0.5, svg.FlattenTolerance)
+
+	w, h, err := svg.Size()
+	require.NoError(t, err)
+	require.InDelta(t, 25.4, w, 1e-9)
+	require.InDelta(t, 50.8, h, 1e-9)
+
+	shapes, err := svg.Shapes()
+	require.NoError(t, err)
+	require.Len(t, shapes, 2)
+	// stroke widths are in the user units of the segments
+	for _, s := range shapes {
+		require.Equal(t, "blue", s.Stroke)
+		require.InDelta(t, 3, s.StrokeStyle.Width, 1e-9)
+	}
+
+	// circles take the default fill unless they set their own
+	svg, err = ParseSvgWithOptions(strings.NewReader(`<svg><circle r="1"/><circle r="1" fill="red"/><g><circle r="1"/></g></svg>`),
+		NewOptions(WithDefaults(StyleDefaults{Fill: "green"})))
+	require.NoError(t, err)
+	shapes, err = svg.Shapes()
+	require.NoError(t, err)
+	require.Len(t, shapes, 3)
+	require.Equal(t, "green", shapes[0].Fill)
+	require.Equal(t, "red", shapes[1].Fill)
+	require.Equal(t, "green", shapes[2].Fill)
+
+	legacy, err := ParseSvg(doc, "test", -2)
+	require.NoError(t, err)
+	w, _, err = legacy.Size()
+	require.NoError(t, err)
+	require.Equal(t, 48.0, w)
+	scaled, err := ParseSvg(doc, "test", 2)
+	require.NoError(t, err)
+	w, _, err = scaled.Size()
+	require.NoError(t, err)
+	require.Equal(t, 192.0, w)
+
+	_, err = ParseSvgWithOptions(strings.NewReader(doc), NewOptions(WithUnits("furlong")))
+	require.Error(t, err)
+	_, err = ParseSvgWithOptions(strings.NewReader(doc), NewOptions(WithFlattenTolerance(-1)))
+	require.Error(t, err)
+	_, err = ParseSvgWithOptions(strings.NewReader(doc), NewOptions(WithLimits(Limits{MaxPoints: -1})))
+	require.Error(t, err)
+	_, err = ParseSvgWithOptions(strings.NewReader(doc), NewOptions(WithLimits(Limits{MaxDepth: 1})))
+	require.Error(t, err)
+	_, err = ParseSvgWithOptions(strings.NewReader(doc), NewOptions(WithLimits(Limits{MaxElements: 3})))
+	require.Error(t, err)
+	_, err = ParseSvgWithOptions(strings.NewReader(doc), NewOptions(WithLimits(Limits{MaxDepth: 2, MaxElements: 4})))
+	require.NoError(t, err)
+}
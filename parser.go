@@ -1,7 +1,9 @@
 package svg
 
 import (
+	"errors"
 	"fmt"
+	"math"
 	"strconv"
 
 	mt "github.com/rustyoz/Mtransform"
@@ -14,12 +16,27 @@ func parseNumber(i gl.Item) (float64, error) {
 	if i.Type == gl.ItemNumber {
 		n, ok = strconv.ParseFloat(i.Value, 64)
 		if ok != nil {
-			return n, fmt.Errorf("Error passing number %s", ok)
+			return n, fmt.Errorf("invalid number: %w", ok)
 		}
 	}
 	return n, nil
 }
 
+// drainLexer reads the remaining items of l, so that its goroutine
+// ends.
+func drainLexer(l *gl.Lexer) {
+	for range l.Items {
+	}
+}
+
+// unexpectedItem is the error for an item found where a number was expected.
+func unexpectedItem(i gl.Item) error {
+	if i.Type == gl.ItemEOS {
+		return errors.New("expected number, got end of data")
+	}
+	return fmt.Errorf("expected number, got %q", i.Value)
+}
+
 func parseTuple(l *gl.Lexer) (Tuple, error) {
 	t := Tuple{}
 
@@ -29,11 +46,11 @@ func parseTuple(l *gl.Lexer) (Tuple, error) {
 	if ni.Type == gl.ItemNumber {
 		n, ok := strconv.ParseFloat(ni.Value, 64)
 		if ok != nil {
-			return t, fmt.Errorf("Error parsing number %s", ok)
+			return t, fmt.Errorf("invalid number: %w", ok)
 		}
 		t[0] = n
 	} else {
-		return t, fmt.Errorf("Error parsing Tuple expected Number got: %s", ni.Value)
+		return t, unexpectedItem(ni)
 	}
 
 	if l.PeekItem().Type == gl.ItemWSP || l.PeekItem().Type == gl.ItemComma {
@@ -43,33 +60,61 @@ func parseTuple(l *gl.Lexer) (Tuple, error) {
 	if ni.Type == gl.ItemNumber {
 		n, ok := strconv.ParseFloat(ni.Value, 64)
 		if ok != nil {
-			return t, fmt.Errorf("Error passing Number %s", ok)
+			return t, fmt.Errorf("invalid number: %w", ok)
 		}
 		t[1] = n
 	} else {
-		return t, fmt.Errorf("Error passing Tuple expected Number got: %v", ni)
+		return t, unexpectedItem(ni)
 	}
 
 	return t, nil
 }
 
+// parseTransform parses a transform attribute: a list of matrix,
+// translate, scale, rotate, skewX and skewY functions that are applied
+// from right to left.
 func parseTransform(tstring string) (mt.Transform, error) {
 	lexer, _ := gl.Lex("tlexer", tstring)
+	defer drainLexer(lexer)
+	result := mt.Identity()
+	found := false
 	for {
 		i := lexer.NextItem()
 		switch i.Type {
 		case gl.ItemEOS:
+			if !found {
+				return mt.Identity(),
+					fmt.Errorf("transform parse failed")
+			}
+			return result, nil
+		case gl.ItemError:
 			return mt.Identity(),
 				fmt.Errorf("transform parse failed")
 		case gl.ItemWord:
+			var (
+				t   mt.Transform
+				err error
+			)
 			switch i.Value {
 			case "matrix":
-				return parseMatrix(lexer)
-				// case "scale":
-				// case "rotate":
+				t, err = parseMatrix(lexer)
 			case "translate":
-				return parseTranslate(lexer)
+				t, err = parseTranslate(lexer)
+			case "scale":
+				t, err = parseScale(lexer)
+			case "rotate":
+				t, err = parseRotate(lexer)
+			case "skewX", "skewY":
+				t, err = parseSkew(lexer, i.Value == "skewX")
+			default:
+				return mt.Identity(),
+					fmt.Errorf("unknown transform function %q", i.Value)
+			}
+			if err != nil {
+				return mt.Identity(), err
 			}
+			result = mt.MultiplyTransforms(result, t)
+			found = true
 		}
 	}
 }
@@ -95,44 +140,96 @@ func parseMatrix(l *gl.Lexer) (mt.Transform, error) {
 }
 
 func parseTranslate(l *gl.Lexer) (mt.Transform, error) {
-	nums, err := parseParenNumList(l, 2)
+	nums, err := parseParenNumRange(l, 1, 2)
 	if err != nil {
 		return mt.Identity(), fmt.Errorf("Error Parsing Translate: %v", err)
 	}
 	tm := mt.Identity()
 	tm[0][2] = nums[0]
-	tm[1][2] = nums[1]
+	if len(nums) > 1 {
+		tm[1][2] = nums[1]
+	}
+	return tm, nil
+}
+
+func parseScale(l *gl.Lexer) (mt.Transform, error) {
+	nums, err := parseParenNumRange(l, 1, 2)
+	if err != nil {
+		return mt.Identity(), fmt.Errorf("Error Parsing Scale: %v", err)
+	}
+	tm := mt.Identity()
+	tm[0][0] = nums[0]
+	tm[1][1] = nums[0]
+	if len(nums) > 1 {
+		tm[1][1] = nums[1]
+	}
+	return tm, nil
+}
+
+func parseRotate(l *gl.Lexer) (mt.Transform, error) {
+	nums, err := parseParenNumRange(l, 1, 3)
+	if err != nil {
+		return mt.Identity(), fmt.Errorf("Error Parsing Rotate: %v", err)
+	}
+	if len(nums) == 2 {
+		return mt.Identity(), fmt.Errorf("Error Parsing Rotate: expected 1 or 3 numbers")
+	}
+	tm := mt.Identity()
+	if len(nums) == 3 {
+		tm.Translate(nums[1], nums[2])
+	}
+	tm.RotateOrigin(nums[0] * math.Pi / 180)
+	if len(nums) == 3 {
+		tm.Translate(-nums[1], -nums[2])
+	}
+	return tm, nil
+}
+
+func parseSkew(l *gl.Lexer, x bool) (mt.Transform, error) {
+	nums, err := parseParenNumList(l, 1)
+	if err != nil {
+		return mt.Identity(), fmt.Errorf("Error Parsing Skew: %v", err)
+	}
+	tm := mt.Identity()
+	if x {
+		tm.SkewX(nums[0] * math.Pi / 180)
+	} else {
+		tm.SkewY(nums[0] * math.Pi / 180)
+	}
 	return tm, nil
 }
 
 // Parse a parenthesized list of ncount numbers.
 func parseParenNumList(l *gl.Lexer, ncount int) ([]float64, error) {
+	return parseParenNumRange(l, ncount, ncount)
+}
+
+// Parse a parenthesized list of between min and max numbers.
+func parseParenNumRange(l *gl.Lexer, min, max int) ([]float64, error) {
+	l.ConsumeWhiteSpace()
 	i := l.NextItem()
 	if i.Type != gl.ItemParan {
 		return nil, fmt.Errorf("Expected Opening Parantheses")
 	}
 	var nums []float64
 	for {
-		if len(nums) > 0 {
-			for l.PeekItem().Type == gl.ItemComma || l.PeekItem().Type == gl.ItemWSP {
-				l.NextItem()
-			}
+		for l.PeekItem().Type == gl.ItemComma || l.PeekItem().Type == gl.ItemWSP {
+			l.NextItem()
+		}
+		if len(nums) >= min && l.PeekItem().Type == gl.ItemParan {
+			l.NextItem() // consume Parantheses
+			return nums, nil
+		}
+		if len(nums) >= max {
+			return nil, fmt.Errorf("Expected Closing Parantheses")
 		}
 		if l.PeekItem().Type != gl.ItemNumber {
-			return nil, fmt.Errorf("Expected Number got %v", l.PeekItem().String())
+			return nil, unexpectedItem(l.PeekItem())
 		}
 		n, err := parseNumber(l.NextItem())
 		if err != nil {
 			return nil, err
 		}
 		nums = append(nums, n)
-		if len(nums) >= ncount {
-			i = l.PeekItem()
-			if i.Type != gl.ItemParan {
-				return nil, fmt.Errorf("Expected Closing Parantheses")
-			}
-			l.NextItem() // consume Parantheses
-			return nums, nil
-		}
 	}
 }
@@ -5,6 +5,7 @@ import (
 	"testing"
 
 	"github.com/cheekybits/is"
+	"github.com/stretchr/testify/require"
 )
 
 const testSvg = `<?xml version="1.0" encoding="utf-8"?>
@@ -29,3 +30,44 @@ func TestParse(t *testing.T) {
 	is.NoErr(err)
 	is.NotNil(svg)
 }
+
+func TestParseTransform(t *testing.T) {
+	tests := []struct {
+		Transform string
+		X, Y      float64
+	}{
+		{"translate(10 20)", 11, 21},
+		{"translate(10)", 11, 1},
+		{"scale(2)", 2, 2},
+		{"scale(2, 3)", 2, 3},
+		{"rotate(90)", -1, 1},
+		{"rotate(90 1 0)", 0, 0},
+		{"translate(10,0) scale(2)", 12, 2},
+		{"matrix(1 0 0 1 5 5)", 6, 6},
+	}
+
+	for _, test := range tests {
+		tr, err := parseTransform(test.Transform)
+		require.NoError(t, err, test.Transform)
+		x, y := tr.Apply(1, 1)
+		require.InDelta(t, test.X, x, 1e-9, test.Transform)
+		require.InDelta(t, test.Y, y, 1e-9, test.Transform)
+	}
+
+	_, err := parseTransform("bogus(1)")
+	require.Error(t, err)
+}
+
+func TestParseScale(t *testing.T) {
+	doc := `<svg><path d="M0 0 L10 0" stroke="black" stroke-width="4"/></svg>`
+	// zero leaves the document as it is, a negative scale divides
+	for scale, width := range map[float64]float64{0: 4, 1: 4, 2: 8, -2: 2} {
+		svg, err := ParseSvg(doc, "test", scale)
+		require.NoError(t, err)
+		instrs, err := collectInstructions(svg.Elements[0])
+		require.NoError(t, err)
+		paint := instrs[len(instrs)-1]
+		require.Equal(t, PaintInstruction, paint.Kind)
+		require.Equal(t, width, *paint.StrokeWidth, "scale %g", scale)
+	}
+}
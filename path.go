@@ -1,8 +1,10 @@
 package svg
 
 import (
+	"errors"
 	"fmt"
 	"strconv"
+	"unicode/utf8"
 
 	mt "github.com/rustyoz/Mtransform"
 	gl "github.com/rustyoz/genericlexer"
@@ -10,20 +12,30 @@ import (
 
 // Path is an SVG XML path element
 type Path struct {
-	ID              string `xml:"id,attr"`
-	D               string `xml:"d,attr"`
-	Style           string `xml:"style,attr"`
-	TransformString string `xml:"transform,attr"`
-	properties      map[string]string
-	StrokeWidth     float64 `xml:"stroke-width,attr"`
-	Fill            *string `xml:"fill,attr"`
-	Stroke          *string `xml:"stroke,attr"`
-	StrokeLineCap   *string `xml:"stroke-linecap,attr"`
-	StrokeLineJoin  *string `xml:"stroke-linejoin,attr"`
-	Segments        chan Segment
-	instructions    chan *DrawingInstruction
-	errors          chan error
-	group           *Group
+	ID               string `xml:"id,attr"`
+	D                string `xml:"d,attr"`
+	Style            string `xml:"style,attr"`
+	TransformString  string `xml:"transform,attr"`
+	Display          string `xml:"display,attr"`
+	Visibility       string `xml:"visibility,attr"`
+	properties       map[string]string
+	StrokeWidth      float64  `xml:"stroke-width,attr"`
+	Fill             *string  `xml:"fill,attr"`
+	FillRule         *string  `xml:"fill-rule,attr"`
+	Stroke           *string  `xml:"stroke,attr"`
+	StrokeLineCap    *string  `xml:"stroke-linecap,attr"`
+	StrokeLineJoin   *string  `xml:"stroke-linejoin,attr"`
+	StrokeMiterLimit float64  `xml:"stroke-miterlimit,attr"`
+	StrokeDashArray  *string  `xml:"stroke-dasharray,attr"`
+	StrokeDashOffset *float64 `xml:"stroke-dashoffset,attr"`
+	Opacity          *float64 `xml:"opacity,attr"`
+	FillOpacity      *float64 `xml:"fill-opacity,attr"`
+	StrokeOpacity    *float64 `xml:"stroke-opacity,attr"`
+	Segments         chan Segment
+	instructions     chan *DrawingInstruction
+	errors           chan error
+	group            *Group
+	pos              position
 }
 
 // A Segment of a path that contains a list of connected points, its
@@ -35,20 +47,13 @@ type Segment struct {
 	Points [][2]float64
 }
 
-func (p Path) newSegment(start [2]float64) *Segment {
-	var s Segment
-	s.Width = p.StrokeWidth * p.group.Owner.scale
-	s.Points = append(s.Points, start)
-	return &s
-}
-
 func (s *Segment) addPoint(p [2]float64) {
 	s.Points = append(s.Points, p)
 }
 
 type pathDescriptionParser struct {
 	p              *Path
-	lex            gl.Lexer
+	lex            *gl.Lexer
 	x, y           float64
 	currentcommand int
 	tokbuf         [4]gl.Item
@@ -56,7 +61,7 @@ type pathDescriptionParser struct {
 	lasttuple      Tuple
 	transform      mt.Transform
 	svg            *Svg
-	currentsegment *Segment
+	started        bool
 }
 
 func newPathDParse() *pathDescriptionParser {
@@ -66,45 +71,16 @@ func newPathDParse() *pathDescriptionParser {
 }
 
 // Parse interprets path description, transform and style atttributes to
-// create a channel of segments.
+// create a channel of segments. The segments are the flattened drawing
+// instructions of the path, so problems in the path data end them early;
+// ParseDrawingInstructions reports those problems.
 func (p *Path) Parse() chan Segment {
-	p.parseStyle()
-	pdp := newPathDParse()
-	pdp.p = p
-	if p.group == nil {
-		p.group = new(Group)
-		temp := mt.Identity()
-		p.group.Transform = &temp
-	}
-	pdp.svg = p.group.Owner
-	pathTransform := mt.Identity()
-	if p.TransformString != "" {
-		pt, err := parseTransform(p.TransformString)
-		if err == nil {
-			pathTransform = pt
-		}
-	}
-	pdp.transform = mt.MultiplyTransforms(pdp.transform, *p.group.Transform)
-	pdp.transform = mt.MultiplyTransforms(pdp.transform, pathTransform)
 	p.Segments = make(chan Segment)
-	l, _ := gl.Lex(fmt.Sprint(p.ID), p.D)
-	pdp.lex = *l
 	go func() {
 		defer close(p.Segments)
-		for {
-			i := pdp.lex.NextItem()
-			switch {
-			case i.Type == gl.ItemError:
-				return
-			case i.Type == gl.ItemEOS:
-				if pdp.currentsegment != nil {
-					p.Segments <- *pdp.currentsegment
-				}
-				return
-			case i.Type == gl.ItemLetter:
-				pdp.parseCommand(l, i)
-			default:
-			}
+		instrs, _ := collectInstructions(p)
+		for _, s := range flattenInstructions(instrs, p.flattenTolerance()) {
+			p.Segments <- s
 		}
 	}()
 	return p.Segments
@@ -124,52 +100,115 @@ func (p *Path) ParseDrawingInstructions() (chan *DrawingInstruction, chan error)
 		p.group.Transform = &temp
 	}
 	pdp.svg = p.group.Owner
+	p.instructions = make(chan *DrawingInstruction, 100)
+	p.errors = make(chan error, 100)
+
 	pathTransform := mt.Identity()
 	if p.TransformString != "" {
 		pt, err := parseTransform(p.TransformString)
 		if err == nil {
 			pathTransform = pt
+		} else if err = p.warn(-1, fmt.Errorf("invalid transform %q: %w", p.TransformString, err)); err != nil {
+			p.errors <- err
+			close(p.instructions)
+			close(p.errors)
+			return p.instructions, p.errors
 		}
 	}
 	pdp.transform = mt.MultiplyTransforms(pdp.transform, *p.group.Transform)
 	pdp.transform = mt.MultiplyTransforms(pdp.transform, pathTransform)
 
-	p.instructions = make(chan *DrawingInstruction, 100)
-	p.errors = make(chan error, 100)
 	l, _ := gl.Lex(fmt.Sprint(p.ID), p.D)
-
-	pdp.lex = *l
+	pdp.lex = l
 	go func() {
 		defer close(p.instructions)
 		defer close(p.errors)
-		var count int
+		defer drainLexer(l)
+
+		// paint is sent at the end of the path data and, when parsing
+		// leniently, after the last command before an error
+		paint := func() {
+			scale := 1.0
+			if pdp.p.group.Owner != nil {
+				scale = pdp.p.group.Owner.scale
+			}
+			scaledStrokeWidth := p.StrokeWidth * scale
+
+			var miterLimit *float64
+			if p.StrokeMiterLimit != 0 {
+				miterLimit = &p.StrokeMiterLimit
+			}
+
+			var dashes []float64
+			var dashOffset *float64
+			if p.StrokeDashArray != nil {
+				for _, d := range ParseDashArray(*p.StrokeDashArray) {
+					dashes = append(dashes, d*scale)
+				}
+			}
+			if p.StrokeDashOffset != nil {
+				o := *p.StrokeDashOffset * scale
+				dashOffset = &o
+			}
+
+			pdp.p.instructions <- &DrawingInstruction{
+				Kind:             PaintInstruction,
+				StrokeWidth:      &scaledStrokeWidth,
+				Stroke:           p.Stroke,
+				StrokeLineCap:    p.StrokeLineCap,
+				StrokeLineJoin:   p.StrokeLineJoin,
+				StrokeMiterLimit: miterLimit,
+				StrokeDashArray:  dashes,
+				StrokeDashOffset: dashOffset,
+				Fill:             p.Fill,
+				FillRule:         p.FillRule,
+				Opacity:          p.Opacity,
+				FillOpacity:      p.FillOpacity,
+				StrokeOpacity:    p.StrokeOpacity,
+				FillGradient:     pdp.svg.paintGradient(p.Fill, pdp.transform),
+				StrokeGradient:   pdp.svg.paintGradient(p.Stroke, pdp.transform),
+			}
+		}
+		fail := func(offset int, err error) {
+			if err = p.warn(offset, err); err != nil {
+				p.errors <- err
+				return
+			}
+			paint()
+		}
+
+		var commands int
 		for {
 			i := pdp.lex.NextItem()
-			count++
 			switch {
 			case i.Type == gl.ItemError:
+				fail(commandOffset(p.D, commands), errors.New(i.Value))
 				return
 			case i.Type == gl.ItemEOS:
-				scaledStrokeWidth := p.StrokeWidth * pdp.p.group.Owner.scale
-
-				pdp.p.instructions <- &DrawingInstruction{
-					Kind:           PaintInstruction,
-					StrokeWidth:    &scaledStrokeWidth,
-					Stroke:         p.Stroke,
-					StrokeLineCap:  p.StrokeLineCap,
-					StrokeLineJoin: p.StrokeLineJoin,
-					Fill:           p.Fill,
+				// the lexer stops at the first character that cannot
+				// start a token
+				if n := lexedLength(p.D); n < len(p.D) {
+					r, _ := utf8.DecodeRuneInString(p.D[n:])
+					fail(n, fmt.Errorf("unexpected character %q", r))
+					return
 				}
+				paint()
 				return
 			case i.Type == gl.ItemLetter:
 				err := pdp.parseCommandDrawingInstructions(l, i)
 				if err != nil {
-					p.errors <- fmt.Errorf("error when parsing instruction number %d: %s", count, err)
+					fail(commandOffset(p.D, commands), err)
 					return
 				}
-
+				commands++
+			case i.Type == gl.ItemWSP || i.Type == gl.ItemComma:
 			default:
-				fmt.Printf("Default invoked: %d item %v\n", count, i)
+				offset := commandOffset(p.D, commands-1)
+				if offset < 0 {
+					offset = 0
+				}
+				fail(offset, fmt.Errorf("unexpected %q", i.Value))
+				return
 			}
 		}
 	}()
@@ -177,37 +216,11 @@ func (p *Path) ParseDrawingInstructions() (chan *DrawingInstruction, chan error)
 	return p.instructions, p.errors
 }
 
-func (pdp *pathDescriptionParser) parseCommand(l *gl.Lexer, i gl.Item) error {
-
-	switch i.Value {
-	case "M":
-		return pdp.parseMoveToAbs()
-	case "m":
-		return pdp.parseMoveToRel()
-	case "c":
-		return pdp.parseCurveToRel()
-	case "C":
-		return pdp.parseCurveToAbs()
-	case "L":
-		return pdp.parseLineToAbs()
-	case "l":
-		return pdp.parseLineToRel()
-	case "H":
-		return pdp.parseHLineToAbs()
-	case "h":
-		return pdp.parseHLineToRel()
-	case "V":
-		return pdp.parseVLineToAbs()
-	case "v":
-		return pdp.parseVLineToRel()
-	case "z", "Z":
-		return pdp.parseClose()
-	}
-
-	return fmt.Errorf("unknown command found in SVG: %s", i.Value)
-}
-
 func (pdp *pathDescriptionParser) parseCommandDrawingInstructions(l *gl.Lexer, i gl.Item) error {
+	if !pdp.started && i.Value != "M" && i.Value != "m" {
+		return errors.New("path data must begin with a moveto")
+	}
+	pdp.started = true
 
 	switch i.Value {
 	case "M":
@@ -234,15 +247,15 @@ func (pdp *pathDescriptionParser) parseCommandDrawingInstructions(l *gl.Lexer, i
 		return pdp.parseCloseDI()
 	}
 
-	return fmt.Errorf("unknown command found in SVG: %s", i.Value)
+	return fmt.Errorf("unknown command %q", i.Value)
 }
 
 func (pdp *pathDescriptionParser) parseMoveToAbsDI() error {
 	var tuples []Tuple
 
-	t, err := parseTuple(&pdp.lex)
+	t, err := parseTuple(pdp.lex)
 	if err != nil {
-		return fmt.Errorf("error parsing MoveToAbs. Expected tuple: %s", err)
+		return fmt.Errorf("invalid moveto: %w", err)
 	}
 
 	pdp.x = t[0]
@@ -257,9 +270,9 @@ func (pdp *pathDescriptionParser) parseMoveToAbsDI() error {
 
 	pdp.lex.ConsumeWhiteSpace()
 	for pdp.lex.PeekItem().Type == gl.ItemNumber {
-		t, err := parseTuple(&pdp.lex)
+		t, err := parseTuple(pdp.lex)
 		if err != nil {
-			return fmt.Errorf("Error Passing MoveToAbs\n%s", err)
+			return fmt.Errorf("invalid moveto: %w", err)
 		}
 		tuples = append(tuples, t)
 		pdp.lex.ConsumeWhiteSpace()
@@ -278,73 +291,13 @@ func (pdp *pathDescriptionParser) parseMoveToAbsDI() error {
 	return nil
 }
 
-func (pdp *pathDescriptionParser) parseMoveToAbs() error {
-	var tuples []Tuple
-
-	t, err := parseTuple(&pdp.lex)
-	if err != nil {
-		return fmt.Errorf("Error Passing MoveToAbs Expected Tuple\n%s", err)
-	}
-
-	pdp.x = t[0]
-	pdp.y = t[1]
-
-	if pdp.p.group.Owner == nil {
-		pdp.p.group.Owner = &Svg{scale: 1}
-	}
-	if pdp.p.StrokeWidth == 0 {
-		pdp.p.StrokeWidth = 1
-	}
-
-	scaledStroke := pdp.p.StrokeWidth * pdp.p.group.Owner.scale
-
-	pdp.lex.ConsumeWhiteSpace()
-	for pdp.lex.PeekItem().Type == gl.ItemNumber {
-		t, err := parseTuple(&pdp.lex)
-		if err != nil {
-			return fmt.Errorf("Error Passing MoveToAbs\n%s", err)
-		}
-		tuples = append(tuples, t)
-		pdp.lex.ConsumeWhiteSpace()
-	}
-
-	if pdp.currentsegment != nil {
-		pdp.p.Segments <- *pdp.currentsegment
-		pdp.currentsegment = nil
-	} else {
-		var s Segment
-		s.Width = scaledStroke
-		x, y := pdp.transform.Apply(pdp.x, pdp.y)
-		s.addPoint([2]float64{x, y})
-		pdp.currentsegment = &s
-		//fmt.Printf("orig x %f y %f, applied x %f y %f\n", pdp.x, pdp.y, x, y)
-		pdp.p.instructions <- &DrawingInstruction{Kind: MoveInstruction, M: &Tuple{x, y}}
-	}
-
-	if len(tuples) > 0 {
-		x, y := pdp.transform.Apply(pdp.x, pdp.y)
-		s := pdp.p.newSegment([2]float64{x, y})
-		pdp.p.instructions <- &DrawingInstruction{Kind: MoveInstruction, M: &Tuple{x, y}}
-		for _, nt := range tuples {
-			pdp.x = nt[0]
-			pdp.y = nt[1]
-			x, y = pdp.transform.Apply(pdp.x, pdp.y)
-			s.addPoint([2]float64{x, y})
-			pdp.p.instructions <- &DrawingInstruction{Kind: MoveInstruction, M: &Tuple{x, y}}
-		}
-		pdp.currentsegment = s
-	}
-
-	return nil
-}
-
 func (pdp *pathDescriptionParser) parseLineToAbsDI() error {
 	var tuples []Tuple
 	pdp.lex.ConsumeWhiteSpace()
 	for pdp.lex.PeekItem().Type == gl.ItemNumber {
-		t, err := parseTuple(&pdp.lex)
+		t, err := parseTuple(pdp.lex)
 		if err != nil {
-			return fmt.Errorf("Error Passing LineToAbs\n%s", err)
+			return fmt.Errorf("invalid lineto: %w", err)
 		}
 		tuples = append(tuples, t)
 		pdp.lex.ConsumeWhiteSpace()
@@ -363,38 +316,11 @@ func (pdp *pathDescriptionParser) parseLineToAbsDI() error {
 	return nil
 }
 
-func (pdp *pathDescriptionParser) parseLineToAbs() error {
-	var tuples []Tuple
-	pdp.lex.ConsumeWhiteSpace()
-	for pdp.lex.PeekItem().Type == gl.ItemNumber {
-		t, err := parseTuple(&pdp.lex)
-		if err != nil {
-			return fmt.Errorf("Error Passing LineToAbs\n%s", err)
-		}
-		tuples = append(tuples, t)
-		pdp.lex.ConsumeWhiteSpace()
-	}
-	if len(tuples) > 0 {
-		x, y := pdp.transform.Apply(pdp.x, pdp.y)
-		pdp.currentsegment.addPoint([2]float64{x, y})
-
-		for _, nt := range tuples {
-			pdp.x = nt[0]
-			pdp.y = nt[1]
-			x, y = pdp.transform.Apply(pdp.x, pdp.y)
-			pdp.currentsegment.addPoint([2]float64{x, y})
-			pdp.p.instructions <- &DrawingInstruction{Kind: LineInstruction, M: &Tuple{x, y}}
-		}
-	}
-
-	return nil
-}
-
 func (pdp *pathDescriptionParser) parseMoveToRelDI() error {
 	pdp.lex.ConsumeWhiteSpace()
-	t, err := parseTuple(&pdp.lex)
+	t, err := parseTuple(pdp.lex)
 	if err != nil {
-		return fmt.Errorf("Error Passing MoveToRel Expected First Tuple %s", err)
+		return fmt.Errorf("invalid moveto: %w", err)
 	}
 
 	pdp.x += t[0]
@@ -403,9 +329,9 @@ func (pdp *pathDescriptionParser) parseMoveToRelDI() error {
 	var tuples []Tuple
 	pdp.lex.ConsumeWhiteSpace()
 	for pdp.lex.PeekItem().Type == gl.ItemNumber {
-		t, err := parseTuple(&pdp.lex)
+		t, err := parseTuple(pdp.lex)
 		if err != nil {
-			return fmt.Errorf("Error Passing MoveToRel\n%s", err)
+			return fmt.Errorf("invalid moveto: %w", err)
 		}
 		tuples = append(tuples, t)
 		pdp.lex.ConsumeWhiteSpace()
@@ -424,54 +350,6 @@ func (pdp *pathDescriptionParser) parseMoveToRelDI() error {
 	return nil
 }
 
-func (pdp *pathDescriptionParser) parseMoveToRel() error {
-	pdp.lex.ConsumeWhiteSpace()
-	t, err := parseTuple(&pdp.lex)
-	if err != nil {
-		return fmt.Errorf("Error Passing MoveToRel Expected First Tuple\n%s", err)
-	}
-
-	pdp.x += t[0]
-	pdp.y += t[1]
-
-	var tuples []Tuple
-	pdp.lex.ConsumeWhiteSpace()
-	for pdp.lex.PeekItem().Type == gl.ItemNumber {
-		t, err := parseTuple(&pdp.lex)
-		if err != nil {
-			return fmt.Errorf("Error Passing MoveToRel\n%s", err)
-		}
-		tuples = append(tuples, t)
-		pdp.lex.ConsumeWhiteSpace()
-	}
-	if pdp.currentsegment != nil {
-		pdp.p.Segments <- *pdp.currentsegment
-		pdp.currentsegment = nil
-	} else {
-		var s Segment
-		scaledStroke := pdp.p.StrokeWidth * pdp.svg.scale
-		s.Width = scaledStroke
-
-		x, y := pdp.transform.Apply(pdp.x, pdp.y)
-		s.addPoint([2]float64{x, y})
-		pdp.p.instructions <- &DrawingInstruction{Kind: MoveInstruction, M: &Tuple{x, y}}
-		pdp.currentsegment = &s
-	}
-	if len(tuples) > 0 {
-		x, y := pdp.transform.Apply(pdp.x, pdp.y)
-		pdp.currentsegment.addPoint([2]float64{x, y})
-		for _, nt := range tuples {
-			pdp.x += nt[0]
-			pdp.y += nt[1]
-			x, y = pdp.transform.Apply(pdp.x, pdp.y)
-			pdp.currentsegment.addPoint([2]float64{x, y})
-			pdp.p.instructions <- &DrawingInstruction{Kind: MoveInstruction, M: &Tuple{x, y}}
-		}
-	}
-
-	return nil
-}
-
 func (pdp *pathDescriptionParser) parseHLineToDI(abs bool) error {
 	coords := []float64{}
 	pdp.lex.ConsumeWhiteSpace()
@@ -479,7 +357,7 @@ func (pdp *pathDescriptionParser) parseHLineToDI(abs bool) error {
 		item := pdp.lex.NextItem()
 		c, err := strconv.ParseFloat(item.Value, 64)
 		if err != nil {
-			return fmt.Errorf("parsing %q: %s", item.Value, err)
+			return fmt.Errorf("invalid horizontal lineto: %w", err)
 		}
 		coords = append(coords, c)
 		pdp.lex.ConsumeWhiteSpace()
@@ -502,9 +380,9 @@ func (pdp *pathDescriptionParser) parseLineToRelDI() error {
 	var tuples []Tuple
 	pdp.lex.ConsumeWhiteSpace()
 	for pdp.lex.PeekItem().Type == gl.ItemNumber {
-		t, err := parseTuple(&pdp.lex)
+		t, err := parseTuple(pdp.lex)
 		if err != nil {
-			return fmt.Errorf("Error Passing LineToRel\n%s", err)
+			return fmt.Errorf("invalid lineto: %w", err)
 		}
 		tuples = append(tuples, t)
 		pdp.lex.ConsumeWhiteSpace()
@@ -524,85 +402,13 @@ func (pdp *pathDescriptionParser) parseLineToRelDI() error {
 	return nil
 }
 
-func (pdp *pathDescriptionParser) parseLineToRel() error {
-
-	var tuples []Tuple
-	pdp.lex.ConsumeWhiteSpace()
-	for pdp.lex.PeekItem().Type == gl.ItemNumber {
-		t, err := parseTuple(&pdp.lex)
-		if err != nil {
-			return fmt.Errorf("Error Passing LineToRel\n%s", err)
-		}
-		tuples = append(tuples, t)
-		pdp.lex.ConsumeWhiteSpace()
-	}
-	if len(tuples) > 0 {
-		x, y := pdp.transform.Apply(pdp.x, pdp.y)
-		pdp.currentsegment.addPoint([2]float64{x, y})
-
-		for _, nt := range tuples {
-			pdp.x += nt[0]
-			pdp.y += nt[1]
-			x, y = pdp.transform.Apply(pdp.x, pdp.y)
-			pdp.currentsegment.addPoint([2]float64{x, y})
-			pdp.p.instructions <- &DrawingInstruction{Kind: LineInstruction, M: &Tuple{x, y}}
-		}
-	}
-
-	return nil
-}
-
-func (pdp *pathDescriptionParser) parseHLineToAbs() error {
-	pdp.lex.ConsumeWhiteSpace()
-	var n float64
-	var err error
-	if pdp.lex.PeekItem().Type != gl.ItemNumber {
-		n, err = parseNumber(pdp.lex.NextItem())
-		if err != nil {
-			return fmt.Errorf("Error Passing HLineToAbs\n%s", err)
-		}
-	}
-
-	x, y := pdp.transform.Apply(pdp.x, pdp.y)
-	pdp.currentsegment.addPoint([2]float64{x, y})
-	pdp.p.instructions <- &DrawingInstruction{Kind: MoveInstruction, M: &Tuple{x, y}}
-	pdp.x = n
-	x, y = pdp.transform.Apply(pdp.x, pdp.y)
-	pdp.currentsegment.addPoint([2]float64{x, y})
-	pdp.p.instructions <- &DrawingInstruction{Kind: LineInstruction, M: &Tuple{x, y}}
-
-	return nil
-}
-
-func (pdp *pathDescriptionParser) parseHLineToRel() error {
-	pdp.lex.ConsumeWhiteSpace()
-	var n float64
-	var err error
-	if pdp.lex.PeekItem().Type != gl.ItemNumber {
-		n, err = parseNumber(pdp.lex.NextItem())
-		if err != nil {
-			return fmt.Errorf("Error Passing HLineToRel\n%s", err)
-		}
-	}
-
-	x, y := pdp.transform.Apply(pdp.x, pdp.y)
-	pdp.currentsegment.addPoint([2]float64{x, y})
-	pdp.p.instructions <- &DrawingInstruction{Kind: MoveInstruction, M: &Tuple{x, y}}
-	pdp.x += n
-	x, y = pdp.transform.Apply(pdp.x, pdp.y)
-	pdp.p.instructions <- &DrawingInstruction{Kind: LineInstruction, M: &Tuple{x, y}}
-	pdp.currentsegment.addPoint([2]float64{x, y})
-
-	return nil
-}
-
 func (pdp *pathDescriptionParser) parseVLineToDI(abs bool) error {
 	pdp.lex.ConsumeWhiteSpace()
 	coords := []float64{}
 	for pdp.lex.PeekItem().Type == gl.ItemNumber {
 		n, err := parseNumber(pdp.lex.NextItem())
 		if err != nil {
-			return fmt.Errorf("Error Passing VLineToRel\n%s", err)
+			return fmt.Errorf("invalid vertical lineto: %w", err)
 		}
 		coords = append(coords, n)
 		pdp.lex.ConsumeWhiteSpace()
@@ -621,26 +427,6 @@ func (pdp *pathDescriptionParser) parseVLineToDI(abs bool) error {
 	return nil
 }
 
-func (pdp *pathDescriptionParser) parseVLineToAbs() error {
-	pdp.lex.ConsumeWhiteSpace()
-	var n float64
-	var err error
-	if pdp.lex.PeekItem().Type != gl.ItemNumber {
-		n, err = parseNumber(pdp.lex.NextItem())
-		if err != nil {
-			return fmt.Errorf("Error Passing VLineToAbs\n%s", err)
-		}
-	}
-
-	x, y := pdp.transform.Apply(pdp.x, pdp.y)
-	pdp.currentsegment.addPoint([2]float64{x, y})
-	pdp.y = n
-	x, y = pdp.transform.Apply(pdp.x, pdp.y)
-	pdp.currentsegment.addPoint([2]float64{x, y})
-
-	return nil
-}
-
 func (pdp *pathDescriptionParser) parseCloseDI() error {
 	pdp.lex.ConsumeWhiteSpace()
 
@@ -649,48 +435,13 @@ func (pdp *pathDescriptionParser) parseCloseDI() error {
 	return nil
 }
 
-func (pdp *pathDescriptionParser) parseClose() error {
-	pdp.lex.ConsumeWhiteSpace()
-
-	if pdp.currentsegment != nil {
-		pdp.currentsegment.addPoint(pdp.currentsegment.Points[0])
-		pdp.currentsegment.Closed = true
-		pdp.p.Segments <- *pdp.currentsegment
-		pdp.currentsegment = nil
-	}
-
-	pdp.p.instructions <- &DrawingInstruction{Kind: CloseInstruction}
-	return nil
-}
-
-func (pdp *pathDescriptionParser) parseVLineToRel() error {
-	pdp.lex.ConsumeWhiteSpace()
-	var n float64
-	var err error
-	if pdp.lex.PeekItem().Type != gl.ItemNumber {
-		n, err = parseNumber(pdp.lex.NextItem())
-		if err != nil {
-			return fmt.Errorf("Error Passing VLineToRel\n%s", err)
-		}
-	}
-
-	x, y := pdp.transform.Apply(pdp.x, pdp.y)
-	pdp.currentsegment.addPoint([2]float64{x, y})
-	pdp.y += n
-	x, y = pdp.transform.Apply(pdp.x, pdp.y)
-	pdp.currentsegment.addPoint([2]float64{x, y})
-
-	return nil
-
-}
-
 func (pdp *pathDescriptionParser) parseCurveToRelDI() error {
 	var tuples []Tuple
 	pdp.lex.ConsumeWhiteSpace()
 	for pdp.lex.PeekItem().Type == gl.ItemNumber {
-		t, err := parseTuple(&pdp.lex)
+		t, err := parseTuple(pdp.lex)
 		if err != nil {
-			return fmt.Errorf("Error Passing CurveToRel\n%s", err)
+			return fmt.Errorf("invalid curveto: %w", err)
 		}
 		tuples = append(tuples, t)
 		pdp.lex.ConsumeWhiteSpace()
@@ -718,60 +469,6 @@ func (pdp *pathDescriptionParser) parseCurveToRelDI() error {
 	return nil
 }
 
-func (pdp *pathDescriptionParser) parseCurveToRel() error {
-	var tuples []Tuple
-	pdp.lex.ConsumeWhiteSpace()
-	for pdp.lex.PeekItem().Type == gl.ItemNumber {
-		t, err := parseTuple(&pdp.lex)
-		if err != nil {
-			return fmt.Errorf("Error Passing CurveToRel\n%s", err)
-		}
-		tuples = append(tuples, t)
-		pdp.lex.ConsumeWhiteSpace()
-	}
-	x, y := pdp.transform.Apply(pdp.x, pdp.y)
-	pdp.currentsegment.addPoint([2]float64{x, y})
-
-	for j := 0; j < len(tuples)/3; j++ {
-		var cb cubicBezier
-		cb.controlpoints[0][0] = pdp.x
-		cb.controlpoints[0][1] = pdp.y
-
-		cb.controlpoints[1][0] = pdp.x + tuples[j*3][0]
-		cb.controlpoints[1][1] = pdp.y + tuples[j*3][1]
-
-		cb.controlpoints[2][0] = pdp.x + tuples[j*3+1][0]
-		cb.controlpoints[2][1] = pdp.y + tuples[j*3+1][1]
-
-		pdp.x += tuples[j*3+2][0]
-		pdp.y += tuples[j*3+2][1]
-
-		cb.controlpoints[3][0] = pdp.x
-		cb.controlpoints[3][1] = pdp.y
-
-		c1x, c1y := pdp.transform.Apply(x+tuples[j*3][0], y+tuples[j*3][1])
-		c2x, c2y := pdp.transform.Apply(x+tuples[j*3+1][0], y+tuples[j*3+1][1])
-		tx, ty := pdp.transform.Apply(x+tuples[j*3+2][0], y+tuples[j*3+2][1])
-
-		pdp.p.instructions <- &DrawingInstruction{
-			Kind: CurveInstruction,
-			CurvePoints: &CurvePoints{
-				C1: &Tuple{c1x, c1y},
-				C2: &Tuple{c2x, c2y},
-				T:  &Tuple{tx, ty},
-			},
-		}
-
-		vertices := cb.recursiveInterpolate(10, 0)
-		for _, v := range vertices {
-			x, y = pdp.transform.Apply(v[0], v[1])
-			pdp.currentsegment.addPoint([2]float64{x, y})
-		}
-	}
-
-	return nil
-}
-
 func (pdp *pathDescriptionParser) parseCurveToAbsDI() error {
 	var (
 		tuples      []Tuple
@@ -780,9 +477,9 @@ func (pdp *pathDescriptionParser) parseCurveToAbsDI() error {
 
 	pdp.lex.ConsumeWhiteSpace()
 	for pdp.lex.PeekItem().Type == gl.ItemNumber {
-		t, err := parseTuple(&pdp.lex)
+		t, err := parseTuple(pdp.lex)
 		if err != nil {
-			return fmt.Errorf("Error parsing CurveToRel\n%s", err)
+			return fmt.Errorf("invalid curveto: %w", err)
 		}
 		tuples = append(tuples, t)
 		pdp.lex.ConsumeWhiteSpace()
@@ -811,60 +508,6 @@ func (pdp *pathDescriptionParser) parseCurveToAbsDI() error {
 	return nil
 }
 
-func (pdp *pathDescriptionParser) parseCurveToAbs() error {
-	var (
-		tuples      []Tuple
-		instrTuples []Tuple
-	)
-
-	pdp.lex.ConsumeWhiteSpace()
-	for pdp.lex.PeekItem().Type == gl.ItemNumber {
-		t, err := parseTuple(&pdp.lex)
-		if err != nil {
-			return fmt.Errorf("Error parsing CurveToRel\n%s", err)
-		}
-		tuples = append(tuples, t)
-		pdp.lex.ConsumeWhiteSpace()
-		pdp.lex.ConsumeComma()
-	}
-
-	x, y := pdp.transform.Apply(pdp.x, pdp.y)
-	pdp.currentsegment.addPoint([2]float64{x, y})
-
-	for j := 0; j < len(tuples)/3; j++ {
-		var cb cubicBezier
-		cb.controlpoints[0][0] = pdp.x
-		cb.controlpoints[0][1] = pdp.y
-
-		for i, nt := range tuples[j*3 : (j+1)*3] {
-			pdp.x = nt[0]
-			pdp.y = nt[1]
-			cb.controlpoints[i+1][0] = pdp.x
-			cb.controlpoints[i+1][1] = pdp.y
-
-			tx, ty := pdp.transform.Apply(pdp.x, pdp.y)
-			instrTuples = append(instrTuples, Tuple{tx, ty})
-		}
-
-		pdp.p.instructions <- &DrawingInstruction{
-			Kind: CurveInstruction,
-			CurvePoints: &CurvePoints{
-				C1: &instrTuples[0],
-				C2: &instrTuples[1],
-				T:  &instrTuples[2],
-			},
-		}
-
-		vertices := cb.recursiveInterpolate(10, 0)
-		for _, v := range vertices {
-			x, y = pdp.transform.Apply(v[0], v[1])
-			pdp.currentsegment.addPoint([2]float64{x, y})
-		}
-	}
-
-	return nil
-}
-
 func (p *Path) parseStyle() {
 	p.properties = splitStyle(p.Style)
 	for key, val := range p.properties {
@@ -874,7 +517,74 @@ func (p *Path) parseStyle() {
 			if ok == nil {
 				p.StrokeWidth = sw
 			}
-
+		case "display":
+			p.Display = val
+		case "visibility":
+			p.Visibility = val
+		case "stroke-linecap":
+			lc := val
+			p.StrokeLineCap = &lc
+		case "stroke-linejoin":
+			lj := val
+			p.StrokeLineJoin = &lj
+		case "stroke-miterlimit":
+			ml, ok := strconv.ParseFloat(val, 64)
+			if ok == nil {
+				p.StrokeMiterLimit = ml
+			}
+		case "stroke-dasharray":
+			da := val
+			p.StrokeDashArray = &da
+		case "stroke-dashoffset":
+			do, ok := strconv.ParseFloat(val, 64)
+			if ok == nil {
+				p.StrokeDashOffset = &do
+			}
+		case "opacity", "fill-opacity", "stroke-opacity":
+			o, ok := strconv.ParseFloat(val, 64)
+			if ok != nil {
+				continue
+			}
+			switch key {
+			case "opacity":
+				p.Opacity = &o
+			case "fill-opacity":
+				p.FillOpacity = &o
+			default:
+				p.StrokeOpacity = &o
+			}
 		}
 	}
 }
+
+// flattenTolerance returns the flattening tolerance of the document the
+// path belongs to.
+func (p *Path) flattenTolerance() float64 {
+	if p.group == nil || p.group.Owner == nil {
+		return 0
+	}
+	return p.group.Owner.FlattenTolerance
+}
+
+// warn handles a recoverable problem in the path at offset within its
+// path data. See Svg.warn.
+func (p *Path) warn(offset int, err error) error {
+	var owner *Svg
+	if p.group != nil {
+		owner = p.group.Owner
+	}
+	return owner.warn(p.pos, "path", p.ID, offset, err)
+}
+
+// lexedLength returns how much of the path data d the lexer tokenizes.
+// The lexer silently stops at the first character that cannot start a
+// token, and its items cover everything before it, so d is lexed again
+// and the lengths of the items are added up.
+func lexedLength(d string) int {
+	_, items := gl.Lex("d", d)
+	n := 0
+	for i := range items {
+		n += len(i.Value)
+	}
+	return n
+}
@@ -1,6 +1,7 @@
 package svg
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -102,3 +103,59 @@ func TestParsePathList(t *testing.T) {
 		}
 	}
 }
+
+func TestFlattenTolerance(t *testing.T) {
+	svg, err := ParseSvg(`<svg><path d="M0 0 C0 100 100 100 100 0"/><circle cx="0" cy="0" r="100"/></svg>`, "test", 0)
+	require.NoError(t, err)
+	count := func(tolerance float64) (int, int) {
+		svg.FlattenTolerance = tolerance
+		shapes, err := svg.Shapes()
+		require.NoError(t, err)
+		require.Len(t, shapes, 2)
+		return len(shapes[0].Segments[0].Points), len(shapes[1].Segments[0].Points)
+	}
+
+	// zero keeps the fixed subdivision and the 72 sided circle
+	curve, circle := count(0)
+	require.Equal(t, circleSteps+1, circle)
+	fine, fineCircle := count(0.01)
+	coarse, coarseCircle := count(5)
+	require.Greater(t, fine, coarse)
+	require.Greater(t, fineCircle, coarseCircle)
+	require.NotEqual(t, curve, fine)
+}
+
+func TestParsePathCrashers(t *testing.T) {
+	segments := func(d string) [][][2]float64 {
+		var result [][][2]float64
+		for s := range (&Path{D: d}).Parse() {
+			result = append(result, s.Points)
+		}
+		return result
+	}
+
+	// a moveto followed by another starts the segment at the second
+	require.Equal(t, [][][2]float64{{{1, 1}, {2, 2}}}, segments("M0 0 M1 1 L2 2"))
+	instrs, err := collectInstructions(&Path{D: "M0 0 M1 1 L2 2"})
+	require.NoError(t, err)
+	require.Len(t, instrs, 4)
+	require.Equal(t, MoveInstruction, instrs[1].Kind)
+	require.Equal(t, Tuple{1, 1}, *instrs[1].M)
+
+	// path data without a leading moveto draws nothing and is reported
+	require.Empty(t, segments("L1 1"))
+	svg, err := ParseSvg(`<svg><path id="p" d="L1 1"/></svg>`, "test", 0)
+	require.NoError(t, err)
+	shapes, err := svg.Shapes()
+	require.NoError(t, err)
+	require.Len(t, shapes, 1)
+	require.Empty(t, shapes[0].Segments)
+	require.Len(t, svg.Warnings, 1)
+	require.Contains(t, svg.Warnings[0].String(), "path data must begin with a moveto")
+
+	svg, err = ParseSvgWithOptions(strings.NewReader(`<svg><path d="L1 1"/></svg>`), NewOptions(WithStrict(true)))
+	require.NoError(t, err)
+	_, err = svg.Shapes()
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "path data must begin with a moveto")
+}
@@ -0,0 +1,94 @@
+package svg
+
+import (
+	"strconv"
+	"strings"
+)
+
+// circleKappa is the distance of the control points of a cubic Bézier
+// approximating a quarter circle, relative to the radius.
+const circleKappa = 0.5522847498307936
+
+// PathData formats drawing instructions as the value of a path's d
+// attribute using absolute commands. Circles are written as four cubic
+// curves, paint instructions are ignored.
+func PathData(instrs []*DrawingInstruction) string {
+	var b strings.Builder
+	command := func(c byte, pts ...Tuple) {
+		if b.Len() > 0 {
+			b.WriteByte(' ')
+		}
+		b.WriteByte(c)
+		for i, p := range pts {
+			if i > 0 {
+				b.WriteByte(' ')
+			}
+			b.WriteString(formatNumber(p[0]))
+			b.WriteByte(' ')
+			b.WriteString(formatNumber(p[1]))
+		}
+	}
+
+	for _, di := range expandCircles(instrs) {
+		switch di.Kind {
+		case MoveInstruction:
+			command('M', *di.M)
+		case LineInstruction:
+			command('L', *di.M)
+		case CurveInstruction:
+			command('C', *di.CurvePoints.C1, *di.CurvePoints.C2, *di.CurvePoints.T)
+		case CloseInstruction:
+			command('Z')
+		}
+	}
+	return b.String()
+}
+
+// expandCircles replaces every CircleInstruction by a closed path of
+// four cubic curves.
+func expandCircles(instrs []*DrawingInstruction) []*DrawingInstruction {
+	var result []*DrawingInstruction
+	for _, di := range instrs {
+		if di.Kind != CircleInstruction {
+			result = append(result, di)
+			continue
+		}
+		cx, cy, r := di.M[0], di.M[1], *di.Radius
+		k := r * circleKappa
+		curve := func(c1, c2, t Tuple) *DrawingInstruction {
+			return &DrawingInstruction{Kind: CurveInstruction, CurvePoints: &CurvePoints{C1: &c1, C2: &c2, T: &t}}
+		}
+		result = append(result,
+			&DrawingInstruction{Kind: MoveInstruction, M: &Tuple{cx + r, cy}},
+			curve(Tuple{cx + r, cy + k}, Tuple{cx + k, cy + r}, Tuple{cx, cy + r}),
+			curve(Tuple{cx - k, cy + r}, Tuple{cx - r, cy + k}, Tuple{cx - r, cy}),
+			curve(Tuple{cx - r, cy - k}, Tuple{cx - k, cy - r}, Tuple{cx, cy - r}),
+			curve(Tuple{cx + k, cy - r}, Tuple{cx + r, cy - k}, Tuple{cx + r, cy}),
+			&DrawingInstruction{Kind: CloseInstruction},
+		)
+	}
+	return result
+}
+
+// formatNumber formats a coordinate with the shortest representation
+// that round-trips.
+func formatNumber(v float64) string {
+	if v == 0 {
+		// avoid writing negative zero
+		v = 0
+	}
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+// fixedNumber formats v with at most prec decimals and without trailing
+// zeros or exponent.
+func fixedNumber(v float64, prec int) string {
+	s := strconv.FormatFloat(v, 'f', prec, 64)
+	if strings.Contains(s, ".") {
+		s = strings.TrimSuffix(strings.TrimRight(s, "0"), ".")
+	}
+	if s == "-0" {
+		return "0"
+	}
+	return s
+}
@@ -0,0 +1,232 @@
+package svg
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+
+	mt "github.com/rustyoz/Mtransform"
+)
+
+// pointsPerUserUnit converts user units (px) to PDF points.
+const pointsPerUserUnit = 72 / userUnitsPerInch
+
+// WritePDF writes the document to w as a single page PDF. The page size
+// is the Size of the document. Fills, strokes with their
+// caps, joins, miter limits and dashes, and element and group opacity
+// are carried over as PDF vector graphics.
+func WritePDF(w io.Writer, svg *Svg) error {
+	width, height, err := svg.Size()
+	if err != nil {
+		return err
+	}
+
+	// PDF user space is in points with the origin at the bottom left
+	view := mt.Identity()
+	view.Translate(0, height*pointsPerUserUnit)
+	view.Scale(pointsPerUserUnit, -pointsPerUserUnit)
+	view = mt.MultiplyTransforms(view, viewportTransform(svg, width, height))
+
+	r := &pdfRenderer{view: view, states: make(map[[2]float64]int)}
+	r.content = []*bytes.Buffer{{}}
+	if err := Replay(svg, r); err != nil {
+		return err
+	}
+	return r.write(w, width*pointsPerUserUnit, height*pointsPerUserUnit)
+}
+
+// pdfRenderer is a Renderer that builds a PDF content stream. Groups
+// that are not fully opaque are written as transparency group form
+// XObjects and painted with their opacity.
+type pdfRenderer struct {
+	view      mt.Transform
+	transform mt.Transform
+	path      bytes.Buffer
+
+	// content is a stack of content streams, one per open group
+	content []*bytes.Buffer
+	opacity []float64
+	states  map[[2]float64]int
+	forms   [][]byte
+}
+
+func (r *pdfRenderer) out() *bytes.Buffer {
+	return r.content[len(r.content)-1]
+}
+
+// SetTransform implements the Renderer interface
+func (r *pdfRenderer) SetTransform(t mt.Transform) {
+	r.transform = mt.MultiplyTransforms(r.view, t)
+}
+
+// PushGroup implements the Renderer interface
+func (r *pdfRenderer) PushGroup(opacity float64, clip []Segment) {
+	r.opacity = append(r.opacity, opacity)
+	if opacity < 1 {
+		r.content = append(r.content, &bytes.Buffer{})
+	}
+}
+
+// PopGroup implements the Renderer interface
+func (r *pdfRenderer) PopGroup() {
+	opacity := r.opacity[len(r.opacity)-1]
+	r.opacity = r.opacity[:len(r.opacity)-1]
+	if opacity >= 1 {
+		return
+	}
+	form := r.out().Bytes()
+	r.content = r.content[:len(r.content)-1]
+	r.forms = append(r.forms, form)
+	fmt.Fprintf(r.out(), "q /GS%d gs /Fm%d Do Q\n", r.state(opacity, opacity), len(r.forms)-1)
+}
+
+// MoveTo implements the Renderer interface
+func (r *pdfRenderer) MoveTo(x, y float64) {
+	fmt.Fprintf(&r.path, "%s %s m\n", pdfNumber(x), pdfNumber(y))
+}
+
+// LineTo implements the Renderer interface
+func (r *pdfRenderer) LineTo(x, y float64) {
+	fmt.Fprintf(&r.path, "%s %s l\n", pdfNumber(x), pdfNumber(y))
+}
+
+// CubicTo implements the Renderer interface
+func (r *pdfRenderer) CubicTo(c1x, c1y, c2x, c2y, x, y float64) {
+	fmt.Fprintf(&r.path, "%s %s %s %s %s %s c\n",
+		pdfNumber(c1x), pdfNumber(c1y), pdfNumber(c2x), pdfNumber(c2y), pdfNumber(x), pdfNumber(y))
+}
+
+// ClosePath implements the Renderer interface
+func (r *pdfRenderer) ClosePath() {
+	r.path.WriteString("h\n")
+}
+
+// Fill implements the Renderer interface
+func (r *pdfRenderer) Fill(style PaintStyle) {
+	op := "f"
+	if style.FillRule == "evenodd" {
+		op = "f*"
+	}
+	r.paint(style, fmt.Sprintf("/GS%d gs %s rg\n", r.state(style.Opacity, 1), pdfColor(style)), op)
+}
+
+// Stroke implements the Renderer interface
+func (r *pdfRenderer) Stroke(style PaintStyle) {
+	s := style.Stroke
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "/GS%d gs %s RG\n", r.state(1, style.Opacity), pdfColor(style))
+	fmt.Fprintf(&b, "%s w %d J %d j", pdfNumber(s.Width), s.LineCap, s.LineJoin)
+	if s.MiterLimit >= 1 {
+		fmt.Fprintf(&b, " %s M", pdfNumber(s.MiterLimit))
+	}
+	if len(s.Dash) > 0 {
+		b.WriteString(" [")
+		for i, d := range s.Dash {
+			if i > 0 {
+				b.WriteByte(' ')
+			}
+			b.WriteString(pdfNumber(d))
+		}
+		fmt.Fprintf(&b, "] %s d", pdfNumber(s.DashOffset))
+	}
+	b.WriteByte('\n')
+	r.paint(style, b.String(), "S")
+}
+
+// paint writes the current path with the graphics state set up by state
+// and painted with op. The path is kept in document space and mapped by
+// the current transform, so that line widths and dashes scale with it.
+func (r *pdfRenderer) paint(style PaintStyle, state, op string) {
+	defer r.path.Reset()
+	if style.Opacity <= 0 || r.path.Len() == 0 {
+		return
+	}
+	t := r.transform
+	out := r.out()
+	fmt.Fprintf(out, "q %s %s %s %s %s %s cm\n",
+		pdfNumber(t[0][0]), pdfNumber(t[1][0]), pdfNumber(t[0][1]), pdfNumber(t[1][1]), pdfNumber(t[0][2]), pdfNumber(t[1][2]))
+	out.WriteString(state)
+	out.Write(r.path.Bytes())
+	out.WriteString(op + "\nQ\n")
+}
+
+// state returns the index of the ExtGState with the given fill and
+// stroke alpha, adding it if needed.
+func (r *pdfRenderer) state(fill, stroke float64) int {
+	key := [2]float64{fill, stroke}
+	if i, ok := r.states[key]; ok {
+		return i
+	}
+	r.states[key] = len(r.states)
+	return r.states[key]
+}
+
+// write writes the complete PDF file for a page of the given size in
+// points.
+func (r *pdfRenderer) write(w io.Writer, width, height float64) error {
+	var buf bytes.Buffer
+	var offsets []int
+	object := func(format string, args ...interface{}) {
+		offsets = append(offsets, buf.Len())
+		fmt.Fprintf(&buf, "%d 0 obj\n", len(offsets))
+		fmt.Fprintf(&buf, format, args...)
+		buf.WriteString("\nendobj\n")
+	}
+	stream := func(dict string, data []byte) {
+		object("<< %s/Length %d >>\nstream\n%s\nendstream", dict, len(data), data)
+	}
+
+	// objects 1 to 4 are the catalog, the page tree, the page and its
+	// content; graphics states and forms follow
+	firstState := 5
+	firstForm := firstState + len(r.states)
+	var resources bytes.Buffer
+	resources.WriteString("<< /ExtGState <<")
+	for i := 0; i < len(r.states); i++ {
+		fmt.Fprintf(&resources, " /GS%d %d 0 R", i, firstState+i)
+	}
+	resources.WriteString(" >> /XObject <<")
+	for i := range r.forms {
+		fmt.Fprintf(&resources, " /Fm%d %d 0 R", i, firstForm+i)
+	}
+	resources.WriteString(" >> >>")
+
+	box := fmt.Sprintf("[0 0 %s %s]", pdfNumber(width), pdfNumber(height))
+	buf.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+	object("<< /Type /Catalog /Pages 2 0 R >>")
+	object("<< /Type /Pages /Kids [3 0 R] /Count 1 >>")
+	object("<< /Type /Page /Parent 2 0 R /MediaBox %s /Resources %s /Contents 4 0 R /Group << /S /Transparency /CS /DeviceRGB >> >>",
+		box, resources.String())
+	stream("", r.content[0].Bytes())
+
+	alphas := make([][2]float64, len(r.states))
+	for key, i := range r.states {
+		alphas[i] = key
+	}
+	for _, a := range alphas {
+		object("<< /Type /ExtGState /ca %s /CA %s >>", pdfNumber(a[0]), pdfNumber(a[1]))
+	}
+	for _, form := range r.forms {
+		stream(fmt.Sprintf("/Type /XObject /Subtype /Form /BBox %s /Group << /S /Transparency >> /Resources %s ", box, resources.String()), form)
+	}
+
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, o := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", o)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+func pdfColor(style PaintStyle) string {
+	c := style.Color
+	return fmt.Sprintf("%s %s %s", pdfNumber(float64(c.R)/255), pdfNumber(float64(c.G)/255), pdfNumber(float64(c.B)/255))
+}
+
+// pdfNumber formats a real number without exponent, as PDF requires.
+func pdfNumber(v float64) string {
+	return fixedNumber(v, 6)
+}
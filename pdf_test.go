@@ -0,0 +1,62 @@
+package svg
+
+import (
+	"bytes"
+	"regexp"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWritePDF(t *testing.T) {
+	svg, err := ParseSvg(`<svg width="100mm" height="50mm" viewBox="0 0 100 50">
+<path d="M5 5 L45 5 L45 45 Z" fill="#ff0000" fill-rule="evenodd" fill-opacity="0.5"/>
+<g opacity="0.25"><path d="M50 5 L95 45" fill="none" stroke="blue" stroke-width="2" stroke-linecap="round" stroke-linejoin="bevel" stroke-miterlimit="8" stroke-dasharray="4 2" stroke-dashoffset="1"/></g>
+</svg>`, "test", 0)
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	require.NoError(t, WritePDF(&buf, svg))
+	pdf := buf.String()
+
+	require.Regexp(t, `^%PDF-1\.4\n`, pdf)
+	require.Regexp(t, `\n%%EOF\n$`, pdf)
+	require.Contains(t, pdf, "/MediaBox [0 0 283.464567 141.732283]")
+	require.Contains(t, pdf, "1 0 0 rg\n5 5 m\n45 5 l\n45 45 l\nh\nf*\n")
+	require.Contains(t, pdf, "0 0 1 RG\n2 w 1 J 2 j 8 M [4 2] 1 d\n50 5 m\n95 45 l\nS\n")
+	require.Contains(t, pdf, "/ca 0.5 /CA 1")
+	require.Contains(t, pdf, "/ca 0.25 /CA 0.25")
+	require.Contains(t, pdf, "/Subtype /Form")
+	require.Regexp(t, `/Fm0 Do`, pdf)
+
+	// every cross reference entry points at its object
+	m := regexp.MustCompile(`(?s)xref\n0 (\d+)\n0000000000 65535 f \n(.*)trailer`).FindStringSubmatch(pdf)
+	require.NotNil(t, m)
+	entries := regexp.MustCompile(`(\d{10}) 00000 n`).FindAllStringSubmatch(m[2], -1)
+	n, _ := strconv.Atoi(m[1])
+	require.Len(t, entries, n-1)
+	for i, e := range entries {
+		off, _ := strconv.Atoi(e[1])
+		require.Regexp(t, "^"+strconv.Itoa(i+1)+" 0 obj", pdf[off:])
+	}
+}
+
+func TestWritePDFScale(t *testing.T) {
+	// the page grows with the scale but strokes keep their width
+	doc := `<svg width="100" height="50"><path d="M50 5 L95 45" fill="none" stroke="blue" stroke-width="2" stroke-dasharray="4 2"/></svg>`
+	for scale, box := range map[float64]string{1: "75 37.5", 2: "150 75"} {
+		svg, err := ParseSvg(doc, "test", scale)
+		require.NoError(t, err)
+		var buf bytes.Buffer
+		require.NoError(t, WritePDF(&buf, svg))
+		require.Contains(t, buf.String(), "/MediaBox [0 0 "+box+"]")
+		require.Contains(t, buf.String(), "2 w 0 J 0 j 4 M [4 2] 0 d\n")
+	}
+}
+
+func TestWritePDFWithoutSize(t *testing.T) {
+	svg, err := ParseSvg(`<svg><path d="M0 0 L1 1"/></svg>`, "test", 0)
+	require.NoError(t, err)
+	require.Error(t, WritePDF(&bytes.Buffer{}, svg))
+}
@@ -0,0 +1,368 @@
+package svg
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+	"sort"
+
+	mt "github.com/rustyoz/Mtransform"
+)
+
+// subScanlines is the number of vertical samples per pixel row used for
+// anti-aliasing. Horizontal coverage is computed exactly.
+const subScanlines = 16
+
+// RenderOptions controls how a document is rasterised.
+type RenderOptions struct {
+	// Background, if not nil, is painted over the whole image before
+	// the document is drawn.
+	Background color.Color
+}
+
+// Render rasterises the document into img. The viewBox, or the width
+// and height if there is none, is scaled uniformly to fit the image
+// bounds and centred (preserveAspectRatio="xMidYMid meet"). Fills honour
+// the nonzero and evenodd fill rules, strokes are drawn with their
+// joins and caps, fills and strokes are painted with colours or linear
+// and radial gradients, and element and group opacity are composited. A
+// nil opts uses the defaults.
+func Render(svg *Svg, img draw.Image, opts *RenderOptions) error {
+	if opts == nil {
+		opts = &RenderOptions{}
+	}
+	if opts.Background != nil {
+		draw.Draw(img, img.Bounds(), image.NewUniform(opts.Background), image.Point{}, draw.Src)
+	}
+
+	r := &rasterizer{target: img, view: viewTransform(svg, img.Bounds()), tolerance: svg.FlattenTolerance}
+	return Replay(svg, r)
+}
+
+// viewTransform maps the document's viewport onto bounds.
+func viewTransform(svg *Svg, bounds image.Rectangle) mt.Transform {
+	t := mt.Identity()
+	t.Translate(float64(bounds.Min.X), float64(bounds.Min.Y))
+	return mt.MultiplyTransforms(t, viewportTransform(svg, float64(bounds.Dx()), float64(bounds.Dy())))
+}
+
+// viewportTransform maps the document's viewBox, or its width and height
+// if there is none, onto a w by h viewport starting at the origin. The
+// content is scaled uniformly and centred. Replay applies the scale given
+// to ParseSvg to the content, so the viewBox is scaled by it as well.
+func viewportTransform(svg *Svg, w, h float64) mt.Transform {
+	var vx, vy, vw, vh float64
+	if vb, err := svg.ViewBoxValues(); err == nil && len(vb) == 4 {
+		vx, vy, vw, vh = vb[0], vb[1], vb[2], vb[3]
+	} else {
+		vw, _ = svg.length(svg.Width)
+		vh, _ = svg.length(svg.Height)
+	}
+	if s := svg.scale; s > 0 {
+		vx, vy, vw, vh = vx*s, vy*s, vw*s, vh*s
+	}
+
+	t := mt.Identity()
+	if vw <= 0 || vh <= 0 {
+		return t
+	}
+	scale := math.Min(w/vw, h/vh)
+	t.Translate((w-vw*scale)/2, (h-vh*scale)/2)
+	t.Scale(scale, scale)
+	t.Translate(-vx, -vy)
+	return t
+}
+
+// rasterizer is a Renderer that draws into an image through a stack of
+// layers. A new layer is only allocated for groups that are not fully
+// opaque.
+type rasterizer struct {
+	target    draw.Image
+	view      mt.Transform
+	transform mt.Transform
+	layers    []layer
+	path      []*DrawingInstruction
+	tolerance float64
+}
+
+type layer struct {
+	parent  draw.Image
+	opacity float64
+}
+
+// SetTransform implements the Renderer interface
+func (r *rasterizer) SetTransform(t mt.Transform) {
+	r.transform = mt.MultiplyTransforms(r.view, t)
+}
+
+// PushGroup implements the Renderer interface
+func (r *rasterizer) PushGroup(opacity float64, clip []Segment) {
+	l := layer{parent: r.target, opacity: opacity}
+	if opacity < 1 {
+		r.target = image.NewRGBA(r.target.Bounds())
+	}
+	r.layers = append(r.layers, l)
+}
+
+// PopGroup implements the Renderer interface
+func (r *rasterizer) PopGroup() {
+	l := r.layers[len(r.layers)-1]
+	r.layers = r.layers[:len(r.layers)-1]
+	if l.parent == r.target {
+		return
+	}
+	mask := image.NewUniform(color.Alpha{A: uint8(l.opacity*255 + 0.5)})
+	draw.DrawMask(l.parent, l.parent.Bounds(), r.target, r.target.Bounds().Min, mask, image.Point{}, draw.Over)
+	r.target = l.parent
+}
+
+// MoveTo implements the Renderer interface
+func (r *rasterizer) MoveTo(x, y float64) {
+	r.path = append(r.path, &DrawingInstruction{Kind: MoveInstruction, M: &Tuple{x, y}})
+}
+
+// LineTo implements the Renderer interface
+func (r *rasterizer) LineTo(x, y float64) {
+	r.path = append(r.path, &DrawingInstruction{Kind: LineInstruction, M: &Tuple{x, y}})
+}
+
+// CubicTo implements the Renderer interface
+func (r *rasterizer) CubicTo(c1x, c1y, c2x, c2y, x, y float64) {
+	r.path = append(r.path, &DrawingInstruction{
+		Kind:        CurveInstruction,
+		CurvePoints: &CurvePoints{C1: &Tuple{c1x, c1y}, C2: &Tuple{c2x, c2y}, T: &Tuple{x, y}},
+	})
+}
+
+// ClosePath implements the Renderer interface
+func (r *rasterizer) ClosePath() {
+	r.path = append(r.path, &DrawingInstruction{Kind: CloseInstruction})
+}
+
+// Fill implements the Renderer interface
+func (r *rasterizer) Fill(style PaintStyle) {
+	segments := flattenInstructions(r.path, r.tolerance)
+	r.fill(segments, style.FillRule, r.source(style, segments))
+	r.path = nil
+}
+
+// Stroke implements the Renderer interface
+func (r *rasterizer) Stroke(style PaintStyle) {
+	segments := flattenInstructions(r.path, r.tolerance)
+	r.fill(StrokeSegments(segments, style.Stroke), "nonzero", r.source(style, segments))
+	r.path = nil
+}
+
+// source returns the image the area painted with style is filled from,
+// or nil if nothing is painted. Geometry is the path, in user space,
+// whose bounding box objectBoundingBox gradients are relative to.
+func (r *rasterizer) source(style PaintStyle, geometry []Segment) image.Image {
+	if style.Opacity <= 0 {
+		return nil
+	}
+	g := style.Gradient
+	if g == nil {
+		c := style.Color
+		c.A = uint8(float64(c.A)*style.Opacity + 0.5)
+		return image.NewUniform(c)
+	}
+
+	t := r.transform
+	if g.Units == "objectBoundingBox" {
+		min, max := [2]float64{math.Inf(1), math.Inf(1)}, [2]float64{math.Inf(-1), math.Inf(-1)}
+		for _, s := range geometry {
+			for _, p := range s.Points {
+				min = [2]float64{math.Min(min[0], p[0]), math.Min(min[1], p[1])}
+				max = [2]float64{math.Max(max[0], p[0]), math.Max(max[1], p[1])}
+			}
+		}
+		// a gradient relative to an empty bounding box paints nothing
+		if !(max[0] > min[0] && max[1] > min[1]) {
+			return nil
+		}
+		t.Translate(min[0], min[1])
+		t.Scale(max[0]-min[0], max[1]-min[1])
+	}
+	inverse, ok := invertTransform(mt.MultiplyTransforms(t, g.Transform))
+	if !ok {
+		return nil
+	}
+	return &gradientImage{gradient: g, inverse: inverse, opacity: style.Opacity}
+}
+
+// gradientImage is an infinite image of a gradient in device space.
+type gradientImage struct {
+	gradient *Gradient
+	// inverse maps device space to the coordinates of the gradient
+	inverse mt.Transform
+	opacity float64
+}
+
+func (gi *gradientImage) ColorModel() color.Model {
+	return color.RGBAModel
+}
+
+func (gi *gradientImage) Bounds() image.Rectangle {
+	return image.Rect(-1e9, -1e9, 1e9, 1e9)
+}
+
+func (gi *gradientImage) At(x, y int) color.Color {
+	gx, gy := gi.inverse.Apply(float64(x)+0.5, float64(y)+0.5)
+	c := gi.gradient.color(gx, gy)
+	if gi.opacity < 1 {
+		scale := func(v uint8) uint8 { return uint8(float64(v)*gi.opacity + 0.5) }
+		c = color.RGBA{scale(c.R), scale(c.G), scale(c.B), scale(c.A)}
+	}
+	return c
+}
+
+// fill paints the area enclosed by segments, given in user space, with
+// src.
+func (r *rasterizer) fill(segments []Segment, fillRule string, src image.Image) {
+	if src == nil || len(segments) == 0 {
+		return
+	}
+	device := make([]Segment, len(segments))
+	for i, s := range segments {
+		device[i].Points = make([][2]float64, len(s.Points))
+		for j, p := range s.Points {
+			x, y := r.transform.Apply(p[0], p[1])
+			device[i].Points[j] = [2]float64{x, y}
+		}
+	}
+
+	mask := rasterize(device, fillRule, r.target.Bounds())
+	if mask == nil {
+		return
+	}
+	draw.DrawMask(r.target, mask.Rect, src, mask.Rect.Min, mask, mask.Rect.Min, draw.Over)
+}
+
+type rasterEdge struct {
+	x0, y0, x1, y1 float64
+	dir            int
+}
+
+// rasterize computes the anti-aliased coverage of the area enclosed by
+// segments, given in device space and implicitly closed, clipped to
+// bounds. It returns nil if nothing is covered.
+func rasterize(segments []Segment, fillRule string, bounds image.Rectangle) *image.Alpha {
+	var edges []rasterEdge
+	minY, maxY := math.Inf(1), math.Inf(-1)
+	for _, s := range segments {
+		n := len(s.Points)
+		for i := 0; i < n && n > 1; i++ {
+			a, b := s.Points[i], s.Points[(i+1)%n]
+			if a[1] == b[1] {
+				continue
+			}
+			e := rasterEdge{a[0], a[1], b[0], b[1], 1}
+			if a[1] > b[1] {
+				e = rasterEdge{b[0], b[1], a[0], a[1], -1}
+			}
+			edges = append(edges, e)
+			minY, maxY = math.Min(minY, e.y0), math.Max(maxY, e.y1)
+		}
+	}
+	if len(edges) == 0 {
+		return nil
+	}
+	sort.Slice(edges, func(i, j int) bool { return edges[i].y0 < edges[j].y0 })
+
+	y0 := maxInt(bounds.Min.Y, int(math.Floor(minY)))
+	y1 := minInt(bounds.Max.Y, int(math.Ceil(maxY)))
+	if y0 >= y1 {
+		return nil
+	}
+	mask := image.NewAlpha(image.Rect(bounds.Min.X, y0, bounds.Max.X, y1))
+	width := bounds.Dx()
+	coverage := make([]float64, width+1)
+
+	type crossing struct {
+		x   float64
+		dir int
+	}
+	var (
+		active    []rasterEdge
+		crossings []crossing
+		next      int
+	)
+	for y := y0; y < y1; y++ {
+		for next < len(edges) && edges[next].y0 < float64(y+1) {
+			active = append(active, edges[next])
+			next++
+		}
+		kept := active[:0]
+		for _, e := range active {
+			if e.y1 > float64(y) {
+				kept = append(kept, e)
+			}
+		}
+		active = kept
+
+		for i := range coverage {
+			coverage[i] = 0
+		}
+		for s := 0; s < subScanlines; s++ {
+			sy := float64(y) + (float64(s)+0.5)/subScanlines
+			crossings = crossings[:0]
+			for _, e := range active {
+				if sy < e.y0 || sy >= e.y1 {
+					continue
+				}
+				x := e.x0 + (sy-e.y0)*(e.x1-e.x0)/(e.y1-e.y0)
+				crossings = append(crossings, crossing{x - float64(bounds.Min.X), e.dir})
+			}
+			sort.Slice(crossings, func(i, j int) bool { return crossings[i].x < crossings[j].x })
+
+			winding := 0
+			for i, c := range crossings {
+				winding += c.dir
+				if i+1 < len(crossings) && insideFill(winding, fillRule) {
+					addSpan(coverage, c.x, crossings[i+1].x, 1.0/subScanlines)
+				}
+			}
+		}
+
+		row := mask.Pix[(y-y0)*mask.Stride:]
+		for x := 0; x < width; x++ {
+			row[x] = uint8(math.Min(coverage[x], 1)*255 + 0.5)
+		}
+	}
+	return mask
+}
+
+// addSpan adds weight times the covered fraction of every pixel between
+// x0 and x1 to coverage.
+func addSpan(coverage []float64, x0, x1, weight float64) {
+	width := float64(len(coverage) - 1)
+	x0, x1 = math.Max(x0, 0), math.Min(x1, width)
+	if x0 >= x1 {
+		return
+	}
+	i0, i1 := int(x0), int(x1)
+	if i0 == i1 {
+		coverage[i0] += (x1 - x0) * weight
+		return
+	}
+	coverage[i0] += (float64(i0+1) - x0) * weight
+	for i := i0 + 1; i < i1; i++ {
+		coverage[i] += weight
+	}
+	coverage[i1] += (x1 - float64(i1)) * weight
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
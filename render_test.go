@@ -0,0 +1,116 @@
+package svg
+
+import (
+	"flag"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+var update = flag.Bool("update", false, "update golden files")
+
+const renderSvg = `<svg width="200" height="100" viewBox="0 0 100 50">
+<path d="M5 5 L45 5 L45 45 L5 45 Z M15 15 L35 15 L35 35 L15 35 Z" fill="#009FE3" fill-rule="evenodd"/>
+<path d="M5 5 L45 5 L45 45 L5 45 Z M15 15 L35 15 L35 35 L15 35 Z" fill="none" stroke="navy" stroke-width="2" stroke-linejoin="round"/>
+<g opacity="0.5">
+	<path d="M55 5 L95 5 L95 45 L55 45 Z" fill="red"/>
+	<path d="M65 15 L85 15 L85 35 L65 35 Z" fill="rgb(0, 0, 255)"/>
+</g>
+<path d="M55 48 L95 48" stroke="black" stroke-width="2" stroke-linecap="round" fill="none" stroke-opacity="0.8"/>
+<circle cx="75" cy="25" r="5" fill="yellow"/>
+</svg>`
+
+// requireGolden compares img against a PNG in testdata, allowing small
+// per channel differences caused by floating point rounding.
+func requireGolden(t *testing.T, name string, img *image.RGBA) {
+	path := filepath.Join("testdata", name)
+	if *update {
+		f, err := os.Create(path)
+		require.NoError(t, err)
+		require.NoError(t, png.Encode(f, img))
+		require.NoError(t, f.Close())
+	}
+
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer f.Close()
+	golden, err := png.Decode(f)
+	require.NoError(t, err)
+	require.Equal(t, img.Bounds(), golden.Bounds())
+
+	for y := img.Rect.Min.Y; y < img.Rect.Max.Y; y++ {
+		for x := img.Rect.Min.X; x < img.Rect.Max.X; x++ {
+			got := img.RGBAAt(x, y)
+			want := color.RGBAModel.Convert(golden.At(x, y)).(color.RGBA)
+			for _, d := range []int{
+				int(got.R) - int(want.R), int(got.G) - int(want.G),
+				int(got.B) - int(want.B), int(got.A) - int(want.A),
+			} {
+				if d < -2 || d > 2 {
+					t.Fatalf("%s: pixel %d,%d is %v, want %v", name, x, y, got, want)
+				}
+			}
+		}
+	}
+}
+
+func TestRender(t *testing.T) {
+	svg, err := ParseSvg(renderSvg, "test", 0)
+	require.NoError(t, err)
+
+	img := image.NewRGBA(image.Rect(0, 0, 200, 100))
+	require.NoError(t, Render(svg, img, &RenderOptions{Background: color.White}))
+	requireGolden(t, "render.png", img)
+
+	// spot checks that do not depend on the golden file
+	require.Equal(t, color.RGBA{255, 255, 255, 255}, img.RGBAAt(50, 50), "evenodd hole")
+	require.Equal(t, color.RGBA{0, 0x9f, 0xe3, 255}, img.RGBAAt(20, 20), "fill")
+	require.Equal(t, color.RGBA{255, 127, 127, 255}, img.RGBAAt(115, 15), "group opacity")
+}
+
+func TestRenderScale(t *testing.T) {
+	// the viewport is fitted to the image, so the parse scale must not
+	// change the result
+	doc := `<svg width="100" height="100"><path d="M10 50 L90 50" stroke="black" stroke-width="4" stroke-dasharray="10 10" fill="none"/></svg>`
+	dark := func(scale float64) int {
+		svg, err := ParseSvg(doc, "test", scale)
+		require.NoError(t, err)
+		img := image.NewRGBA(image.Rect(0, 0, 100, 100))
+		require.NoError(t, Render(svg, img, &RenderOptions{Background: color.White}))
+		n := 0
+		for i := 0; i < len(img.Pix); i += 4 {
+			if img.Pix[i] < 128 {
+				n++
+			}
+		}
+		return n
+	}
+	n := dark(1)
+	require.InDelta(t, 160, n, 10)
+	require.Equal(t, n, dark(2))
+	require.Equal(t, n, dark(-2))
+}
+
+func TestViewportTransform(t *testing.T) {
+	// the viewBox is scaled like the content Replay draws
+	for _, scale := range []float64{0, 1, 2} {
+		svg, err := ParseSvg(`<svg width="200" height="100" viewBox="10 10 20 10"/>`, "test", scale)
+		require.NoError(t, err)
+		s := scale
+		if s == 0 {
+			s = 1
+		}
+		v := viewportTransform(svg, 200, 100)
+		x, y := v.Apply(10*s, 10*s)
+		require.InDelta(t, 0, x, 1e-9)
+		require.InDelta(t, 0, y, 1e-9)
+		x, y = v.Apply(30*s, 20*s)
+		require.InDelta(t, 200, x, 1e-9)
+		require.InDelta(t, 100, y, 1e-9)
+	}
+}
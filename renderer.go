@@ -0,0 +1,247 @@
+package svg
+
+import (
+	"fmt"
+	"image/color"
+
+	mt "github.com/rustyoz/Mtransform"
+)
+
+// Renderer is a drawing backend that a parsed document can be replayed
+// into with Replay. Coordinates are given in document space; the
+// transform passed to SetTransform maps them to the backend's space.
+//
+// Path commands build the current path, which is consumed by the next
+// call to Fill or Stroke. When an element is both filled and stroked,
+// its path is replayed before each of the two calls.
+type Renderer interface {
+	SetTransform(t mt.Transform)
+	PushGroup(opacity float64, clip []Segment)
+	PopGroup()
+	MoveTo(x, y float64)
+	LineTo(x, y float64)
+	CubicTo(c1x, c1y, c2x, c2y, x, y float64)
+	ClosePath()
+	Fill(style PaintStyle)
+	Stroke(style PaintStyle)
+}
+
+// TextRenderer is implemented by Renderers that draw text. Replay passes
+// every run of a filled text element to Text; Renderers that do not
+// implement it skip text.
+type TextRenderer interface {
+	Text(run TextRun, style PaintStyle)
+}
+
+// PaintStyle describes how the current path is filled or stroked.
+type PaintStyle struct {
+	// Paint is the fill or stroke value as written in the document.
+	Paint string
+	// Color is the parsed paint colour. For a gradient it is the colour
+	// of the first stop, for Renderers that cannot paint gradients.
+	Color color.NRGBA
+	// Gradient is the gradient the paint refers to, if any. Renderers
+	// that paint it use the bounding box of the current path for
+	// objectBoundingBox units.
+	Gradient *Gradient
+	// Opacity combines the element opacity with fill-opacity or
+	// stroke-opacity.
+	Opacity  float64
+	FillRule string
+	Stroke   StrokeStyle
+}
+
+// Replay walks the document in paint order and issues the matching
+// calls on r. The document's Transform, which carries the scale given
+// to ParseSvg, is passed to SetTransform first. Groups are bracketed by
+// PushGroup and PopGroup; clip paths are not supported yet, so clip is
+// always nil. Hidden elements and paints that are neither colours nor
+// gradients are skipped.
+func Replay(svg *Svg, r Renderer) error {
+	t := mt.Identity()
+	if svg.Transform != nil {
+		t = *svg.Transform
+	}
+	r.SetTransform(t)
+	return replayElements(svg.children(), nil, svg.FlattenTolerance, svg.pointLimit(), r)
+}
+
+func replayElements(elements []DrawingInstructionParser, g *Group, tolerance float64, limit *pointLimit, r Renderer) error {
+	for _, e := range elements {
+		if u, ok := e.(*Use); ok {
+			if u.ref == nil || u.Display == "none" {
+				continue
+			}
+			if err := replayElements([]DrawingInstructionParser{u.ref}, u.scope, tolerance, limit, r); err != nil {
+				return err
+			}
+			continue
+		}
+		if group, ok := e.(*Group); ok {
+			if group.Display == "none" {
+				continue
+			}
+			opacity := 1.0
+			if group.Opacity != nil {
+				opacity = clampUnit(*group.Opacity)
+			}
+			r.PushGroup(opacity, nil)
+			err := replayElements(group.Elements, group, tolerance, limit, r)
+			r.PopGroup()
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		shapes, err := appendShapes(nil, e, g, tolerance, limit)
+		if err != nil {
+			return err
+		}
+		for _, s := range shapes {
+			replayShape(s, r)
+		}
+	}
+	return nil
+}
+
+func replayShape(s Shape, r Renderer) {
+	if s.Hidden {
+		return
+	}
+	if s.isText() {
+		tr, ok := r.(TextRenderer)
+		if !ok || !s.Filled() {
+			return
+		}
+		if style, ok := paintStyle(s.Fill, s.FillGradient); ok {
+			style.Opacity = s.Opacity * s.FillOpacity
+			for _, di := range s.Instructions {
+				if di.Kind == TextInstruction {
+					tr.Text(*di.Text, style)
+				}
+			}
+		}
+		return
+	}
+	if s.Filled() {
+		if style, ok := paintStyle(s.Fill, s.FillGradient); ok {
+			style.Opacity, style.FillRule = s.Opacity*s.FillOpacity, s.FillRule
+			replayPath(s.Instructions, r)
+			r.Fill(style)
+		}
+	}
+	if s.Stroked() {
+		if style, ok := paintStyle(s.Stroke, s.StrokeGradient); ok {
+			style.Opacity, style.Stroke = s.Opacity*s.StrokeOpacity, s.StrokeStyle
+			replayPath(s.Instructions, r)
+			r.Stroke(style)
+		}
+	}
+}
+
+// paintStyle returns the style of the paint value paint, which refers
+// to the gradient g if it is not nil. It reports false if the paint
+// cannot be drawn.
+func paintStyle(paint string, g *Gradient) (PaintStyle, bool) {
+	if g != nil {
+		if len(g.Stops) == 0 {
+			return PaintStyle{}, false
+		}
+		return PaintStyle{Paint: paint, Color: g.Stops[0].Color, Gradient: g}, true
+	}
+	c, ok := ParseColor(paint)
+	return PaintStyle{Paint: paint, Color: c}, ok
+}
+
+// replayPath issues the path commands for instrs. Circles are replayed
+// as four cubic curves.
+func replayPath(instrs []*DrawingInstruction, r Renderer) {
+	for _, di := range expandCircles(instrs) {
+		switch di.Kind {
+		case MoveInstruction:
+			r.MoveTo(di.M[0], di.M[1])
+		case LineInstruction:
+			r.LineTo(di.M[0], di.M[1])
+		case CurveInstruction:
+			cp := di.CurvePoints
+			r.CubicTo(cp.C1[0], cp.C1[1], cp.C2[0], cp.C2[1], cp.T[0], cp.T[1])
+		case CloseInstruction:
+			r.ClosePath()
+		}
+	}
+}
+
+// RenderCall is a single call recorded by RecordingRenderer.
+type RenderCall struct {
+	Method string
+	Args   []float64
+	Style  PaintStyle
+	Clip   []Segment
+	// Text is the run of a Text call.
+	Text *TextRun
+}
+
+func (c RenderCall) String() string {
+	return fmt.Sprintf("%s%v", c.Method, c.Args)
+}
+
+// RecordingRenderer is a Renderer that records every call, for use in
+// tests.
+type RecordingRenderer struct {
+	Calls []RenderCall
+}
+
+func (r *RecordingRenderer) record(c RenderCall) {
+	r.Calls = append(r.Calls, c)
+}
+
+// SetTransform implements the Renderer interface
+func (r *RecordingRenderer) SetTransform(t mt.Transform) {
+	r.record(RenderCall{Method: "SetTransform", Args: []float64{t[0][0], t[1][0], t[0][1], t[1][1], t[0][2], t[1][2]}})
+}
+
+// PushGroup implements the Renderer interface
+func (r *RecordingRenderer) PushGroup(opacity float64, clip []Segment) {
+	r.record(RenderCall{Method: "PushGroup", Args: []float64{opacity}, Clip: clip})
+}
+
+// PopGroup implements the Renderer interface
+func (r *RecordingRenderer) PopGroup() {
+	r.record(RenderCall{Method: "PopGroup"})
+}
+
+// MoveTo implements the Renderer interface
+func (r *RecordingRenderer) MoveTo(x, y float64) {
+	r.record(RenderCall{Method: "MoveTo", Args: []float64{x, y}})
+}
+
+// LineTo implements the Renderer interface
+func (r *RecordingRenderer) LineTo(x, y float64) {
+	r.record(RenderCall{Method: "LineTo", Args: []float64{x, y}})
+}
+
+// CubicTo implements the Renderer interface
+func (r *RecordingRenderer) CubicTo(c1x, c1y, c2x, c2y, x, y float64) {
+	r.record(RenderCall{Method: "CubicTo", Args: []float64{c1x, c1y, c2x, c2y, x, y}})
+}
+
+// ClosePath implements the Renderer interface
+func (r *RecordingRenderer) ClosePath() {
+	r.record(RenderCall{Method: "ClosePath"})
+}
+
+// Fill implements the Renderer interface
+func (r *RecordingRenderer) Fill(style PaintStyle) {
+	r.record(RenderCall{Method: "Fill", Style: style})
+}
+
+// Stroke implements the Renderer interface
+func (r *RecordingRenderer) Stroke(style PaintStyle) {
+	r.record(RenderCall{Method: "Stroke", Style: style})
+}
+
+// Text implements the TextRenderer interface
+func (r *RecordingRenderer) Text(run TextRun, style PaintStyle) {
+	r.record(RenderCall{Method: "Text", Args: []float64{run.X, run.Y}, Style: style, Text: &run})
+}
@@ -0,0 +1,42 @@
+package svg
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestReplay(t *testing.T) {
+	svg, err := ParseSvg(`<svg>
+<path d="M0 0 L10 0 L10 10 Z" fill="red" stroke="blue" stroke-width="2"/>
+<g opacity="0.5"><path d="M0 0 C1 1 2 2 3 3" fill="none" stroke="#000"/></g>
+<g display="none"><path d="M0 0 L1 1"/></g>
+</svg>`, "test", 0)
+	require.NoError(t, err)
+
+	r := &RecordingRenderer{}
+	require.NoError(t, Replay(svg, r))
+
+	var calls []string
+	for _, c := range r.Calls {
+		calls = append(calls, c.String())
+	}
+	require.Equal(t, []string{
+		"SetTransform[1 0 0 1 0 0]",
+		"MoveTo[0 0]", "LineTo[10 0]", "LineTo[10 10]", "ClosePath[]", "Fill[]",
+		"MoveTo[0 0]", "LineTo[10 0]", "LineTo[10 10]", "ClosePath[]", "Stroke[]",
+		"PushGroup[0.5]",
+		"MoveTo[0 0]", "CubicTo[1 1 2 2 3 3]", "Stroke[]",
+		"PopGroup[]",
+	}, calls)
+
+	fill := r.Calls[5].Style
+	require.Equal(t, "red", fill.Paint)
+	require.Equal(t, uint8(255), fill.Color.R)
+	require.Equal(t, "nonzero", fill.FillRule)
+	require.Equal(t, 1.0, fill.Opacity)
+
+	stroke := r.Calls[10].Style
+	require.Equal(t, "blue", stroke.Paint)
+	require.Equal(t, 2.0, stroke.Stroke.Width)
+}
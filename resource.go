@@ -0,0 +1,98 @@
+package svg
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// ErrResourceDenied is returned for references to external resources
+// that the loader does not allow. Documents parsed without a loader deny
+// every external reference.
+var ErrResourceDenied = errors.New("external resource denied")
+
+// ErrImportUnsupported is reported for the @import rules of style
+// elements. Style sheets are not applied, so the style sheets they
+// import are not loaded either, even with a loader.
+var ErrImportUnsupported = errors.New("style sheet @import is not supported")
+
+// importRule matches an @import rule and captures its URL.
+var importRule = regexp.MustCompile(`@import\s+(?:url\(\s*)?["']?([^"')\s;]*)`)
+
+// decodeStyle reads a style element and reports each of its @import
+// rules as unsupported.
+func (s *Svg) decodeStyle(decoder *xml.Decoder, start xml.StartElement, pos position) error {
+	var sheet struct {
+		Text string `xml:",chardata"`
+	}
+	id := attrValue(start, "id")
+	if err := decoder.DecodeElement(&sheet, &start); err != nil {
+		return pos.error("style", id, -1, err)
+	}
+	for _, m := range importRule.FindAllStringSubmatch(sheet.Text, -1) {
+		if err := s.warn(pos, "style", id, -1, fmt.Errorf("%w: %q", ErrImportUnsupported, m[1])); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// FSLoader returns a ResourceLoader that opens the files of fsys. Hrefs
+// are resolved relative to dir, the directory of the document in fsys.
+// URLs, absolute paths and paths that leave fsys are denied.
+func FSLoader(fsys fs.FS, dir string) ResourceLoader {
+	return &fsLoader{fsys: fsys, dir: dir}
+}
+
+type fsLoader struct {
+	fsys fs.FS
+	dir  string
+}
+
+// Open implements the ResourceLoader interface
+func (l *fsLoader) Open(href string) (io.ReadCloser, error) {
+	if isExternalURL(href) || strings.HasPrefix(href, "/") {
+		return nil, fmt.Errorf("%w: %q", ErrResourceDenied, href)
+	}
+	name, err := url.PathUnescape(href)
+	if err != nil {
+		return nil, fmt.Errorf("invalid href %q: %w", href, err)
+	}
+	name = path.Join(l.dir, name)
+	if !fs.ValidPath(name) {
+		return nil, fmt.Errorf("%w: %q is outside the file system", ErrResourceDenied, href)
+	}
+	return l.fsys.Open(name)
+}
+
+// isExternalURL reports whether href starts with a URL scheme, like
+// "http:" or "data:".
+func isExternalURL(href string) bool {
+	i := strings.IndexAny(href, ":/?#")
+	return i > 0 && href[i] == ':'
+}
+
+// resolveHref returns the href of the resource href refers to from the
+// document at base, where both are relative to the document being
+// parsed.
+func resolveHref(base, href string) string {
+	if isExternalURL(href) || strings.HasPrefix(href, "/") {
+		return href
+	}
+	return path.Join(path.Dir(base), href)
+}
+
+// open opens the resource href, relative to the document, with the
+// loader of the document.
+func (s *Svg) open(href string) (io.ReadCloser, error) {
+	if s.options.Loader == nil {
+		return nil, fmt.Errorf("%w: %q", ErrResourceDenied, href)
+	}
+	return s.options.Loader.Open(resolveHref(s.href, href))
+}
@@ -0,0 +1,119 @@
+package svg
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/require"
+)
+
+// countingFS counts the files opened in an fs.FS.
+type countingFS struct {
+	fs.FS
+	opened map[string]int
+}
+
+func (c *countingFS) Open(name string) (fs.File, error) {
+	c.opened[name]++
+	return c.FS.Open(name)
+}
+
+func TestExternalUse(t *testing.T) {
+	fsys := &countingFS{opened: make(map[string]int), FS: fstest.MapFS{
+		"doc/icons/icons.svg": {Data: []byte(`<svg>
+<path id="gear" d="M0 0 L1 0" stroke="black"/>
+<g id="pair"><use href="#gear"/><use href="../shapes.svg#dot" x="5"/></g>
+<use id="loop" href="../shapes.svg#back"/>
+</svg>`)},
+		"doc/shapes.svg": {Data: []byte(`<svg>
+<path id="dot" d="M0 0 L0 1"/>
+<use id="back" href="icons/icons.svg#loop"/>
+</svg>`)},
+		"doc/photo.png": {Data: []byte("png")},
+		"secret.svg":    {Data: []byte(`<svg><path id="p" d="M0 0 L1 1"/></svg>`)},
+	}}
+
+	doc := `<svg>
+<use href="icons/icons.svg#gear" x="10"/>
+<use href="icons/icons.svg#pair" y="10"/>
+<use href="icons/icons.svg#loop"/>
+<use href="../../secret.svg#p"/>
+<use href="http://example.com/icons.svg#gear"/>
+<image id="photo" href="photo.png"/>
+</svg>`
+	svg, err := ParseSvgWithOptions(strings.NewReader(doc), NewOptions(WithLoader(FSLoader(fsys, "doc"))))
+	require.NoError(t, err)
+	require.Equal(t, 1, fsys.opened["doc/icons/icons.svg"])
+	require.Equal(t, 1, fsys.opened["doc/shapes.svg"])
+	require.Zero(t, fsys.opened["secret.svg"])
+
+	shapes, err := svg.Shapes()
+	require.NoError(t, err)
+	var points [][][2]float64
+	for _, s := range shapes {
+		points = append(points, s.Segments[0].Points)
+	}
+	require.Equal(t, [][][2]float64{
+		{{10, 0}, {11, 0}},
+		{{0, 10}, {1, 10}},
+		{{5, 10}, {5, 11}},
+	}, points)
+	require.Equal(t, "black", shapes[0].Stroke)
+
+	require.Len(t, svg.Warnings, 3)
+	require.Equal(t, "shapes.svg", svg.Warnings[0].Document)
+	require.Contains(t, svg.Warnings[0].Error(), `circular reference to "icons/icons.svg#loop"`)
+	require.True(t, errors.Is(&svg.Warnings[1], ErrResourceDenied), svg.Warnings[1].String())
+	require.True(t, errors.Is(&svg.Warnings[2], ErrResourceDenied), svg.Warnings[2].String())
+
+	image := svg.Elements[len(svg.Elements)-1].(*Image)
+	f, err := image.Open()
+	require.NoError(t, err)
+	data, err := io.ReadAll(f)
+	f.Close()
+	require.NoError(t, err)
+	require.Equal(t, "png", string(data))
+
+	// without a loader every external reference is denied
+	svg, err = ParseSvgWithOptions(strings.NewReader(doc), NewOptions(WithStrict(true)))
+	require.True(t, errors.Is(err, ErrResourceDenied), "%v", err)
+}
+
+func TestExternalUseLimits(t *testing.T) {
+	big := "<svg>" + strings.Repeat(`<path d="M0 0 L1 1"/>`, 10) + "</svg>"
+	fsys := fstest.MapFS{"big.svg": {Data: []byte(big)}}
+	doc := `<svg><use href="big.svg"/></svg>`
+	parse := func(limits Limits) error {
+		_, err := ParseSvgWithOptions(strings.NewReader(doc), NewOptions(WithLoader(FSLoader(fsys, ".")), WithLimits(limits)))
+		return err
+	}
+	require.NoError(t, parse(Limits{MaxElements: 22, MaxBytes: len(doc) + len(big)}))
+
+	// the loaded document and the copies of its elements count towards
+	// the limits of the document that uses them
+	for _, limits := range []Limits{{MaxElements: 21}, {MaxBytes: len(doc) + len(big) - 1}} {
+		var le *LimitError
+		require.True(t, errors.As(parse(limits), &le), "%+v", limits)
+	}
+}
+
+func TestStyleImport(t *testing.T) {
+	fsys := fstest.MapFS{"a.css": {Data: []byte("path { fill: red }")}}
+	doc := `<svg><style>@import url("a.css"); @import 'b.css';</style><g><style id="s">@import url(c.css)</style></g></svg>`
+
+	svg, err := ParseSvgWithOptions(strings.NewReader(doc), NewOptions(WithLoader(FSLoader(fsys, "."))))
+	require.NoError(t, err)
+	require.Len(t, svg.Warnings, 3)
+	for i, href := range []string{"a.css", "b.css", "c.css"} {
+		require.True(t, errors.Is(&svg.Warnings[i], ErrImportUnsupported))
+		require.Contains(t, svg.Warnings[i].Error(), `"`+href+`"`)
+	}
+	require.Equal(t, "s", svg.Warnings[2].ID)
+
+	_, err = ParseSvgWithOptions(strings.NewReader(doc), NewOptions(WithStrict(true)))
+	require.True(t, errors.Is(err, ErrImportUnsupported), "%v", err)
+}
@@ -0,0 +1,279 @@
+package svg
+
+// Shape is a single painted element flattened to world space segments
+// together with the paint that applies to it.
+type Shape struct {
+	ID      string
+	GroupID string
+	// Layer is the name of the innermost Inkscape layer containing the
+	// shape.
+	Layer        string
+	Element      DrawingInstructionParser
+	Instructions []*DrawingInstruction
+	Segments     []Segment
+	Fill         string
+	FillRule     string
+	Stroke       string
+	StrokeStyle  StrokeStyle
+	Hidden       bool
+	// FillGradient and StrokeGradient are the gradients Fill and Stroke
+	// refer to, if any.
+	FillGradient   *Gradient
+	StrokeGradient *Gradient
+
+	// tolerance is the flattening tolerance the segments were made with
+	tolerance float64
+
+	// Opacity, FillOpacity and StrokeOpacity are the element's own
+	// opacities in the range 0 to 1. Group opacity is not included.
+	Opacity       float64
+	FillOpacity   float64
+	StrokeOpacity float64
+}
+
+// Filled reports whether the interior of the shape is painted.
+func (s Shape) Filled() bool {
+	return s.Fill != "none"
+}
+
+// isText reports whether the shape is text that was not converted to
+// outlines, whose runs are not part of its segments.
+func (s Shape) isText() bool {
+	for _, di := range s.Instructions {
+		if di.Kind == TextInstruction {
+			return true
+		}
+	}
+	return false
+}
+
+// Stroked reports whether the outline of the shape is painted.
+func (s Shape) Stroked() bool {
+	return s.Stroke != "" && s.Stroke != "none" && s.StrokeStyle.Width > 0
+}
+
+// Shapes flattens every element of the document into a Shape, in paint
+// order.
+func (s *Svg) Shapes() ([]Shape, error) {
+	var shapes []Shape
+	limit := s.pointLimit()
+	for _, e := range s.children() {
+		var err error
+		if shapes, err = appendShapes(shapes, e, nil, s.FlattenTolerance, limit); err != nil {
+			return nil, err
+		}
+	}
+	return shapes, nil
+}
+
+// appendShapes appends the shapes of e, counting their points against
+// limit.
+func appendShapes(shapes []Shape, e DrawingInstructionParser, g *Group, tolerance float64, limit *pointLimit) ([]Shape, error) {
+	if group, ok := e.(*Group); ok {
+		for _, child := range group.Elements {
+			var err error
+			if shapes, err = appendShapes(shapes, child, group, tolerance, limit); err != nil {
+				return nil, err
+			}
+		}
+		return shapes, nil
+	}
+	if u, ok := e.(*Use); ok {
+		if u.ref == nil || u.Display == "none" {
+			return shapes, nil
+		}
+		return appendShapes(shapes, u.ref, u.scope, tolerance, limit)
+	}
+
+	instrs, err := collectInstructions(e)
+	if err != nil {
+		return nil, err
+	}
+	if len(instrs) == 0 {
+		return shapes, nil
+	}
+
+	segments, err := flattenLimited(instrs, tolerance, limit)
+	if err != nil {
+		return nil, err
+	}
+	shape := Shape{
+		ID:           elementID(e),
+		Element:      e,
+		Instructions: instrs,
+		Segments:     segments,
+		StrokeStyle:  StrokeStyle{MiterLimit: defaultMiterLimit},
+		tolerance:    tolerance,
+
+		Opacity:       1,
+		FillOpacity:   1,
+		StrokeOpacity: 1,
+	}
+	for _, di := range instrs {
+		if di.Kind != PaintInstruction {
+			continue
+		}
+		// paint instructions multiply stroke widths and dashes by the
+		// scale, which the segments leave out
+		shape.StrokeStyle = strokeStyleFromPaint(di).unscaled(elementScale(e))
+		if di.Fill != nil {
+			shape.Fill = *di.Fill
+		}
+		if di.FillRule != nil {
+			shape.FillRule = *di.FillRule
+		}
+		if di.Stroke != nil {
+			shape.Stroke = *di.Stroke
+		}
+		if di.FillGradient != nil {
+			shape.FillGradient = di.FillGradient
+		}
+		if di.StrokeGradient != nil {
+			shape.StrokeGradient = di.StrokeGradient
+		}
+		if di.Opacity != nil {
+			shape.Opacity = clampUnit(*di.Opacity)
+		}
+		if di.FillOpacity != nil {
+			shape.FillOpacity = clampUnit(*di.FillOpacity)
+		}
+		if di.StrokeOpacity != nil {
+			shape.StrokeOpacity = clampUnit(*di.StrokeOpacity)
+		}
+	}
+
+	display, visibility := elementVisibility(e)
+	shape.Hidden = display == "none"
+	var owner *Svg
+	if g != nil {
+		owner = g.Owner
+	}
+	for ; g != nil; g = g.Parent {
+		if g.Display == "none" {
+			shape.Hidden = true
+		}
+		if visibility == "" {
+			visibility = g.Visibility
+		}
+		if shape.GroupID == "" {
+			shape.GroupID = g.ID
+		}
+		if shape.Layer == "" && g.GroupMode == "layer" {
+			shape.Layer = g.Label
+		}
+		if shape.Fill == "" {
+			shape.Fill = g.Fill
+		}
+		if shape.FillRule == "" {
+			shape.FillRule = g.FillRule
+		}
+		if shape.Stroke == "" {
+			shape.Stroke = g.Stroke
+		}
+	}
+	if shape.FillGradient == nil {
+		shape.FillGradient = owner.gradient(shape.Fill)
+	}
+	if shape.StrokeGradient == nil {
+		shape.StrokeGradient = owner.gradient(shape.Stroke)
+	}
+	if shape.FillRule == "" {
+		shape.FillRule = "nonzero"
+	}
+	if visibility == "hidden" || visibility == "collapse" {
+		shape.Hidden = true
+	}
+
+	return append(shapes, shape), nil
+}
+
+// clampUnit clamps an opacity to the range 0 to 1.
+func clampUnit(v float64) float64 {
+	if v < 0 {
+		return 0
+	}
+	if v > 1 {
+		return 1
+	}
+	return v
+}
+
+// elementID returns the id attribute of an element.
+func elementID(e DrawingInstructionParser) string {
+	switch el := e.(type) {
+	case *Path:
+		return el.ID
+	case *Circle:
+		return el.ID
+	case *Rect:
+		return el.ID
+	case *Group:
+		return el.ID
+	case *Use:
+		return el.ID
+	case *Image:
+		return el.ID
+	case *Text:
+		return el.ID
+	}
+	return ""
+}
+
+// elementVisibility returns the display and visibility properties set
+// on an element.
+func elementVisibility(e DrawingInstructionParser) (display, visibility string) {
+	switch el := e.(type) {
+	case *Path:
+		return el.Display, el.Visibility
+	case *Circle:
+		return el.Display, el.Visibility
+	case *Rect:
+		return el.Display, el.Visibility
+	case *Text:
+		return el.Display, el.Visibility
+	}
+	return "", ""
+}
+
+// winding returns the winding number of the segment around (x, y). Open
+// segments are treated as implicitly closed, as they are when filled.
+func (s Segment) winding(x, y float64) int {
+	n := len(s.Points)
+	if n < 2 {
+		return 0
+	}
+
+	w := 0
+	for i := 0; i < n; i++ {
+		a, b := s.Points[i], s.Points[(i+1)%n]
+		if a[1] <= y {
+			if b[1] > y && cross(a, b, [2]float64{x, y}) > 0 {
+				w++
+			}
+		} else if b[1] <= y && cross(a, b, [2]float64{x, y}) < 0 {
+			w--
+		}
+	}
+	return w
+}
+
+// insideFill applies a fill rule to a winding number.
+func insideFill(winding int, fillRule string) bool {
+	if fillRule == "evenodd" {
+		return winding%2 != 0
+	}
+	return winding != 0
+}
+
+// cross returns the z component of (b-a) x (p-a).
+func cross(a, b, p [2]float64) float64 {
+	return (b[0]-a[0])*(p[1]-a[1]) - (p[0]-a[0])*(b[1]-a[1])
+}
+
+// elementScale returns the scale the document of e was parsed with.
+func elementScale(e DrawingInstructionParser) float64 {
+	if p, ok := e.(*Path); ok && p.group != nil && p.group.Owner != nil {
+		return p.group.Owner.scale
+	}
+	return 1
+}
@@ -0,0 +1,269 @@
+package svg
+
+import (
+	"container/heap"
+	"math"
+)
+
+// SimplifyMethod selects the algorithm used by Simplify.
+type SimplifyMethod int
+
+// These are the supported simplification algorithms
+const (
+	RamerDouglasPeucker SimplifyMethod = iota
+	VisvalingamWhyatt
+	CollinearMerge
+)
+
+// Simplify reduces the number of points of every segment using method
+// with the given tolerance in output units.
+func Simplify(segments []Segment, method SimplifyMethod, tolerance float64) []Segment {
+	result := make([]Segment, len(segments))
+	for i, s := range segments {
+		switch method {
+		case VisvalingamWhyatt:
+			result[i] = s.SimplifyVW(tolerance)
+		case CollinearMerge:
+			result[i] = s.MergeCollinear(tolerance)
+		default:
+			result[i] = s.SimplifyRDP(tolerance)
+		}
+	}
+	return result
+}
+
+// SimplifyRDP returns a copy of the segment simplified with the
+// Ramer–Douglas–Peucker algorithm: no removed point is further than
+// tolerance from the simplified polyline. The end points of open
+// segments are kept, closed segments stay closed.
+func (s Segment) SimplifyRDP(tolerance float64) Segment {
+	pts := s.Points
+	if len(pts) < 3 {
+		return s.withPoints(pts)
+	}
+
+	keep := make([]bool, len(pts))
+	keep[0], keep[len(pts)-1] = true, true
+	if s.Closed && samePoint(pts[0], pts[len(pts)-1]) {
+		// a ring has no natural end points, so split it at the vertex
+		// furthest from its start
+		far, dist := 0, -1.0
+		for i, p := range pts {
+			if d := math.Hypot(p[0]-pts[0][0], p[1]-pts[0][1]); d > dist {
+				far, dist = i, d
+			}
+		}
+		keep[far] = true
+		rdp(pts, 0, far, tolerance, keep)
+		rdp(pts, far, len(pts)-1, tolerance, keep)
+	} else {
+		rdp(pts, 0, len(pts)-1, tolerance, keep)
+	}
+
+	var out [][2]float64
+	for i, p := range pts {
+		if keep[i] {
+			out = append(out, p)
+		}
+	}
+	return s.withPoints(out)
+}
+
+func rdp(pts [][2]float64, first, last int, tolerance float64, keep []bool) {
+	if last-first < 2 {
+		return
+	}
+	index, dist := -1, tolerance
+	for i := first + 1; i < last; i++ {
+		if d := pointSegmentDistance(pts[i], pts[first], pts[last]); d > dist {
+			index, dist = i, d
+		}
+	}
+	if index < 0 {
+		return
+	}
+	keep[index] = true
+	rdp(pts, first, index, tolerance, keep)
+	rdp(pts, index, last, tolerance, keep)
+}
+
+// SimplifyVW returns a copy of the segment simplified with the
+// Visvalingam–Whyatt algorithm: points are removed in order of the
+// area of the triangle they form with their neighbours while that area
+// is below tolerance². The first point is always kept, as is the last
+// point of open segments.
+func (s Segment) SimplifyVW(tolerance float64) Segment {
+	pts, ring := s.ringPoints()
+	n := len(pts)
+	minPoints := 2
+	if ring {
+		minPoints = 3
+	}
+	if n <= minPoints {
+		return s
+	}
+
+	prev := make([]int, n)
+	next := make([]int, n)
+	for i := range pts {
+		prev[i], next[i] = i-1, i+1
+	}
+	if ring {
+		prev[0], next[n-1] = n-1, 0
+	}
+
+	h := &vwHeap{}
+	items := make([]*vwItem, n)
+	for i := range pts {
+		if (i == 0) || (!ring && i == n-1) {
+			continue
+		}
+		items[i] = &vwItem{index: i, area: triangleArea(pts[prev[i]], pts[i], pts[next[i]])}
+		heap.Push(h, items[i])
+	}
+
+	threshold := tolerance * tolerance
+	removed := make([]bool, n)
+	remaining := n
+	for h.Len() > 0 && remaining > minPoints {
+		it := heap.Pop(h).(*vwItem)
+		if it.area >= threshold {
+			break
+		}
+		removed[it.index] = true
+		remaining--
+		p, q := prev[it.index], next[it.index]
+		next[p], prev[q] = q, p
+		for _, j := range []int{p, q} {
+			if items[j] == nil || removed[j] {
+				continue
+			}
+			// effective areas never decrease, so that a point is not
+			// removed before the neighbours it protects
+			items[j].area = math.Max(it.area, triangleArea(pts[prev[j]], pts[j], pts[next[j]]))
+			heap.Fix(h, items[j].heapIndex)
+		}
+	}
+
+	var out [][2]float64
+	for i, p := range pts {
+		if !removed[i] {
+			out = append(out, p)
+		}
+	}
+	if ring {
+		out = append(out, out[0])
+	}
+	return s.withPoints(out)
+}
+
+// MergeCollinear returns a copy of the segment without the points that
+// lie within tolerance of the straight line between their neighbours.
+// A point is only merged if every point merged since the last kept one
+// is also within tolerance of the new line, so that the error does not
+// accumulate along gentle curves. The first point is always kept, as is
+// the last point of open segments.
+func (s Segment) MergeCollinear(tolerance float64) Segment {
+	pts, ring := s.ringPoints()
+	if len(pts) < 3 {
+		return s
+	}
+
+	out := [][2]float64{pts[0]}
+	last := 0
+	for i := 1; i < len(pts); i++ {
+		if !ring && i == len(pts)-1 {
+			out = append(out, pts[i])
+			break
+		}
+		next := pts[(i+1)%len(pts)]
+		merge := true
+		for j := last + 1; j <= i && merge; j++ {
+			merge = between(pts[j], pts[last], next) && pointSegmentDistance(pts[j], pts[last], next) <= tolerance
+		}
+		if !merge {
+			out = append(out, pts[i])
+			last = i
+		}
+	}
+	if ring {
+		if len(out) < 3 {
+			return s
+		}
+		out = append(out, out[0])
+	}
+	return s.withPoints(out)
+}
+
+// ringPoints returns the points of the segment without the closing
+// duplicate of closed segments, and whether the segment is a ring.
+func (s Segment) ringPoints() ([][2]float64, bool) {
+	n := len(s.Points)
+	if s.Closed && n > 1 && samePoint(s.Points[0], s.Points[n-1]) {
+		return s.Points[:n-1], true
+	}
+	return s.Points, false
+}
+
+// withPoints returns a copy of the segment with new points. Rings that
+// would collapse below a triangle are returned unchanged.
+func (s Segment) withPoints(pts [][2]float64) Segment {
+	if s.Closed && len(pts) < 4 && len(s.Points) >= 4 {
+		return s
+	}
+	s.Points = pts
+	return s
+}
+
+// between reports whether the projection of p falls onto the segment
+// a-b, so that merging p does not cut off a spike.
+func between(p, a, b [2]float64) bool {
+	dx, dy := b[0]-a[0], b[1]-a[1]
+	t := (p[0]-a[0])*dx + (p[1]-a[1])*dy
+	return t >= 0 && t <= dx*dx+dy*dy
+}
+
+// pointSegmentDistance returns the distance of p from the segment a-b.
+func pointSegmentDistance(p, a, b [2]float64) float64 {
+	dx, dy := b[0]-a[0], b[1]-a[1]
+	l2 := dx*dx + dy*dy
+	if l2 == 0 {
+		return math.Hypot(p[0]-a[0], p[1]-a[1])
+	}
+	t := math.Max(0, math.Min(1, ((p[0]-a[0])*dx+(p[1]-a[1])*dy)/l2))
+	return math.Hypot(p[0]-(a[0]+t*dx), p[1]-(a[1]+t*dy))
+}
+
+func triangleArea(a, b, c [2]float64) float64 {
+	return math.Abs(cross(a, b, c)) / 2
+}
+
+type vwItem struct {
+	index     int
+	area      float64
+	heapIndex int
+}
+
+// vwHeap is a min-heap of points ordered by effective area.
+type vwHeap []*vwItem
+
+func (h vwHeap) Len() int           { return len(h) }
+func (h vwHeap) Less(i, j int) bool { return h[i].area < h[j].area }
+func (h vwHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].heapIndex = i
+	h[j].heapIndex = j
+}
+
+func (h *vwHeap) Push(x interface{}) {
+	it := x.(*vwItem)
+	it.heapIndex = len(*h)
+	*h = append(*h, it)
+}
+
+func (h *vwHeap) Pop() interface{} {
+	old := *h
+	it := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return it
+}
@@ -0,0 +1,70 @@
+package svg
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSimplify(t *testing.T) {
+	noisy := Segment{Points: [][2]float64{{0, 0}, {1, 0.01}, {2, -0.01}, {3, 0}, {4, 2}, {5, 4.01}, {6, 6}}}
+	ring := Segment{Closed: true, Points: [][2]float64{
+		{0, 0}, {5, 0}, {10, 0}, {10, 5}, {10, 10}, {5, 10.001}, {0, 10}, {0, 5}, {0, 0},
+	}}
+
+	tests := []struct {
+		Description string
+		Method      SimplifyMethod
+		Segment     Segment
+		Tolerance   float64
+		Points      [][2]float64
+	}{
+		{"rdp open", RamerDouglasPeucker, noisy, 0.2, [][2]float64{{0, 0}, {3, 0}, {6, 6}}},
+		{"vw open", VisvalingamWhyatt, noisy, 0.2, [][2]float64{{0, 0}, {3, 0}, {6, 6}}},
+		{"collinear open", CollinearMerge, noisy, 0.2, [][2]float64{{0, 0}, {3, 0}, {6, 6}}},
+		{"rdp closed", RamerDouglasPeucker, ring, 0.2, [][2]float64{{0, 0}, {10, 0}, {10, 10}, {0, 10}, {0, 0}}},
+		{"vw closed", VisvalingamWhyatt, ring, 0.2, [][2]float64{{0, 0}, {10, 0}, {10, 10}, {0, 10}, {0, 0}}},
+		{"collinear closed", CollinearMerge, ring, 0.2, [][2]float64{{0, 0}, {10, 0}, {10, 10}, {0, 10}, {0, 0}}},
+	}
+
+	for _, test := range tests {
+		result := Simplify([]Segment{test.Segment}, test.Method, test.Tolerance)
+		require.Len(t, result, 1, test.Description)
+		require.Equal(t, test.Segment.Closed, result[0].Closed, test.Description)
+		require.Equal(t, test.Points, result[0].Points, test.Description)
+	}
+}
+
+func TestSimplifyKeepsSmallRings(t *testing.T) {
+	tiny := Segment{Closed: true, Points: [][2]float64{{0, 0}, {0.01, 0}, {0.01, 0.01}, {0, 0.01}, {0, 0}}}
+	for _, method := range []SimplifyMethod{RamerDouglasPeucker, VisvalingamWhyatt, CollinearMerge} {
+		result := Simplify([]Segment{tiny}, method, 1)
+		require.True(t, len(result[0].Points) >= 4)
+		require.True(t, result[0].Closed)
+	}
+}
+
+func TestSimplifyDeviation(t *testing.T) {
+	// a quarter circle of radius 100 with a point every half degree
+	arc := Segment{}
+	for i := 0; i <= 180; i++ {
+		a := float64(i) / 2 * math.Pi / 180
+		arc.Points = append(arc.Points, [2]float64{100 * math.Cos(a), 100 * math.Sin(a)})
+	}
+
+	for _, method := range []SimplifyMethod{RamerDouglasPeucker, CollinearMerge} {
+		result := Simplify([]Segment{arc}, method, 0.5)[0]
+		require.Less(t, len(result.Points), len(arc.Points))
+		// every point of the arc is within tolerance of the result
+		var deviation float64
+		for _, p := range arc.Points {
+			d := math.Inf(1)
+			for i := 1; i < len(result.Points); i++ {
+				d = math.Min(d, pointSegmentDistance(p, result.Points[i-1], result.Points[i]))
+			}
+			deviation = math.Max(deviation, d)
+		}
+		require.LessOrEqual(t, deviation, 0.5, "method %v", method)
+	}
+}
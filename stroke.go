@@ -0,0 +1,423 @@
+package svg
+
+import (
+	"math"
+	"strconv"
+	"strings"
+)
+
+// LineCap is the shape drawn at the ends of open stroked subpaths
+// (the stroke-linecap property).
+type LineCap int
+
+// These are the line caps defined by SVG
+const (
+	ButtCap LineCap = iota
+	RoundCap
+	SquareCap
+)
+
+// LineJoin is the shape drawn at the corners of stroked subpaths (the
+// stroke-linejoin property).
+type LineJoin int
+
+// These are the line joins defined by SVG
+const (
+	MiterJoin LineJoin = iota
+	RoundJoin
+	BevelJoin
+)
+
+// defaultMiterLimit is the initial value of stroke-miterlimit.
+const defaultMiterLimit = 4
+
+// ParseLineCap converts a stroke-linecap value. Unknown values yield the
+// initial value ButtCap.
+func ParseLineCap(s string) LineCap {
+	switch s {
+	case "round":
+		return RoundCap
+	case "square":
+		return SquareCap
+	}
+	return ButtCap
+}
+
+// ParseLineJoin converts a stroke-linejoin value. Unknown values yield
+// the initial value MiterJoin.
+func ParseLineJoin(s string) LineJoin {
+	switch s {
+	case "round":
+		return RoundJoin
+	case "bevel":
+		return BevelJoin
+	}
+	return MiterJoin
+}
+
+// StrokeStyle holds the stroke properties needed to turn a Segment into
+// its outline.
+type StrokeStyle struct {
+	Width      float64
+	LineCap    LineCap
+	LineJoin   LineJoin
+	MiterLimit float64
+
+	// Dash holds the lengths of alternating dashes and gaps, nil for a
+	// solid stroke. DashOffset is the distance into the pattern at which
+	// the stroke starts.
+	Dash       []float64
+	DashOffset float64
+}
+
+// ParseDashArray converts a stroke-dasharray value. A list with an odd
+// number of values is repeated to make it even. It returns nil for
+// "none" and for invalid lists, which render as a solid stroke.
+func ParseDashArray(s string) []float64 {
+	fields := strings.FieldsFunc(s, func(r rune) bool {
+		return r == ',' || r == ' ' || r == '\t' || r == '\n' || r == '\r'
+	})
+	var dashes []float64
+	var total float64
+	for _, f := range fields {
+		d, err := strconv.ParseFloat(f, 64)
+		if err != nil || d < 0 {
+			return nil
+		}
+		dashes = append(dashes, d)
+		total += d
+	}
+	if total == 0 {
+		return nil
+	}
+	if len(dashes)%2 == 1 {
+		dashes = append(dashes, dashes...)
+	}
+	return dashes
+}
+
+// strokeStyleFromPaint builds a StrokeStyle from a PaintInstruction.
+func strokeStyleFromPaint(di *DrawingInstruction) StrokeStyle {
+	style := StrokeStyle{MiterLimit: defaultMiterLimit}
+	if di.StrokeWidth != nil {
+		style.Width = *di.StrokeWidth
+	}
+	if di.StrokeLineCap != nil {
+		style.LineCap = ParseLineCap(*di.StrokeLineCap)
+	}
+	if di.StrokeLineJoin != nil {
+		style.LineJoin = ParseLineJoin(*di.StrokeLineJoin)
+	}
+	if di.StrokeMiterLimit != nil && *di.StrokeMiterLimit >= 1 {
+		style.MiterLimit = *di.StrokeMiterLimit
+	}
+	style.Dash = di.StrokeDashArray
+	if di.StrokeDashOffset != nil {
+		style.DashOffset = *di.StrokeDashOffset
+	}
+	return style
+}
+
+// unscaled returns style with the scale the document was parsed with,
+// which paint instructions multiply stroke widths and dashes by, divided
+// back out, so that it matches the coordinates of the instructions.
+func (style StrokeStyle) unscaled(scale float64) StrokeStyle {
+	if scale <= 0 || scale == 1 {
+		return style
+	}
+	style.Width /= scale
+	style.DashOffset /= scale
+	if style.Dash != nil {
+		dash := make([]float64, len(style.Dash))
+		for i, d := range style.Dash {
+			dash[i] = d / scale
+		}
+		style.Dash = dash
+	}
+	return style
+}
+
+// StrokeOutline flattens the path and returns the outline of its stroke
+// as closed segments, to be filled with the nonzero fill rule.
+func (p *Path) StrokeOutline() ([]Segment, error) {
+	instrs, err := collectInstructions(p)
+	if err != nil {
+		return nil, err
+	}
+
+	style := StrokeStyle{MiterLimit: defaultMiterLimit}
+	for _, di := range instrs {
+		if di.Kind == PaintInstruction {
+			style = strokeStyleFromPaint(di).unscaled(elementScale(p))
+		}
+	}
+	return StrokeSegments(flattenInstructions(instrs, p.flattenTolerance()), style), nil
+}
+
+// StrokeSegments returns the outlines of all segments stroked with
+// style, split into dashes first if style has a dash pattern.
+func StrokeSegments(segments []Segment, style StrokeStyle) []Segment {
+	var outlines []Segment
+	for _, s := range segments {
+		if len(style.Dash) == 0 {
+			outlines = append(outlines, s.Stroke(style)...)
+			continue
+		}
+		for _, d := range s.Dash(style.Dash, style.DashOffset) {
+			outlines = append(outlines, d.Stroke(style)...)
+		}
+	}
+	return outlines
+}
+
+// Dash splits the segment into the open segments drawn by the dash
+// pattern, starting offset into the pattern. Closed segments are
+// walked back to their start point.
+func (s Segment) Dash(pattern []float64, offset float64) []Segment {
+	var total float64
+	for _, d := range pattern {
+		total += d
+	}
+	if total <= 0 || len(s.Points) < 2 {
+		return []Segment{s}
+	}
+
+	pts := s.Points
+	if s.Closed && !samePoint(pts[0], pts[len(pts)-1]) {
+		pts = append(append([][2]float64{}, pts...), pts[0])
+	}
+
+	// find the dash the segment starts in
+	offset = math.Mod(offset, total)
+	if offset < 0 {
+		offset += total
+	}
+	index := 0
+	for offset >= pattern[index] {
+		offset -= pattern[index]
+		index = (index + 1) % len(pattern)
+	}
+	remaining := pattern[index] - offset
+
+	var dashes []Segment
+	var cur *Segment
+	if index%2 == 0 {
+		cur = &Segment{Width: s.Width, Points: [][2]float64{pts[0]}}
+	}
+	for i := 1; i < len(pts); i++ {
+		a, b := pts[i-1], pts[i]
+		l := math.Hypot(b[0]-a[0], b[1]-a[1])
+		pos := 0.0
+		for l-pos > remaining {
+			pos += remaining
+			p := [2]float64{a[0] + (b[0]-a[0])*pos/l, a[1] + (b[1]-a[1])*pos/l}
+			if cur != nil {
+				cur.addPoint(p)
+				dashes = append(dashes, *cur)
+				cur = nil
+			} else {
+				cur = &Segment{Width: s.Width, Points: [][2]float64{p}}
+			}
+			index = (index + 1) % len(pattern)
+			remaining = pattern[index]
+		}
+		remaining -= l - pos
+		if cur != nil {
+			cur.addPoint(b)
+		}
+	}
+	if cur != nil {
+		dashes = append(dashes, *cur)
+	}
+	return dashes
+}
+
+// Stroke returns the outline of the segment stroked with style. The
+// result consists of closed segments that must be filled with the
+// nonzero fill rule: an open segment yields a single outline including
+// its caps, a closed segment yields an outer and an inner loop of
+// opposite orientation. Zero-length segments produce a dot for round
+// and square caps and nothing for butt caps.
+func (s Segment) Stroke(style StrokeStyle) []Segment {
+	hw := style.Width / 2
+	if hw <= 0 {
+		return nil
+	}
+	if style.MiterLimit < 1 {
+		style.MiterLimit = defaultMiterLimit
+	}
+
+	pts := dedupPoints(s.Points)
+	closed := s.Closed
+	if closed && len(pts) > 1 && pts[0] == pts[len(pts)-1] {
+		pts = pts[:len(pts)-1]
+	}
+
+	if len(pts) == 1 {
+		return strokeDot(pts[0], hw, style.LineCap)
+	}
+	if len(pts) == 0 {
+		return nil
+	}
+
+	if closed {
+		outer := Segment{Closed: true, Points: offsetSide(pts, hw, style, true)}
+		inner := Segment{Closed: true, Points: offsetSide(reversePoints(pts), hw, style, true)}
+		outer.addPoint(outer.Points[0])
+		inner.addPoint(inner.Points[0])
+		return []Segment{outer, inner}
+	}
+
+	rev := reversePoints(pts)
+	outline := Segment{Closed: true}
+	outline.Points = append(outline.Points, offsetSide(pts, hw, style, false)...)
+	outline.Points = append(outline.Points, strokeCap(pts[len(pts)-2], pts[len(pts)-1], hw, style.LineCap)...)
+	outline.Points = append(outline.Points, offsetSide(rev, hw, style, false)...)
+	outline.Points = append(outline.Points, strokeCap(rev[len(rev)-2], rev[len(rev)-1], hw, style.LineCap)...)
+	outline.addPoint(outline.Points[0])
+	return []Segment{outline}
+}
+
+// offsetSide returns the left hand offset of the polyline pts at
+// distance hw, including the joins at every interior vertex. When loop
+// is set the polyline is treated as closed and joins are added at every
+// vertex.
+func offsetSide(pts [][2]float64, hw float64, style StrokeStyle, loop bool) [][2]float64 {
+	n := len(pts)
+	edges := n - 1
+	if loop {
+		edges = n
+	}
+	normals := make([][2]float64, edges)
+	for i := range normals {
+		normals[i] = leftNormal(pts[i], pts[(i+1)%n])
+	}
+
+	var out [][2]float64
+	if !loop {
+		out = append(out, offsetPoint(pts[0], normals[0], hw))
+	}
+	for i := 0; i < edges; i++ {
+		if !loop && i == edges-1 {
+			out = append(out, offsetPoint(pts[n-1], normals[i], hw))
+			break
+		}
+		next := (i + 1) % edges
+		out = append(out, strokeJoin(pts[(i+1)%n], normals[i], normals[next], hw, style)...)
+	}
+	return out
+}
+
+// strokeJoin returns the outline points around vertex p where the edge
+// with normal n1 meets the edge with normal n2.
+func strokeJoin(p, n1, n2 [2]float64, hw float64, style StrokeStyle) [][2]float64 {
+	a := offsetPoint(p, n1, hw)
+	b := offsetPoint(p, n2, hw)
+	cross := n1[0]*n2[1] - n1[1]*n2[0]
+	dot := n1[0]*n2[0] + n1[1]*n2[1]
+
+	if math.Abs(cross) < 1e-12 && dot > 0 {
+		return [][2]float64{a}
+	}
+	if cross > 0 {
+		// inner side of the turn: pass through the vertex so that the
+		// overlap is covered by the nonzero rule
+		return [][2]float64{a, p, b}
+	}
+
+	switch style.LineJoin {
+	case RoundJoin:
+		return arcPoints(p, hw, math.Atan2(n1[1], n1[0]), math.Atan2(cross, dot))
+	case MiterJoin:
+		if 1+dot > 1e-12 && 1/math.Sqrt((1+dot)/2) <= style.MiterLimit {
+			k := hw / (1 + dot)
+			return [][2]float64{a, {p[0] + (n1[0]+n2[0])*k, p[1] + (n1[1]+n2[1])*k}, b}
+		}
+	}
+	return [][2]float64{a, b}
+}
+
+// strokeCap returns the cap points at the end point b of the edge a-b,
+// going from the left offset to the right offset.
+func strokeCap(a, b [2]float64, hw float64, lc LineCap) [][2]float64 {
+	n := leftNormal(a, b)
+	d := [2]float64{n[1], -n[0]}
+	switch lc {
+	case RoundCap:
+		return arcPoints(b, hw, math.Atan2(n[1], n[0]), -math.Pi)
+	case SquareCap:
+		return [][2]float64{
+			{b[0] + (n[0]+d[0])*hw, b[1] + (n[1]+d[1])*hw},
+			{b[0] + (d[0]-n[0])*hw, b[1] + (d[1]-n[1])*hw},
+		}
+	}
+	// butt caps connect the two sides directly
+	return nil
+}
+
+// strokeDot returns the outline of a zero-length subpath at p.
+func strokeDot(p [2]float64, hw float64, lc LineCap) []Segment {
+	switch lc {
+	case RoundCap:
+		return []Segment{circleSegment(Tuple(p), hw, 0)}
+	case SquareCap:
+		return []Segment{{Closed: true, Points: [][2]float64{
+			{p[0] - hw, p[1] - hw},
+			{p[0] + hw, p[1] - hw},
+			{p[0] + hw, p[1] + hw},
+			{p[0] - hw, p[1] + hw},
+			{p[0] - hw, p[1] - hw},
+		}}}
+	}
+	return nil
+}
+
+// arcPoints returns points on the circle around c with radius r,
+// starting at angle a0 and sweeping by sweep radians.
+func arcPoints(c [2]float64, r, a0, sweep float64) [][2]float64 {
+	steps := int(math.Ceil(math.Abs(sweep) / (2 * math.Pi) * circleSteps))
+	if steps < 1 {
+		steps = 1
+	}
+	pts := make([][2]float64, 0, steps+1)
+	for i := 0; i <= steps; i++ {
+		a := a0 + sweep*float64(i)/float64(steps)
+		pts = append(pts, [2]float64{c[0] + r*math.Cos(a), c[1] + r*math.Sin(a)})
+	}
+	return pts
+}
+
+func leftNormal(a, b [2]float64) [2]float64 {
+	dx, dy := b[0]-a[0], b[1]-a[1]
+	l := math.Hypot(dx, dy)
+	return [2]float64{-dy / l, dx / l}
+}
+
+func offsetPoint(p, n [2]float64, d float64) [2]float64 {
+	return [2]float64{p[0] + n[0]*d, p[1] + n[1]*d}
+}
+
+// dedupPoints drops consecutive duplicate points, which would otherwise
+// produce zero-length edges without a direction.
+func dedupPoints(pts [][2]float64) [][2]float64 {
+	var out [][2]float64
+	for _, p := range pts {
+		if len(out) > 0 && samePoint(out[len(out)-1], p) {
+			continue
+		}
+		out = append(out, p)
+	}
+	return out
+}
+
+func samePoint(a, b [2]float64) bool {
+	return math.Abs(a[0]-b[0]) < 1e-9 && math.Abs(a[1]-b[1]) < 1e-9
+}
+
+func reversePoints(pts [][2]float64) [][2]float64 {
+	out := make([][2]float64, len(pts))
+	for i, p := range pts {
+		out[len(pts)-1-i] = p
+	}
+	return out
+}
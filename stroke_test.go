@@ -0,0 +1,102 @@
+package svg
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// outlineArea measures the area covered by the outlines under the
+// nonzero fill rule by sampling a grid.
+func outlineArea(segments []Segment) float64 {
+	const step = 0.04
+	var area float64
+	for y := -10 + step/2; y < 20; y += step {
+		for x := -10 + step/2; x < 20; x += step {
+			winding := 0
+			for _, s := range segments {
+				for i := 0; i+1 < len(s.Points); i++ {
+					a, b := s.Points[i], s.Points[i+1]
+					if (a[1] <= y) != (b[1] <= y) {
+						cx := a[0] + (y-a[1])*(b[0]-a[0])/(b[1]-a[1])
+						if cx > x {
+							if b[1] > a[1] {
+								winding++
+							} else {
+								winding--
+							}
+						}
+					}
+				}
+			}
+			if winding != 0 {
+				area += step * step
+			}
+		}
+	}
+	return area
+}
+
+func TestStrokeSegment(t *testing.T) {
+	line := Segment{Points: [][2]float64{{0, 0}, {10, 0}}}
+	square := Segment{Closed: true, Points: [][2]float64{{0, 0}, {10, 0}, {10, 10}, {0, 10}, {0, 0}}}
+	dot := Segment{Points: [][2]float64{{5, 5}, {5, 5}}}
+
+	tests := []struct {
+		Description string
+		Segment     Segment
+		Style       StrokeStyle
+		Outlines    int
+		Area        float64
+	}{
+		{"butt cap", line, StrokeStyle{Width: 2}, 1, 20},
+		{"square cap", line, StrokeStyle{Width: 2, LineCap: SquareCap}, 1, 24},
+		{"round cap", line, StrokeStyle{Width: 2, LineCap: RoundCap}, 1, 20 + math.Pi},
+		{"closed miter", square, StrokeStyle{Width: 2}, 2, 144 - 64},
+		{"closed bevel", square, StrokeStyle{Width: 2, LineJoin: BevelJoin}, 2, 144 - 2 - 64},
+		{"miter limit", square, StrokeStyle{Width: 2, MiterLimit: 1.2}, 2, 144 - 2 - 64},
+		{"zero length butt", dot, StrokeStyle{Width: 2}, 0, 0},
+		{"zero length round", dot, StrokeStyle{Width: 2, LineCap: RoundCap}, 1, math.Pi},
+		{"zero length square", dot, StrokeStyle{Width: 2, LineCap: SquareCap}, 1, 4},
+	}
+
+	for _, test := range tests {
+		outlines := test.Segment.Stroke(test.Style)
+		require.Len(t, outlines, test.Outlines, test.Description)
+		for _, o := range outlines {
+			require.True(t, o.Closed, test.Description)
+		}
+		require.InDelta(t, test.Area, outlineArea(outlines), 0.3, test.Description)
+	}
+}
+
+func TestPathStrokeOutline(t *testing.T) {
+	svg, err := ParseSvg(`<svg><path d="M0 0 L10 0" stroke-width="4" stroke-linecap="square"/></svg>`, "test", 0)
+	require.NoError(t, err)
+
+	outlines, err := svg.Elements[0].(*Path).StrokeOutline()
+	require.NoError(t, err)
+	require.Len(t, outlines, 1)
+	require.InDelta(t, 56, outlineArea(outlines), 0.3)
+}
+
+func TestSegmentDash(t *testing.T) {
+	line := Segment{Points: [][2]float64{{0, 0}, {10, 0}}}
+	dashes := line.Dash(ParseDashArray("3,1"), 1)
+	require.Equal(t, []Segment{
+		{Points: [][2]float64{{0, 0}, {2, 0}}},
+		{Points: [][2]float64{{3, 0}, {6, 0}}},
+		{Points: [][2]float64{{7, 0}, {10, 0}}},
+	}, dashes)
+
+	square := Segment{Closed: true, Points: [][2]float64{{0, 0}, {4, 0}, {4, 4}, {0, 4}}}
+	dashes = square.Dash([]float64{6, 2}, 0)
+	require.Len(t, dashes, 2)
+	require.Equal(t, [][2]float64{{0, 0}, {4, 0}, {4, 2}}, dashes[0].Points)
+	require.Equal(t, [][2]float64{{4, 4}, {0, 4}, {0, 2}}, dashes[1].Points)
+
+	require.Nil(t, ParseDashArray("none"))
+	require.Nil(t, ParseDashArray("1 -2"))
+	require.Equal(t, []float64{1, 2, 3, 1, 2, 3}, ParseDashArray("1 2 3"))
+}
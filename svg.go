@@ -33,12 +33,60 @@ type Svg struct {
 	Elements     []DrawingInstructionParser
 	Name         string
 	Transform    *mt.Transform
+	// FlattenTolerance is the largest distance between a curve and the
+	// segments it is flattened to. Zero subdivides curves a fixed
+	// number of times.
+	FlattenTolerance float64
 	scale        float64
+	// Warnings are the problems that lenient parsing recovered from.
+	// Problems in path data are found when the drawing instructions are
+	// parsed, so the list is complete once they have been read.
+	Warnings     []Warning
+	options      Options
+	counts       *parseCounts
+	warnMu       sync.Mutex
+	order        []childRef
+	source       *lineIndex
+	// href is the path of an external document relative to the document
+	// that refers to it, empty for the document being parsed
+	href         string
+	// gradientElements are the gradients as decoded and gradients the
+	// resolved gradients by ID
+	gradientElements []*gradientElement
+	gradients    map[string]*Gradient
 	instructions chan *DrawingInstruction
 	errors       chan error
 	segments     chan Segment
 }
 
+// childRef locates a top level child of an Svg, which keeps groups and
+// other elements in separate slices.
+type childRef struct {
+	group bool
+	index int
+}
+
+// children returns the top level elements in document order.
+func (s *Svg) children() []DrawingInstructionParser {
+	var children []DrawingInstructionParser
+	if len(s.order) != len(s.Elements)+len(s.Groups) {
+		// not decoded from XML, so the order is unknown
+		children = append(children, s.Elements...)
+		for i := range s.Groups {
+			children = append(children, &s.Groups[i])
+		}
+		return children
+	}
+	for _, ref := range s.order {
+		if ref.group {
+			children = append(children, &s.Groups[ref.index])
+		} else {
+			children = append(children, s.Elements[ref.index])
+		}
+	}
+	return children
+}
+
 // Group represents an SVG group (usually located in a 'g' XML element)
 type Group struct {
 	ID              string
@@ -46,11 +94,17 @@ type Group struct {
 	StrokeWidth     float64
 	Fill            string
 	FillRule        string
+	Display         string
+	Visibility      string
+	Opacity         *float64
+	Label           string // inkscape:label
+	GroupMode       string // inkscape:groupmode, "layer" for Inkscape layers
 	Elements        []DrawingInstructionParser
 	TransformString string
 	Transform       *mt.Transform // row, column
 	Parent          *Group
 	Owner           *Svg
+	pos             position
 	instructions    chan *DrawingInstruction
 	errors          chan error
 	segments        chan Segment
@@ -63,31 +117,47 @@ func (g *Group) ParseDrawingInstructions() (chan *DrawingInstruction, chan error
 	g.instructions = make(chan *DrawingInstruction, 100)
 	g.errors = make(chan error, 100)
 
-	errWg := &sync.WaitGroup{}
-
 	go func() {
 		defer close(g.instructions)
-		defer func() { errWg.Wait(); close(g.errors) }()
+		defer close(g.errors)
 		for _, e := range g.Elements {
-			instrs, errs := e.ParseDrawingInstructions()
-			errWg.Add(1)
-			go func() {
-				for er := range errs {
-					g.errors <- er
-				}
-				errWg.Done()
-			}()
-			for is := range instrs {
-				g.instructions <- is
-			}
+			forwardInstructions(e, g.instructions, g.errors)
 		}
 	}()
 
 	return g.instructions, g.errors
 }
 
+// forwardInstructions sends the drawing instructions and errors of e to
+// instrs and errs. Errors that do not fit in the buffer of errs are
+// dropped rather than blocking, since readers usually drain the
+// instructions first and then only need the first error.
+func forwardInstructions(e DrawingInstructionParser, instrs chan<- *DrawingInstruction, errs chan<- error) {
+	is, es := e.ParseDrawingInstructions()
+	for is != nil || es != nil {
+		select {
+		case di, ok := <-is:
+			if !ok {
+				is = nil
+				continue
+			}
+			instrs <- di
+		case err, ok := <-es:
+			if !ok {
+				es = nil
+				continue
+			}
+			select {
+			case errs <- err:
+			default:
+			}
+		}
+	}
+}
+
 // UnmarshalXML implements the encoding.xml.Unmarshaler interface
 func (g *Group) UnmarshalXML(decoder *xml.Decoder, start xml.StartElement) error {
+	var attrErrs []error
 	for _, attr := range start.Attr {
 		switch attr.Name.Local {
 		case "id":
@@ -97,47 +167,113 @@ func (g *Group) UnmarshalXML(decoder *xml.Decoder, start xml.StartElement) error
 		case "stroke-width":
 			floatValue, err := strconv.ParseFloat(attr.Value, 64)
 			if err != nil {
-				return err
+				attrErrs = append(attrErrs, fmt.Errorf("invalid stroke-width: %w", err))
+				continue
 			}
 			g.StrokeWidth = floatValue
 		case "fill":
 			g.Fill = attr.Value
 		case "fill-rule":
 			g.FillRule = attr.Value
+		case "opacity":
+			floatValue, err := strconv.ParseFloat(attr.Value, 64)
+			if err != nil {
+				attrErrs = append(attrErrs, fmt.Errorf("invalid opacity: %w", err))
+				continue
+			}
+			g.Opacity = &floatValue
+		case "display":
+			g.Display = attr.Value
+		case "visibility":
+			g.Visibility = attr.Value
+		case "label":
+			g.Label = attr.Value
+		case "groupmode":
+			g.GroupMode = attr.Value
 		case "transform":
 			g.TransformString = attr.Value
 			t, err := parseTransform(g.TransformString)
 			if err != nil {
-				fmt.Println(err)
+				attrErrs = append(attrErrs, fmt.Errorf("invalid transform %q: %w", attr.Value, err))
+				continue
+			}
+			if g.Transform == nil {
+				g.Transform = mt.NewTransform()
 			}
-			g.Transform = &t
+			g.Transform.MultiplyWith(t)
+		}
+	}
+	// invalid attributes are ignored as if they were not specified
+	for _, err := range attrErrs {
+		if err = g.Owner.warn(g.pos, "g", g.ID, -1, err); err != nil {
+			return err
 		}
 	}
 
+	ancestors := g.pos.child("g", g.ID)
 	for {
+		offset := decoder.InputOffset()
 		token, err := decoder.Token()
 		if err != nil {
-			return err
+			return g.pos.error("g", g.ID, -1, err)
 		}
 
 		switch tok := token.(type) {
 		case xml.StartElement:
 			var elementStruct DrawingInstructionParser
+			pos := g.Owner.position(offset, ancestors)
+			if err := g.Owner.checkLimits(pos); err != nil {
+				return pos.error(tok.Name.Local, attrValue(tok, "id"), -1, err)
+			}
 
 			switch tok.Name.Local {
 			case "g":
-				elementStruct = &Group{Parent: g, Owner: g.Owner, Transform: mt.NewTransform()}
+				inherited := mt.Identity()
+				if g.Transform != nil {
+					inherited = *g.Transform
+				}
+				elementStruct = &Group{Parent: g, Owner: g.Owner, Transform: &inherited, StrokeWidth: g.StrokeWidth, pos: pos}
 			case "rect":
 				elementStruct = &Rect{group: g}
 			case "circle":
-				elementStruct = &Circle{group: g}
+				elementStruct = &Circle{group: g, pos: pos}
 			case "path":
-				elementStruct = &Path{group: g, StrokeWidth: float64(g.StrokeWidth), Stroke: &g.Stroke, Fill: &g.Fill}
+				// copy the inherited values so that the path's own
+				// attributes do not overwrite the group's
+				stroke, fill, fillRule := g.Stroke, g.Fill, g.FillRule
+				elementStruct = &Path{group: g, StrokeWidth: float64(g.StrokeWidth), Stroke: &stroke, Fill: &fill, FillRule: &fillRule, pos: pos}
+			case "use":
+				elementStruct = &Use{group: g, pos: pos}
+			case "image":
+				elementStruct = &Image{group: g, doc: g.Owner}
+			case "text":
+				elementStruct = &Text{group: g, pos: pos}
+			case "linearGradient", "radialGradient":
+				if err = g.Owner.decodeGradient(decoder, tok, pos); err != nil {
+					return err
+				}
+				continue
+			case "style":
+				if err = g.Owner.decodeStyle(decoder, tok, pos); err != nil {
+					return err
+				}
+				continue
 			default:
 				continue
 			}
 			if err = decoder.DecodeElement(elementStruct, &tok); err != nil {
-				return fmt.Errorf("error decoding element of Group: %s", err)
+				if _, ok := elementStruct.(*Group); ok {
+					return pos.error("g", attrValue(tok, "id"), -1, err)
+				}
+				if err = g.Owner.skipInvalid(decoder, tok, pos, err); err != nil {
+					return err
+				}
+				continue
+			}
+			if p, ok := elementStruct.(*Path); ok {
+				if err = g.Owner.checkPathData(p.D); err != nil {
+					return pos.error("path", p.ID, -1, err)
+				}
 			}
 			g.Elements = append(g.Elements, elementStruct)
 		case xml.EndElement:
@@ -156,38 +292,10 @@ func (s *Svg) ParseDrawingInstructions() (chan *DrawingInstruction, chan error)
 	s.errors = make(chan error, 100)
 
 	go func() {
-		errWg := &sync.WaitGroup{}
-		var elecount int
 		defer close(s.instructions)
-		defer func() { errWg.Wait(); close(s.errors) }()
-		for _, e := range s.Elements {
-			elecount++
-			instrs, errs := e.ParseDrawingInstructions()
-			errWg.Add(1)
-			go func(count int) {
-				for er := range errs {
-					s.errors <- fmt.Errorf("error when parsing element nr. %d: %s", count, er)
-				}
-				errWg.Done()
-			}(elecount)
-
-			for is := range instrs {
-				s.instructions <- is
-			}
-		}
-
-		for _, g := range s.Groups {
-			instrs, errs := g.ParseDrawingInstructions()
-			errWg.Add(1)
-			go func() {
-				for er := range errs {
-					s.errors <- er
-				}
-				errWg.Done()
-			}()
-			for is := range instrs {
-				s.instructions <- is
-			}
+		defer close(s.errors)
+		for _, e := range s.children() {
+			forwardInstructions(e, s.instructions, s.errors)
 		}
 	}()
 
@@ -209,38 +317,78 @@ func (s *Svg) UnmarshalXML(decoder *xml.Decoder, start xml.StartElement) error {
 			}
 		}
 
+		offset := decoder.InputOffset()
 		token, err := decoder.Token()
 		if err != nil {
-			return err
+			return position{}.error("svg", attrValue(start, "id"), -1, err)
 		}
 
 		switch tok := token.(type) {
 		case xml.StartElement:
 			var dip DrawingInstructionParser
+			pos := s.position(offset, []string{"svg"})
+			if err := s.checkLimits(pos); err != nil {
+				return pos.error(tok.Name.Local, attrValue(tok, "id"), -1, err)
+			}
+			defaults := s.options.Defaults
 
 			switch tok.Name.Local {
 			case "g":
-				g := &Group{Owner: s, Transform: mt.NewTransform()}
+				g := &Group{
+					Owner:       s,
+					Transform:   mt.NewTransform(),
+					Stroke:      defaults.Stroke,
+					StrokeWidth: defaults.StrokeWidth,
+					Fill:        defaults.Fill,
+					FillRule:    defaults.FillRule,
+					pos:         pos,
+				}
 				if err = decoder.DecodeElement(g, &tok); err != nil {
-					return fmt.Errorf("error decoding group element within SVG struct: %s", err)
+					return pos.error("g", attrValue(tok, "id"), -1, err)
 				}
+				s.order = append(s.order, childRef{group: true, index: len(s.Groups)})
 				s.Groups = append(s.Groups, *g)
 				continue
 			case "rect":
 				dip = &Rect{}
 			case "circle":
-				dip = &Circle{}
+				dip = defaults.newCircle(s, pos)
 			case "path":
-				dip = &Path{}
+				dip = defaults.newPath(s, pos)
+			case "use":
+				dip = &Use{pos: pos}
+			case "image":
+				dip = &Image{doc: s}
+			case "text":
+				dip = defaults.newText(s, pos)
+			case "linearGradient", "radialGradient":
+				if err = s.decodeGradient(decoder, tok, pos); err != nil {
+					return err
+				}
+				continue
+			case "style":
+				if err = s.decodeStyle(decoder, tok, pos); err != nil {
+					return err
+				}
+				continue
 
 			default:
 				continue
 			}
 
 			if err = decoder.DecodeElement(dip, &tok); err != nil {
-				return fmt.Errorf("error decoding element of SVG struct: %s", err)
+				if err = s.skipInvalid(decoder, tok, pos, err); err != nil {
+					return err
+				}
+				continue
+			}
+			if p, ok := dip.(*Path); ok {
+				if err = s.checkPathData(p.D); err != nil {
+					return pos.error("path", p.ID, -1, err)
+				}
 			}
 
+			s.order = append(s.order, childRef{index: len(s.Elements)})
 			s.Elements = append(s.Elements, dip)
 
 		case xml.EndElement:
@@ -251,59 +399,73 @@ func (s *Svg) UnmarshalXML(decoder *xml.Decoder, start xml.StartElement) error {
 	}
 }
 
-// ParseSvg parses an SVG string into an SVG struct
+// ParseSvg parses an SVG string into an SVG struct. A positive scale
+// multiplies all coordinates, a negative one divides them and zero
+// leaves them as they are.
 func ParseSvg(str string, name string, scale float64) (*Svg, error) {
-	var svg Svg
-	svg.Name = name
-	svg.Transform = mt.NewTransform()
-	if scale > 0 {
-		svg.Transform.Scale(scale, scale)
-		svg.scale = scale
-	}
-	if scale < 0 {
-		svg.Transform.Scale(1.0/-scale, 1.0/-scale)
-		svg.scale = 1.0 / -scale
-	}
+	return ParseSvgWithOptions(strings.NewReader(str), legacyOptions(name, scale))
+}
 
-	err := xml.Unmarshal([]byte(str), &svg)
-	if err != nil {
-		return nil, fmt.Errorf("ParseSvg Error: %v", err)
-	}
+// ParseSvgFromReader parses an SVG struct from an io.Reader, which may
+// be gzip compressed. The scale is interpreted as by ParseSvg.
+func ParseSvgFromReader(r io.Reader, name string, scale float64) (*Svg, error) {
+	return ParseSvgWithOptions(r, legacyOptions(name, scale))
+}
 
-	for i := range svg.Groups {
-		svg.Groups[i].SetOwner(&svg)
-		if svg.Groups[i].Transform == nil {
-			svg.Groups[i].Transform = mt.NewTransform()
+// newPath returns a top level path of svg at pos with the default
+// presentation attributes.
+func (d StyleDefaults) newPath(svg *Svg, pos position) *Path {
+	p := &Path{group: &Group{Owner: svg, Transform: mt.NewTransform()}, StrokeWidth: d.StrokeWidth, pos: pos}
+	// nil leaves the attributes unset
+	str := func(v string) *string {
+		if v == "" {
+			return nil
 		}
+		return &v
 	}
-	return &svg, nil
+	p.Fill, p.FillRule, p.Stroke = str(d.Fill), str(d.FillRule), str(d.Stroke)
+	return p
 }
 
-// ParseSvgFromReader parses an SVG struct from an io.Reader
-func ParseSvgFromReader(r io.Reader, name string, scale float64) (*Svg, error) {
-	var svg Svg
-	svg.Name = name
-	svg.Transform = mt.NewTransform()
-	if scale > 0 {
-		svg.Transform.Scale(scale, scale)
-		svg.scale = scale
+// newCircle returns a top level circle of svg at pos with the default
+// fill. Circles are not stroked.
+func (d StyleDefaults) newCircle(svg *Svg, pos position) *Circle {
+	return &Circle{group: &Group{Owner: svg, Transform: mt.NewTransform()}, Fill: d.Fill, pos: pos}
+}
+
+// newText returns a top level text element of svg at pos with the
+// default presentation attributes.
+func (d StyleDefaults) newText(svg *Svg, pos position) *Text {
+	t := &Text{group: &Group{Owner: svg, Transform: mt.NewTransform()}, pos: pos}
+	if d.Fill != "" {
+		t.Fill = &d.Fill
 	}
-	if scale < 0 {
-		svg.Transform.Scale(1.0/-scale, 1.0/-scale)
-		svg.scale = 1.0 / -scale
+	if d.Stroke != "" {
+		t.Stroke = &d.Stroke
 	}
+	return t
+}
 
-	if err := xml.NewDecoder(r).Decode(&svg); err != nil {
-		return nil, fmt.Errorf("ParseSvg Error: %v", err)
+// parseSvgError returns ParseErrors unchanged and wraps other decoding
+// errors.
+func parseSvgError(err error) error {
+	var pe *ParseError
+	if errors.As(err, &pe) {
+		return err
 	}
+	return fmt.Errorf("ParseSvg Error: %w", err)
+}
 
-	for i := range svg.Groups {
-		svg.Groups[i].SetOwner(&svg)
-		if svg.Groups[i].Transform == nil {
-			svg.Groups[i].Transform = mt.NewTransform()
-		}
+// position returns the position of an element that starts at offset in
+// the source document.
+func (s *Svg) position(offset int64, ancestors []string) position {
+	var source *lineIndex
+	var document string
+	if s != nil {
+		source, document = s.source, s.href
 	}
-	return &svg, nil
+	line, column := source.position(offset)
+	return position{document: document, line: line, column: column, ancestors: ancestors}
 }
 
 // ViewBoxValues returns all the numerical values in the viewBox
@@ -338,6 +500,12 @@ func (g *Group) SetOwner(svg *Svg) {
 			gn.(*Group).SetOwner(svg)
 		case *Path:
 			gn.(*Path).group = g
+		case *Use:
+			gn.(*Use).group = g
+		case *Image:
+			gn.(*Image).group = g
+		case *Text:
+			gn.(*Text).group = g
 		}
 	}
 }
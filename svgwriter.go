@@ -0,0 +1,259 @@
+package svg
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+
+	mt "github.com/rustyoz/Mtransform"
+)
+
+// SvgOptions controls how shapes are written by WriteSvg.
+type SvgOptions struct {
+	// Compress writes the document gzip compressed, as an SVGZ file.
+	Compress bool
+	// Precision is the number of decimals coordinates are rounded to.
+	// Zero writes them exactly.
+	Precision int
+}
+
+// WriteSvg writes the visible shapes to w as an SVG document of
+// flattened paths with the paint of each shape. The viewBox is the
+// bounding box of the shapes including their strokes. A nil opts writes
+// an uncompressed document with exact coordinates.
+func WriteSvg(w io.Writer, shapes []Shape, opts *SvgOptions) error {
+	if opts == nil {
+		opts = &SvgOptions{}
+	}
+	if opts.Compress {
+		zw := gzip.NewWriter(w)
+		if err := WriteSvg(zw, shapes, &SvgOptions{Precision: opts.Precision}); err != nil {
+			return err
+		}
+		return zw.Close()
+	}
+
+	sw := &svgWriter{w: bufio.NewWriter(w), precision: opts.Precision, ids: make(map[string]bool)}
+	min, max := svgBounds(shapes)
+	sw.printf(`<?xml version="1.0" encoding="UTF-8"?>` + "\n")
+	sw.printf(`<svg xmlns="http://www.w3.org/2000/svg" viewBox="%s %s %s %s">`+"\n",
+		sw.num(min[0]), sw.num(min[1]), sw.num(max[0]-min[0]), sw.num(max[1]-min[1]))
+	for _, s := range shapes {
+		if !s.Hidden {
+			sw.shape(s)
+		}
+	}
+	sw.printf("</svg>\n")
+	if sw.err != nil {
+		return sw.err
+	}
+	return sw.w.Flush()
+}
+
+// svgBounds returns the bounding box of the visible shapes, or an empty
+// box at the origin if there are none.
+func svgBounds(shapes []Shape) (min, max [2]float64) {
+	min = [2]float64{math.Inf(1), math.Inf(1)}
+	max = [2]float64{math.Inf(-1), math.Inf(-1)}
+	for _, s := range shapes {
+		if s.Hidden {
+			continue
+		}
+		var pad float64
+		if s.Stroked() {
+			pad = s.StrokeStyle.Width / 2
+		}
+		for _, seg := range s.Segments {
+			for _, p := range seg.Points {
+				min[0], min[1] = math.Min(min[0], p[0]-pad), math.Min(min[1], p[1]-pad)
+				max[0], max[1] = math.Max(max[0], p[0]+pad), math.Max(max[1], p[1]+pad)
+			}
+		}
+	}
+	if min[0] > max[0] {
+		return [2]float64{}, [2]float64{}
+	}
+	return min, max
+}
+
+// svgWriter writes the elements of an SVG document. It keeps the first
+// write error.
+type svgWriter struct {
+	w         *bufio.Writer
+	precision int
+	err       error
+	// ids are the IDs written so far, so that copies of an element made
+	// by use elements do not repeat its ID
+	ids map[string]bool
+	// gradients is the number of gradients written
+	gradients int
+}
+
+func (sw *svgWriter) printf(format string, args ...interface{}) {
+	if sw.err == nil {
+		_, sw.err = fmt.Fprintf(sw.w, format, args...)
+	}
+}
+
+func (sw *svgWriter) num(v float64) string {
+	if sw.precision > 0 {
+		s := strconv.FormatFloat(v, 'f', sw.precision, 64)
+		s = strings.TrimRight(strings.TrimRight(s, "0"), ".")
+		if s == "-0" {
+			return "0"
+		}
+		return s
+	}
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+// attr writes the attribute name with value v.
+func (sw *svgWriter) attr(name, v string) {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(v))
+	sw.printf(` %s="%s"`, name, b.String())
+}
+
+// shape writes s as a path element.
+func (sw *svgWriter) shape(s Shape) {
+	var d strings.Builder
+	for _, seg := range s.Segments {
+		points := seg.Points
+		// Z draws the closing line
+		if n := len(points); seg.Closed && n > 1 && points[0] == points[n-1] {
+			points = points[:n-1]
+		}
+		for i, p := range points {
+			if i == 0 {
+				d.WriteString(" M")
+			} else if i == 1 {
+				d.WriteString(" L")
+			}
+			fmt.Fprintf(&d, " %s %s", sw.num(p[0]), sw.num(p[1]))
+		}
+		if seg.Closed && len(points) > 0 {
+			d.WriteString(" Z")
+		}
+	}
+	if d.Len() == 0 {
+		return
+	}
+
+	// the gradients are written for every shape, since userSpaceOnUse
+	// gradients include the shape's transform
+	fill, stroke := s.Fill, s.Stroke
+	if s.FillGradient != nil {
+		fill = sw.gradient(s.FillGradient)
+	}
+	if s.StrokeGradient != nil && s.Stroked() {
+		stroke = sw.gradient(s.StrokeGradient)
+	}
+
+	sw.printf("<path")
+	if s.ID != "" && !sw.ids[s.ID] {
+		sw.ids[s.ID] = true
+		sw.attr("id", s.ID)
+	}
+	sw.attr("d", d.String()[1:])
+	if fill != "" {
+		sw.attr("fill", fill)
+	}
+	if s.FillRule != "" {
+		sw.attr("fill-rule", s.FillRule)
+	}
+	if s.Stroked() {
+		st := s.StrokeStyle
+		sw.attr("stroke", stroke)
+		sw.attr("stroke-width", sw.num(st.Width))
+		switch st.LineCap {
+		case RoundCap:
+			sw.attr("stroke-linecap", "round")
+		case SquareCap:
+			sw.attr("stroke-linecap", "square")
+		}
+		switch st.LineJoin {
+		case RoundJoin:
+			sw.attr("stroke-linejoin", "round")
+		case BevelJoin:
+			sw.attr("stroke-linejoin", "bevel")
+		}
+		if st.MiterLimit != defaultMiterLimit && st.MiterLimit > 0 {
+			sw.attr("stroke-miterlimit", sw.num(st.MiterLimit))
+		}
+		if len(st.Dash) > 0 {
+			dash := make([]string, len(st.Dash))
+			for i, v := range st.Dash {
+				dash[i] = sw.num(v)
+			}
+			sw.attr("stroke-dasharray", strings.Join(dash, " "))
+			if st.DashOffset != 0 {
+				sw.attr("stroke-dashoffset", sw.num(st.DashOffset))
+			}
+		}
+	}
+	for _, o := range []struct {
+		name  string
+		value float64
+	}{{"opacity", s.Opacity}, {"fill-opacity", s.FillOpacity}, {"stroke-opacity", s.StrokeOpacity}} {
+		if o.value < 1 {
+			sw.attr(o.name, sw.num(o.value))
+		}
+	}
+	sw.printf("/>\n")
+}
+
+// gradient writes g and returns the paint value that refers to it.
+func (sw *svgWriter) gradient(g *Gradient) string {
+	var id string
+	for id == "" || sw.ids[id] {
+		sw.gradients++
+		id = fmt.Sprintf("gradient-%d", sw.gradients)
+	}
+	sw.ids[id] = true
+
+	tag := "linearGradient"
+	if g.Radial {
+		tag = "radialGradient"
+	}
+	sw.printf("<defs><%s", tag)
+	sw.attr("id", id)
+	sw.attr("gradientUnits", g.Units)
+	if g.SpreadMethod != "pad" {
+		sw.attr("spreadMethod", g.SpreadMethod)
+	}
+	t := g.Transform
+	if t != mt.Identity() {
+		sw.attr("gradientTransform", fmt.Sprintf("matrix(%s %s %s %s %s %s)",
+			sw.num(t[0][0]), sw.num(t[1][0]), sw.num(t[0][1]), sw.num(t[1][1]), sw.num(t[0][2]), sw.num(t[1][2])))
+	}
+	attrs := []struct {
+		name  string
+		value float64
+	}{{"x1", g.X1}, {"y1", g.Y1}, {"x2", g.X2}, {"y2", g.Y2}}
+	if g.Radial {
+		attrs = []struct {
+			name  string
+			value float64
+		}{{"cx", g.Cx}, {"cy", g.Cy}, {"r", g.R}, {"fx", g.Fx}, {"fy", g.Fy}}
+	}
+	for _, a := range attrs {
+		sw.attr(a.name, sw.num(a.value))
+	}
+	sw.printf(">")
+	for _, s := range g.Stops {
+		sw.printf("<stop")
+		sw.attr("offset", sw.num(s.Offset))
+		sw.attr("stop-color", fmt.Sprintf("#%02x%02x%02x", s.Color.R, s.Color.G, s.Color.B))
+		if s.Color.A < 0xff {
+			sw.attr("stop-opacity", sw.num(float64(s.Color.A)/0xff))
+		}
+		sw.printf("/>")
+	}
+	sw.printf("</%s></defs>\n", tag)
+	return "url(#" + id + ")"
+}
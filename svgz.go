@@ -0,0 +1,45 @@
+package svg
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+)
+
+// gzipMagic starts every gzip stream.
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// decompress returns a reader of the decompressed content of r if r is
+// gzip compressed, as SVGZ documents are, and of r itself otherwise.
+func decompress(r io.Reader) (io.Reader, error) {
+	br := bufio.NewReader(r)
+	magic, err := br.Peek(len(gzipMagic))
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	if !bytes.Equal(magic, gzipMagic) {
+		return br, nil
+	}
+	zr, err := gzip.NewReader(br)
+	if err != nil {
+		return nil, fmt.Errorf("invalid svgz: %w", err)
+	}
+	return zr, nil
+}
+
+// OpenFile parses the SVG or SVGZ document in the file name. If opts has
+// no Name, the document is named after the file.
+func OpenFile(name string, opts Options) (*Svg, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	if opts.Name == "" {
+		opts.Name = name
+	}
+	return ParseSvgWithOptions(f, opts)
+}
@@ -0,0 +1,65 @@
+package svg
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSvgz(t *testing.T) {
+	doc := `<svg><path id="p" d="M0 0 L10 0 L10 10 Z" fill="red" fill-rule="evenodd" stroke="blue" stroke-width="2" stroke-linecap="round" stroke-dasharray="1 2" opacity="0.5"/><path d="M0 0 L1 1" display="none"/></svg>`
+	svg, err := ParseSvg(doc, "", 1)
+	require.NoError(t, err)
+	shapes, err := svg.Shapes()
+	require.NoError(t, err)
+
+	var plain, compressed bytes.Buffer
+	require.NoError(t, WriteSvg(&plain, shapes, nil))
+	require.Contains(t, plain.String(), `viewBox="-1 -1 12 12"`)
+	require.Contains(t, plain.String(), `<path id="p" d="M 0 0 L 10 0 10 10 Z" fill="red" fill-rule="evenodd" stroke="blue" stroke-width="2" stroke-linecap="round" stroke-dasharray="1 2" opacity="0.5"/>`)
+	require.Equal(t, 1, strings.Count(plain.String(), "<path"))
+
+	require.NoError(t, WriteSvg(&compressed, shapes, &SvgOptions{Compress: true}))
+	require.Equal(t, gzipMagic, compressed.Bytes()[:2])
+
+	// the compressed document parses to the same shapes
+	dir := t.TempDir()
+	file := filepath.Join(dir, "out.svgz")
+	require.NoError(t, os.WriteFile(file, compressed.Bytes(), 0644))
+	svgz, err := OpenFile(file, Options{})
+	require.NoError(t, err)
+	require.Equal(t, file, svgz.Name)
+	again, err := svgz.Shapes()
+	require.NoError(t, err)
+	require.Len(t, again, 1)
+	require.Equal(t, shapes[0].Segments, again[0].Segments)
+	require.Equal(t, shapes[0].StrokeStyle, again[0].StrokeStyle)
+	require.Equal(t, "red", again[0].Fill)
+
+	_, err = ParseSvgFromReader(bytes.NewReader(gzipMagic), "", 1)
+	require.Error(t, err)
+}
+
+func TestSvgzBomb(t *testing.T) {
+	var bomb bytes.Buffer
+	zw := gzip.NewWriter(&bomb)
+	zw.Write([]byte("<svg>"))
+	zw.Write(bytes.Repeat([]byte(" "), 10<<20))
+	zw.Write([]byte("</svg>"))
+	require.NoError(t, zw.Close())
+	require.Less(t, bomb.Len(), 100<<10)
+
+	_, err := ParseSvgWithOptions(bytes.NewReader(bomb.Bytes()), NewOptions(WithLimits(Limits{MaxBytes: 1 << 20})))
+	var le *LimitError
+	require.True(t, errors.As(err, &le), "%v", err)
+	require.Equal(t, "MaxBytes", le.Limit)
+
+	_, err = ParseSvgWithOptions(bytes.NewReader(bomb.Bytes()), NewOptions(WithLimits(Limits{MaxBytes: 11 << 20})))
+	require.NoError(t, err)
+}
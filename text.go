@@ -0,0 +1,614 @@
+package svg
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	mt "github.com/rustyoz/Mtransform"
+)
+
+// TextContent is a piece of the content of a text element: either
+// character data or a tspan or textPath child.
+type TextContent struct {
+	Text string
+	Span *TSpan
+}
+
+// TSpan holds the attributes and content of a text, tspan or textPath
+// element. Empty font properties are inherited from the parent element.
+type TSpan struct {
+	// Tag is the name of the element.
+	Tag string
+	// X, Y, Dx and Dy position the characters of the element, including
+	// those of its descendants: the n-th value applies to the n-th
+	// character. Rotate lists the rotations of the characters in
+	// degrees; its last value applies to the remaining characters.
+	X, Y, Dx, Dy, Rotate []float64
+
+	FontFamily string
+	// FontSize is in user units.
+	FontSize         float64
+	FontWeight       string
+	TextAnchor       string
+	DominantBaseline string
+	// LetterSpacing is the space added after every character in user
+	// units, or nil to inherit it.
+	LetterSpacing *float64
+
+	// Href is the path a textPath element lays its text along and
+	// StartOffset the distance along the path where it starts, as
+	// written in the document.
+	Href        string
+	StartOffset string
+
+	Content []TextContent
+}
+
+// Text is an SVG text element.
+type Text struct {
+	ID              string
+	TransformString string
+	Display         string
+	Visibility      string
+	Fill            *string
+	Stroke          *string
+	Opacity         *float64
+	// Space is the xml:space attribute. "preserve" keeps white space
+	// that is otherwise collapsed.
+	Space string
+	TSpan
+
+	group *Group
+	pos   position
+}
+
+// TextRun is a run of characters drawn from one position, as carried by
+// a TextInstruction. Positions are in the coordinate system of the text
+// element; Transform maps them to the coordinates of the other drawing
+// instructions.
+type TextRun struct {
+	Text string `json:"text"`
+	// X and Y are the start of the run. If Relative is set, the run
+	// continues where the previous run of the element ends, which depends
+	// on the font's glyph advances, and X is an offset from there.
+	X        float64 `json:"x"`
+	Y        float64 `json:"y"`
+	Relative bool    `json:"relative,omitempty"`
+	// Rotate lists the rotations of the characters in degrees; the last
+	// value applies to the remaining characters.
+	Rotate []float64 `json:"rotate,omitempty"`
+
+	FontFamily       string  `json:"fontFamily,omitempty"`
+	FontSize         float64 `json:"fontSize,omitempty"`
+	FontWeight       string  `json:"fontWeight,omitempty"`
+	TextAnchor       string  `json:"textAnchor,omitempty"`
+	DominantBaseline string  `json:"dominantBaseline,omitempty"`
+	LetterSpacing    float64 `json:"letterSpacing,omitempty"`
+
+	// TextPath and StartOffset are the Href and StartOffset of the
+	// textPath element the run is laid along, if any.
+	TextPath    string `json:"textPath,omitempty"`
+	StartOffset string `json:"startOffset,omitempty"`
+
+	// Transform is the matrix a, b, c, d, e, f of the SVG matrix
+	// transform.
+	Transform [6]float64 `json:"transform"`
+}
+
+// defaultFontSize is the initial value of font-size, "medium".
+const defaultFontSize = 16
+
+// UnmarshalXML implements the encoding.xml.Unmarshaler interface
+func (t *Text) UnmarshalXML(decoder *xml.Decoder, start xml.StartElement) error {
+	var owner *Svg
+	if t.group != nil {
+		owner = t.group.Owner
+	}
+	d := &textDecoder{decoder: decoder, owner: owner}
+
+	var attrErrs []error
+	for _, attr := range start.Attr {
+		var err error
+		switch attr.Name.Local {
+		case "id":
+			t.ID = attr.Value
+		case "transform":
+			t.TransformString = attr.Value
+		case "display":
+			t.Display = attr.Value
+		case "visibility":
+			t.Visibility = attr.Value
+		case "space":
+			t.Space = attr.Value
+		default:
+			err = t.setProperty(d, attr.Name.Local, attr.Value)
+		}
+		if err != nil {
+			attrErrs = append(attrErrs, err)
+		}
+	}
+	if style := attrValue(start, "style"); style != "" {
+		for name, value := range splitStyle(style) {
+			if err := t.setProperty(d, strings.TrimSpace(name), strings.TrimSpace(value)); err != nil {
+				attrErrs = append(attrErrs, err)
+			}
+		}
+	}
+	// invalid attributes are ignored as if they were not specified
+	for _, err := range attrErrs {
+		if err = owner.warn(t.pos, "text", t.ID, -1, err); err != nil {
+			return err
+		}
+	}
+
+	t.Tag = "text"
+	d.ancestors = t.pos.child("text", t.ID)
+	return d.content(&t.TSpan)
+}
+
+// setProperty sets the presentation attribute name of the text element.
+func (t *Text) setProperty(d *textDecoder, name, value string) error {
+	switch name {
+	case "fill":
+		t.Fill = &value
+	case "stroke":
+		t.Stroke = &value
+	case "opacity":
+		v, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return fmt.Errorf("invalid opacity: %w", err)
+		}
+		t.Opacity = &v
+	default:
+		return d.setAttr(&t.TSpan, name, value)
+	}
+	return nil
+}
+
+// textDecoder decodes the content of a text element.
+type textDecoder struct {
+	decoder   *xml.Decoder
+	owner     *Svg
+	ancestors []string
+}
+
+// setAttr sets the attribute name of span. Unknown attributes are
+// ignored.
+func (d *textDecoder) setAttr(span *TSpan, name, value string) error {
+	var err error
+	switch name {
+	case "x":
+		span.X, err = d.lengths(value)
+	case "y":
+		span.Y, err = d.lengths(value)
+	case "dx":
+		span.Dx, err = d.lengths(value)
+	case "dy":
+		span.Dy, err = d.lengths(value)
+	case "rotate":
+		span.Rotate, err = parseNumberList(value)
+	case "font-family":
+		span.FontFamily = value
+	case "font-size":
+		var sizes []float64
+		if sizes, err = d.lengths(value); err == nil && len(sizes) == 1 && sizes[0] > 0 {
+			span.FontSize = sizes[0]
+		} else if err == nil {
+			err = fmt.Errorf("invalid font-size %q", value)
+		}
+	case "font-weight":
+		span.FontWeight = value
+	case "text-anchor":
+		span.TextAnchor = value
+	case "dominant-baseline":
+		span.DominantBaseline = value
+	case "letter-spacing":
+		var spacing []float64
+		if value == "normal" {
+			spacing = []float64{0}
+		} else if spacing, err = d.lengths(value); err == nil && len(spacing) != 1 {
+			err = fmt.Errorf("invalid letter-spacing %q", value)
+		}
+		if err == nil {
+			span.LetterSpacing = &spacing[0]
+		}
+	case "href":
+		span.Href = value
+	case "startOffset":
+		span.StartOffset = value
+	}
+	if err != nil {
+		return fmt.Errorf("invalid %s: %w", name, err)
+	}
+	return nil
+}
+
+// lengths parses a list of lengths.
+func (d *textDecoder) lengths(s string) ([]float64, error) {
+	dpi := userUnitsPerInch
+	if d.owner != nil && d.owner.options.DPI > 0 {
+		dpi = d.owner.options.DPI
+	}
+	var values []float64
+	for _, f := range splitList(s) {
+		v, err := parseLength(f, dpi)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, v)
+	}
+	return values, nil
+}
+
+// content decodes the content of span up to its end element.
+func (d *textDecoder) content(span *TSpan) error {
+	for {
+		offset := d.decoder.InputOffset()
+		token, err := d.decoder.Token()
+		if err != nil {
+			return err
+		}
+
+		switch tok := token.(type) {
+		case xml.CharData:
+			span.Content = append(span.Content, TextContent{Text: string(tok)})
+		case xml.StartElement:
+			name := tok.Name.Local
+			pos := d.owner.position(offset, d.ancestors)
+			if err := d.owner.checkLimits(pos); err != nil {
+				return pos.error(name, attrValue(tok, "id"), -1, err)
+			}
+			if name != "tspan" && name != "textPath" {
+				if err := d.decoder.Skip(); err != nil {
+					return err
+				}
+				continue
+			}
+
+			child := &TSpan{Tag: name}
+			var attrErrs []error
+			for _, attr := range tok.Attr {
+				if err := d.setAttr(child, attr.Name.Local, attr.Value); err != nil {
+					attrErrs = append(attrErrs, err)
+				}
+			}
+			for prop, value := range splitStyle(attrValue(tok, "style")) {
+				if err := d.setAttr(child, strings.TrimSpace(prop), strings.TrimSpace(value)); err != nil {
+					attrErrs = append(attrErrs, err)
+				}
+			}
+			for _, err := range attrErrs {
+				if err = d.owner.warn(pos, name, attrValue(tok, "id"), -1, err); err != nil {
+					return err
+				}
+			}
+			parent := d.ancestors
+			d.ancestors = pos.child(name, attrValue(tok, "id"))
+			if err := d.content(child); err != nil {
+				return err
+			}
+			d.ancestors = parent
+			span.Content = append(span.Content, TextContent{Span: child})
+		case xml.EndElement:
+			return nil
+		}
+	}
+}
+
+// splitList splits a list of numbers separated by white space or
+// commas.
+func splitList(s string) []string {
+	return strings.FieldsFunc(s, func(r rune) bool {
+		return r == ',' || r == ' ' || r == '\t' || r == '\n' || r == '\r'
+	})
+}
+
+// parseNumberList parses a list of numbers separated by white space or
+// commas.
+func parseNumberList(s string) ([]float64, error) {
+	var values []float64
+	for _, f := range splitList(s) {
+		v, err := strconv.ParseFloat(f, 64)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, v)
+	}
+	return values, nil
+}
+
+// ParseDrawingInstructions implements the DrawingInstructionParser
+// interface
+func (t *Text) ParseDrawingInstructions() (chan *DrawingInstruction, chan error) {
+	draw := make(chan *DrawingInstruction)
+	errs := make(chan error, 1)
+
+	go func() {
+		defer close(draw)
+		defer close(errs)
+
+		var instrs []*DrawingInstruction
+		outlined := false
+		if fonts := t.fonts(); fonts != nil {
+			var err error
+			if instrs, err = t.Outlines(fonts); err == nil {
+				outlined = true
+			} else if err = t.group.Owner.warn(t.pos, "text", t.ID, -1, err); err != nil {
+				errs <- err
+				return
+			}
+			// text that cannot be outlined is kept as text
+		}
+		if !outlined {
+			runs, err := t.Runs()
+			if err != nil {
+				errs <- err
+				return
+			}
+			for i := range runs {
+				instrs = append(instrs, &DrawingInstruction{Kind: TextInstruction, Text: &runs[i]})
+			}
+		}
+		for _, di := range instrs {
+			draw <- di
+		}
+		if len(instrs) > 0 {
+			var owner *Svg
+			if t.group != nil {
+				owner = t.group.Owner
+			}
+			ctm, _ := t.ctm()
+			// the fill of a text element is usually inherited
+			fill := t.Fill
+			for g := t.group; fill == nil && g != nil; g = g.Parent {
+				if g.Fill != "" {
+					fill = &g.Fill
+				}
+			}
+			draw <- &DrawingInstruction{
+				Kind:           PaintInstruction,
+				Fill:           t.Fill,
+				Stroke:         t.Stroke,
+				Opacity:        t.Opacity,
+				FillGradient:   owner.paintGradient(fill, ctm),
+				StrokeGradient: owner.paintGradient(t.Stroke, ctm),
+			}
+		}
+	}()
+
+	return draw, errs
+}
+
+// fonts returns the fonts text is converted to outlines with, or nil to
+// keep text as text.
+func (t *Text) fonts() *Fonts {
+	if t.group == nil || t.group.Owner == nil {
+		return nil
+	}
+	return t.group.Owner.options.Fonts
+}
+
+// ctm returns the transform of the text element and its groups. An
+// invalid transform attribute is reported and left out.
+func (t *Text) ctm() (mt.Transform, error) {
+	transform := mt.Identity()
+	if t.group != nil && t.group.Transform != nil {
+		transform = *t.group.Transform
+	}
+	if t.TransformString != "" {
+		tt, err := parseTransform(t.TransformString)
+		if err != nil {
+			return transform, fmt.Errorf("invalid transform %q: %w", t.TransformString, err)
+		}
+		transform = mt.MultiplyTransforms(transform, tt)
+	}
+	return transform, nil
+}
+
+// Runs lays out the characters of the text element in runs. A new run
+// starts at every character that is positioned by an x, y, dx or dy
+// value, or whose font properties differ from the previous character.
+func (t *Text) Runs() ([]TextRun, error) {
+	transform, err := t.ctm()
+	if err != nil {
+		var owner *Svg
+		if t.group != nil {
+			owner = t.group.Owner
+		}
+		if err = owner.warn(t.pos, "text", t.ID, -1, err); err != nil {
+			return nil, err
+		}
+	}
+
+	l := &textLayout{
+		preserve:  t.Space == "preserve",
+		transform: [6]float64{transform[0][0], transform[1][0], transform[0][1], transform[1][1], transform[0][2], transform[1][2]},
+		// white space at the start of the element is removed
+		space: true,
+	}
+	l.layout(&t.TSpan, TextRun{FontSize: defaultFontSize})
+	if !l.preserve {
+		l.trimTrailingSpace()
+	}
+	return l.runs, nil
+}
+
+// textFrame is an element whose characters are being laid out, with
+// the index of its next character.
+type textFrame struct {
+	span *TSpan
+	n    int
+}
+
+// textLayout splits the content of a text element into runs.
+type textLayout struct {
+	preserve  bool
+	transform [6]float64
+	frames    []textFrame
+	runs      []TextRun
+	// space is set if the last character was a space that collapses
+	// the next one
+	space bool
+	// x and y are the position of the next character if known is set;
+	// otherwise x is relative to the end of the last run
+	x, y  float64
+	known bool
+	// started is set once a run has been made, so later runs without
+	// an absolute x are relative
+	started bool
+}
+
+// layout lays out span, whose parent has the font properties of style.
+func (l *textLayout) layout(span *TSpan, style TextRun) {
+	if span.FontFamily != "" {
+		style.FontFamily = span.FontFamily
+	}
+	if span.FontSize > 0 {
+		style.FontSize = span.FontSize
+	}
+	if span.FontWeight != "" {
+		style.FontWeight = span.FontWeight
+	}
+	if span.TextAnchor != "" {
+		style.TextAnchor = span.TextAnchor
+	}
+	if span.DominantBaseline != "" {
+		style.DominantBaseline = span.DominantBaseline
+	}
+	if span.LetterSpacing != nil {
+		style.LetterSpacing = *span.LetterSpacing
+	}
+	if span.Tag == "textPath" {
+		style.TextPath, style.StartOffset = span.Href, span.StartOffset
+		// the text starts at the start of the path
+		l.x, l.y, l.known = 0, 0, true
+	}
+
+	l.frames = append(l.frames, textFrame{span: span})
+	for _, c := range span.Content {
+		if c.Span != nil {
+			l.layout(c.Span, style)
+			continue
+		}
+		for _, r := range c.Text {
+			l.char(r, style)
+		}
+	}
+	l.frames = l.frames[:len(l.frames)-1]
+}
+
+// value returns the value for the next character in the list selected
+// by list of the innermost element that has one.
+func (l *textLayout) value(list func(*TSpan) []float64) (float64, bool) {
+	for i := len(l.frames) - 1; i >= 0; i-- {
+		f := l.frames[i]
+		if values := list(f.span); f.n < len(values) {
+			return values[f.n], true
+		}
+	}
+	return 0, false
+}
+
+// rotation returns the rotation of the next character.
+func (l *textLayout) rotation() (float64, bool) {
+	for i := len(l.frames) - 1; i >= 0; i-- {
+		f := l.frames[i]
+		if values := f.span.Rotate; len(values) > 0 {
+			if f.n < len(values) {
+				return values[f.n], true
+			}
+			return values[len(values)-1], true
+		}
+	}
+	return 0, false
+}
+
+// char adds the character r with the font properties of style.
+func (l *textLayout) char(r rune, style TextRun) {
+	if !l.preserve {
+		switch r {
+		case '\n', '\r':
+			return
+		case '\t':
+			r = ' '
+		}
+		if r == ' ' {
+			if l.space {
+				return
+			}
+			l.space = true
+		} else {
+			l.space = false
+		}
+	} else if r == '\n' || r == '\r' || r == '\t' {
+		r = ' '
+	}
+
+	x, hasX := l.value(func(s *TSpan) []float64 { return s.X })
+	y, hasY := l.value(func(s *TSpan) []float64 { return s.Y })
+	dx, hasDx := l.value(func(s *TSpan) []float64 { return s.Dx })
+	dy, hasDy := l.value(func(s *TSpan) []float64 { return s.Dy })
+	rotate, _ := l.rotation()
+	for i := range l.frames {
+		l.frames[i].n++
+	}
+
+	positioned := hasX || hasY || hasDx || hasDy
+	if hasX {
+		l.x, l.known = x, true
+	}
+	if hasY {
+		l.y = y
+	}
+	l.x += dx
+	l.y += dy
+
+	n := len(l.runs)
+	if n == 0 || positioned || !l.runs[n-1].sameStyle(style) {
+		run := style
+		run.X, run.Y = l.x, l.y
+		run.Relative = l.started && !l.known
+		run.Transform = l.transform
+		l.runs = append(l.runs, run)
+		l.started = true
+		// later characters follow this run
+		l.x, l.known = 0, false
+		n++
+	}
+	cur := &l.runs[n-1]
+	chars := utf8.RuneCountInString(cur.Text)
+	if rotate != 0 || len(cur.Rotate) > 0 {
+		for len(cur.Rotate) < chars {
+			cur.Rotate = append(cur.Rotate, 0)
+		}
+		cur.Rotate = append(cur.Rotate, rotate)
+	}
+	cur.Text += string(r)
+}
+
+// sameStyle reports whether r and s have the same font properties and
+// text path.
+func (r TextRun) sameStyle(s TextRun) bool {
+	return r.FontFamily == s.FontFamily && r.FontSize == s.FontSize && r.FontWeight == s.FontWeight &&
+		r.TextAnchor == s.TextAnchor && r.DominantBaseline == s.DominantBaseline &&
+		r.LetterSpacing == s.LetterSpacing && r.TextPath == s.TextPath && r.StartOffset == s.StartOffset
+}
+
+// trimTrailingSpace removes a collapsed space at the end of the text.
+func (l *textLayout) trimTrailingSpace() {
+	n := len(l.runs)
+	if n == 0 || !strings.HasSuffix(l.runs[n-1].Text, " ") {
+		return
+	}
+	last := &l.runs[n-1]
+	last.Text = last.Text[:len(last.Text)-1]
+	if chars := utf8.RuneCountInString(last.Text); len(last.Rotate) > chars {
+		last.Rotate = last.Rotate[:chars]
+	}
+	if last.Text == "" {
+		l.runs = l.runs[:n-1]
+	}
+}
@@ -0,0 +1,70 @@
+package svg
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestText(t *testing.T) {
+	svg, err := ParseSvg(testSvg, "test", 0)
+	require.NoError(t, err)
+	shapes, err := svg.Shapes()
+	require.NoError(t, err)
+	var texts []Shape
+	for _, s := range shapes {
+		if s.isText() {
+			texts = append(texts, s)
+		}
+	}
+	require.Len(t, texts, 2)
+	run := *texts[0].Instructions[0].Text
+	require.Equal(t, "PODIUM", run.Text)
+	require.Equal(t, "'ArialMT'", run.FontFamily)
+	require.Equal(t, 31.9752, run.FontSize)
+	require.Equal(t, [6]float64{1, 0, 0, 1, 232.3306, 107.5952}, run.Transform)
+	require.Equal(t, "#FFFFFF", texts[0].Fill)
+
+	doc := `<svg><g fill="red"><text id="t" x="10 20" y="30" font-size="10" style="font-weight: bold">
+	ab  c
+	<tspan dy="5" font-size="1in" rotate="45 90">de</tspan>f<textPath href="#p" startOffset="50%">g</textPath>
+</text></g></svg>`
+	svg, err = ParseSvg(doc, "test", 0)
+	require.NoError(t, err)
+	text := svg.Groups[0].Elements[0].(*Text)
+	require.Len(t, text.Content, 5)
+	require.Equal(t, "tspan", text.Content[1].Span.Tag)
+	require.Equal(t, "#p", text.Content[3].Span.Href)
+
+	runs, err := text.Runs()
+	require.NoError(t, err)
+	var got []string
+	for _, r := range runs {
+		got = append(got, strings.Join([]string{r.Text, fmt.Sprint(r.X), fmt.Sprint(r.Y), fmt.Sprint(r.FontSize), r.FontWeight, r.TextPath}, ","))
+	}
+	require.Equal(t, []string{
+		"a,10,30,10,bold,",
+		"b c ,20,30,10,bold,",
+		"de,0,35,96,bold,",
+		"f,0,35,10,bold,",
+		"g,0,0,10,bold,#p",
+	}, got)
+	require.False(t, runs[1].Relative)
+	require.True(t, runs[2].Relative)
+	require.Equal(t, []float64{45, 90}, runs[2].Rotate)
+
+	r := &RecordingRenderer{}
+	require.NoError(t, Replay(svg, r))
+	var calls []string
+	for _, c := range r.Calls {
+		if c.Method == "Text" {
+			calls = append(calls, c.Text.Text+" "+c.Style.Paint)
+		}
+	}
+	require.Equal(t, []string{"a red", "b c  red", "de red", "f red", "g red"}, calls)
+
+	_, err = ParseSvgWithOptions(strings.NewReader(`<svg><text x="1 q">a</text></svg>`), NewOptions(WithStrict(true)))
+	require.EqualError(t, err, `1:6: text in svg: invalid x: unsupported length unit in "q"`)
+}
@@ -0,0 +1,88 @@
+package svg
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// userUnitsPerInch is the CSS resolution used to convert absolute units
+// to user units (px).
+const userUnitsPerInch = 96.0
+
+// unitFactors maps the absolute length units to user units.
+var unitFactors = map[string]float64{
+	"":   1,
+	"px": 1,
+	"pt": userUnitsPerInch / 72,
+	"pc": userUnitsPerInch / 6,
+	"mm": userUnitsPerInch / 25.4,
+	"cm": userUnitsPerInch / 2.54,
+	"in": userUnitsPerInch,
+}
+
+// unitFactor returns the size of unit in user units at a resolution of
+// dpi user units per inch.
+func unitFactor(unit string, dpi float64) (float64, bool) {
+	factor, ok := unitFactors[unit]
+	if unit != "" && unit != "px" {
+		factor *= dpi / userUnitsPerInch
+	}
+	return factor, ok
+}
+
+// parseLength converts a length such as "10mm" or "595.2px" to user
+// units at a resolution of dpi user units per inch. Relative units like
+// % and em are not supported.
+func parseLength(s string, dpi float64) (float64, error) {
+	s = strings.TrimSpace(s)
+	i := len(s)
+	for i > 0 && (s[i-1] >= 'a' && s[i-1] <= 'z' || s[i-1] == '%') {
+		i--
+	}
+	factor, ok := unitFactor(s[i:], dpi)
+	if !ok {
+		return 0, fmt.Errorf("unsupported length unit in %q", s)
+	}
+	v, err := strconv.ParseFloat(s[:i], 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid length %q: %s", s, err)
+	}
+	return v * factor, nil
+}
+
+// Size returns the width and height of the document in user units,
+// multiplied by the scale it was parsed with. They are taken from the
+// width and height attributes, in any absolute unit, falling back to the
+// size of the viewBox.
+func (s *Svg) Size() (float64, float64, error) {
+	scale := s.scale
+	if scale <= 0 {
+		scale = 1
+	}
+	if s.Width != "" && s.Height != "" {
+		w, err := s.length(s.Width)
+		if err != nil {
+			return 0, 0, err
+		}
+		h, err := s.length(s.Height)
+		if err != nil {
+			return 0, 0, err
+		}
+		return w * scale, h * scale, nil
+	}
+	if vb, err := s.ViewBoxValues(); err == nil && len(vb) == 4 {
+		return vb[2] * scale, vb[3] * scale, nil
+	}
+	return 0, 0, fmt.Errorf("svg %s has no width, height or viewBox", s.Name)
+}
+
+// length converts a length to user units at the resolution the document
+// was parsed with.
+func (s *Svg) length(v string) (float64, error) {
+	dpi := s.options.DPI
+	if dpi <= 0 {
+		dpi = userUnitsPerInch
+	}
+	return parseLength(v, dpi)
+}
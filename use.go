@@ -0,0 +1,263 @@
+package svg
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	mt "github.com/rustyoz/Mtransform"
+)
+
+// Use is an SVG use element. It draws a copy of the element its href
+// refers to, transformed by its own transform and translated by X and Y.
+// References to other documents, like "icons.svg#gear", are loaded with
+// the ResourceLoader of the document.
+type Use struct {
+	ID              string  `xml:"id,attr"`
+	Href            string  `xml:"href,attr"`
+	X               float64 `xml:"x,attr"`
+	Y               float64 `xml:"y,attr"`
+	TransformString string  `xml:"transform,attr"`
+	Display         string  `xml:"display,attr"`
+	Visibility      string  `xml:"visibility,attr"`
+
+	group *Group
+	pos   position
+	// scope is the group the copy is drawn in and ref the copy; ref is
+	// nil if the reference could not be resolved
+	scope *Group
+	ref   DrawingInstructionParser
+}
+
+// ParseDrawingInstructions implements the DrawingInstructionParser
+// interface
+func (u *Use) ParseDrawingInstructions() (chan *DrawingInstruction, chan error) {
+	if u.ref != nil {
+		return u.ref.ParseDrawingInstructions()
+	}
+	draw := make(chan *DrawingInstruction)
+	errs := make(chan error)
+	close(draw)
+	close(errs)
+	return draw, errs
+}
+
+// useResolver copies the elements use elements refer to.
+type useResolver struct {
+	svg *Svg
+	// docs are the external documents by href, loaded once per parse
+	docs       map[string]*useDocument
+	expansions int
+}
+
+// useDocument is a document whose elements use elements refer to.
+type useDocument struct {
+	svg *Svg
+	ids map[string]DrawingInstructionParser
+	err error
+}
+
+// newUseDocument indexes the elements of s by ID and returns the use
+// elements of s.
+func newUseDocument(s *Svg) (*useDocument, []*Use) {
+	d := &useDocument{svg: s, ids: make(map[string]DrawingInstructionParser)}
+	var uses []*Use
+	var walk func(elements []DrawingInstructionParser)
+	walk = func(elements []DrawingInstructionParser) {
+		for _, e := range elements {
+			// the first element with an ID wins
+			if id := elementID(e); id != "" && d.ids[id] == nil {
+				d.ids[id] = e
+			}
+			switch el := e.(type) {
+			case *Group:
+				walk(el.Elements)
+			case *Use:
+				uses = append(uses, el)
+			}
+		}
+	}
+	walk(s.children())
+	return d, uses
+}
+
+// resolveUses resolves every use element of the document.
+func (s *Svg) resolveUses() error {
+	r := &useResolver{svg: s, docs: make(map[string]*useDocument)}
+	doc, uses := newUseDocument(s)
+	for _, u := range uses {
+		if err := r.resolve(u, doc, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// load returns the external document at href.
+func (r *useResolver) load(href string) (*useDocument, error) {
+	if d, ok := r.docs[href]; ok {
+		return d, d.err
+	}
+	d := &useDocument{}
+	r.docs[href] = d
+
+	rc, err := r.svg.open(href)
+	if err != nil {
+		d.err = err
+		return d, err
+	}
+	defer rc.Close()
+	opts := r.svg.options
+	opts.Name, opts.Defaults = href, StyleDefaults{}
+	s, err := decodeSvg(rc, opts, 1, href, r.svg.counts)
+	if err != nil {
+		d.err = err
+		return d, err
+	}
+	r.svg.warnMu.Lock()
+	r.svg.Warnings = append(r.svg.Warnings, s.Warnings...)
+	r.svg.warnMu.Unlock()
+	loaded, _ := newUseDocument(s)
+	*d = *loaded
+	return d, nil
+}
+
+// resolve copies the element u, which is in the document doc, refers
+// to into u. Stack holds the elements being copied, to detect circular
+// references.
+func (r *useResolver) resolve(u *Use, doc *useDocument, stack []string) error {
+	file, id := u.Href, ""
+	if i := strings.IndexByte(u.Href, '#'); i >= 0 {
+		file, id = u.Href[:i], u.Href[i+1:]
+	}
+	if file == "" && id == "" {
+		return r.svg.warn(u.pos, "use", u.ID, -1, fmt.Errorf("unsupported reference %q", u.Href))
+	}
+	name := id
+	if file != "" {
+		href := resolveHref(doc.svg.href, file)
+		d, err := r.load(href)
+		var le *LimitError
+		if errors.As(err, &le) {
+			return u.pos.error("use", u.ID, -1, err)
+		}
+		if err != nil {
+			return r.svg.warn(u.pos, "use", u.ID, -1, fmt.Errorf("loading %q: %w", href, err))
+		}
+		doc, name = d, href+"#"+id
+	}
+
+	// a reference without a fragment draws the whole document
+	target := DrawingInstructionParser(&Group{Elements: doc.svg.children()})
+	if id != "" {
+		if target = doc.ids[id]; target == nil {
+			return r.svg.warn(u.pos, "use", u.ID, -1, fmt.Errorf("reference to unknown element %q", name))
+		}
+	}
+	key := doc.svg.href + "#" + id
+	for _, s := range stack {
+		if s == key {
+			return r.svg.warn(u.pos, "use", u.ID, -1, fmt.Errorf("circular reference to %q", name))
+		}
+	}
+
+	r.expansions++
+	if max := r.svg.options.Limits.MaxUses; max > 0 && r.expansions > max {
+		return u.pos.error("use", u.ID, -1, &LimitError{"MaxUses", max})
+	}
+
+	parent := u.group
+	if parent == nil {
+		parent = &Group{Owner: r.svg, Transform: mt.NewTransform()}
+	}
+	t := mt.Identity()
+	if parent.Transform != nil {
+		t = *parent.Transform
+	}
+	if u.TransformString != "" {
+		ut, err := parseTransform(u.TransformString)
+		if err != nil {
+			err = fmt.Errorf("invalid transform %q: %w", u.TransformString, err)
+			if err = r.svg.warn(u.pos, "use", u.ID, -1, err); err != nil {
+				return err
+			}
+		} else {
+			t = mt.MultiplyTransforms(t, ut)
+		}
+	}
+	offset := mt.Identity()
+	offset.Translate(u.X, u.Y)
+	t = mt.MultiplyTransforms(t, offset)
+
+	u.scope = &Group{Parent: parent, Owner: r.svg, Transform: &t, StrokeWidth: parent.StrokeWidth}
+	ref, err := r.copy(target, u.scope, doc, append(stack, key))
+	var le *LimitError
+	if errors.As(err, &le) && !errors.As(err, new(*ParseError)) {
+		return u.pos.error("use", u.ID, -1, err)
+	}
+	if err != nil {
+		return err
+	}
+	u.ref = ref
+	return nil
+}
+
+// copy copies e, which is in the document doc, into the group parent.
+// Every copied element counts against MaxElements.
+func (r *useResolver) copy(e DrawingInstructionParser, parent *Group, doc *useDocument, stack []string) (DrawingInstructionParser, error) {
+	if err := r.svg.countElement(); err != nil {
+		return nil, err
+	}
+	switch el := e.(type) {
+	case *Path:
+		c := *el
+		c.group = parent
+		c.Segments, c.instructions, c.errors = nil, nil, nil
+		return &c, nil
+	case *Circle:
+		c := *el
+		c.group = parent
+		return &c, nil
+	case *Rect:
+		c := *el
+		c.group = parent
+		return &c, nil
+	case *Use:
+		c := *el
+		c.group = parent
+		c.scope, c.ref = nil, nil
+		if err := r.resolve(&c, doc, stack); err != nil {
+			return nil, err
+		}
+		return &c, nil
+	case *Image:
+		c := *el
+		c.group = parent
+		return &c, nil
+	case *Text:
+		c := *el
+		c.group = parent
+		return &c, nil
+	case *Group:
+		c := *el
+		c.Parent, c.Owner = parent, parent.Owner
+		c.instructions, c.errors, c.segments = nil, nil, nil
+		t := *parent.Transform
+		if el.TransformString != "" {
+			if gt, err := parseTransform(el.TransformString); err == nil {
+				t = mt.MultiplyTransforms(t, gt)
+			}
+		}
+		c.Transform = &t
+		c.Elements = nil
+		for _, child := range el.Elements {
+			cc, err := r.copy(child, &c, doc, stack)
+			if err != nil {
+				return nil, err
+			}
+			c.Elements = append(c.Elements, cc)
+		}
+		return &c, nil
+	}
+	return e, nil
+}
@@ -0,0 +1,77 @@
+package svg
+
+import (
+	"io"
+	"strings"
+)
+
+// WriteWKT writes the geometry of every visible shape to w as Well-Known
+// Text, one line per shape in the order of shapes. The geometry types
+// are chosen as for WriteGeoJSON; shapes without segments are written
+// as GEOMETRYCOLLECTION EMPTY. A nil opts uses the defaults.
+func WriteWKT(w io.Writer, shapes []Shape, opts *GeoOptions) error {
+	for _, s := range shapes {
+		if s.Hidden {
+			continue
+		}
+		if _, err := io.WriteString(w, shapeGeometry(s, opts).wkt()+"\n"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WKT returns the geometry of the shape as Well-Known Text.
+func (s Shape) WKT(opts *GeoOptions) string {
+	return shapeGeometry(s, opts).wkt()
+}
+
+func (g geometry) wkt() string {
+	var parts []string
+	switch len(g.polygons) {
+	case 0:
+	case 1:
+		parts = append(parts, "POLYGON "+wktPolygon(g.polygons[0]))
+	default:
+		var polys []string
+		for _, p := range g.polygons {
+			polys = append(polys, wktPolygon(p))
+		}
+		parts = append(parts, "MULTIPOLYGON ("+strings.Join(polys, ", ")+")")
+	}
+	switch len(g.lines) {
+	case 0:
+	case 1:
+		parts = append(parts, "LINESTRING "+wktPoints(g.lines[0]))
+	default:
+		var lines []string
+		for _, l := range g.lines {
+			lines = append(lines, wktPoints(l))
+		}
+		parts = append(parts, "MULTILINESTRING ("+strings.Join(lines, ", ")+")")
+	}
+
+	switch len(parts) {
+	case 0:
+		return "GEOMETRYCOLLECTION EMPTY"
+	case 1:
+		return parts[0]
+	}
+	return "GEOMETRYCOLLECTION (" + strings.Join(parts, ", ") + ")"
+}
+
+func wktPolygon(rings [][][2]float64) string {
+	var parts []string
+	for _, r := range rings {
+		parts = append(parts, wktPoints(r))
+	}
+	return "(" + strings.Join(parts, ", ") + ")"
+}
+
+func wktPoints(pts [][2]float64) string {
+	parts := make([]string, len(pts))
+	for i, p := range pts {
+		parts[i] = formatNumber(p[0]) + " " + formatNumber(p[1])
+	}
+	return "(" + strings.Join(parts, ", ") + ")"
+}